@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const apiKeyPrefix = "tax_"
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is an issued key for a calculation client. Only the SHA-256 hash of
+// the key is stored, the plain key is shown once when it is created.
+type APIKey struct {
+	ID        string
+	Name      string
+	KeyHash   string
+	CreatedBy string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+func randomHex(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// HashAPIKey hashes a plain API key for storage and lookup. Keys carry 256
+// bits of randomness, so a fast hash is enough here unlike admin passwords.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateAPIKey creates a new key in the form tax_<id>_<secret> and returns
+// the plain key together with the record to store.
+func GenerateAPIKey(name, createdBy string, now time.Time) (string, APIKey, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	plain := apiKeyPrefix + id + "_" + secret
+	return plain, APIKey{
+		ID:        id,
+		Name:      name,
+		KeyHash:   HashAPIKey(plain),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}, nil
+}
+
+func looksLikeAPIKey(key string) bool {
+	return strings.HasPrefix(key, apiKeyPrefix) && strings.Count(key, "_") == 2
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleEditor    Role = "editor"
+	RoleSuperuser Role = "superuser"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:    1,
+	RoleEditor:    2,
+	RoleSuperuser: 3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Allows reports whether the role has at least the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+const (
+	ContextKeyUsername = "adminUsername"
+	ContextKeyRole     = "adminRole"
+)
+
+const (
+	DefaultMaxFailedAttempts = 5
+	DefaultLockoutDuration   = 15 * time.Minute
+)
+
+var ErrUserNotFound = errors.New("admin user not found")
+
+type AdminUser struct {
+	Username       string
+	PasswordHash   string
+	Role           Role
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+type Store interface {
+	GetAdminUser(ctx context.Context, username string) (AdminUser, error)
+	ListAdminUsers(ctx context.Context) ([]AdminUser, error)
+	SaveAdminUser(ctx context.Context, user AdminUser) error
+	DeleteAdminUser(ctx context.Context, username string) error
+	CountAdminUsers(ctx context.Context) (int, error)
+	// RecordFailedLogin atomically counts a failed login at now, restarting
+	// the count when a previous lockout has expired, and locks the account
+	// until lockUntil when the count reaches maxFailedAttempts. It returns
+	// the count.
+	RecordFailedLogin(ctx context.Context, username string, now time.Time, maxFailedAttempts int, lockUntil time.Time) (int, error)
+	ResetFailedLogins(ctx context.Context, username string) error
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// compareWithDummyHash spends the same time as a real password check so that
+// unknown usernames cannot be told apart by response time.
+func compareWithDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+type Authenticator struct {
+	Store             Store
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+	Now               func() time.Time
+}
+
+func NewAuthenticator(store Store) *Authenticator {
+	return &Authenticator{
+		Store:             store,
+		MaxFailedAttempts: DefaultMaxFailedAttempts,
+		LockoutDuration:   DefaultLockoutDuration,
+		Now:               time.Now,
+	}
+}
+
+// Authenticate checks the credentials of an admin. Accounts are locked for the
+// lockout duration after too many failed attempts in a row, and a locked
+// account is rejected even with the right password.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (AdminUser, bool, error) {
+	user, err := a.Store.GetAdminUser(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		compareWithDummyHash(password)
+		return AdminUser{}, false, nil
+	}
+	if err != nil {
+		return AdminUser{}, false, err
+	}
+
+	now := a.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		compareWithDummyHash(password)
+		return AdminUser{}, false, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		_, err := a.Store.RecordFailedLogin(ctx, username, now, a.MaxFailedAttempts, now.Add(a.LockoutDuration))
+		if err != nil {
+			return AdminUser{}, false, err
+		}
+		return AdminUser{}, false, nil
+	}
+
+	if user.FailedAttempts > 0 || user.LockedUntil != nil {
+		if err := a.Store.ResetFailedLogins(ctx, username); err != nil {
+			return AdminUser{}, false, err
+		}
+	}
+	return user, true, nil
+}
+
+// Validate is a middleware.BasicAuthValidator that stores the admin's
+// username and role in the echo context.
+func (a *Authenticator) Validate(username, password string, c echo.Context) (bool, error) {
+	user, ok, err := a.Authenticate(c.Request().Context(), username, password)
+	if err != nil || !ok {
+		return false, err
+	}
+	c.Set(ContextKeyUsername, user.Username)
+	c.Set(ContextKeyRole, user.Role)
+	return true, nil
+}
+
+// RequireRole rejects admins whose role does not allow the route.
+func RequireRole(required Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get(ContextKeyRole).(Role)
+			if !role.Allows(required) {
+				return c.JSON(http.StatusForbidden, Err{Message: "Role " + string(required) + " is required"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// Bootstrap creates a superuser from the given credentials when there are no
+// admin users yet, so a fresh database can be administered with the
+// ADMIN_USERNAME and ADMIN_PASSWORD environment variables.
+func Bootstrap(ctx context.Context, store Store, username, password string) (bool, error) {
+	if username == "" || password == "" {
+		return false, nil
+	}
+	count, err := store.CountAdminUsers(ctx)
+	if err != nil || count > 0 {
+		return false, err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	err = store.SaveAdminUser(ctx, AdminUser{Username: username, PasswordHash: hash, Role: RoleSuperuser})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type MockStore struct {
+	mu    sync.Mutex
+	Users map[string]AdminUser
+}
+
+func NewMockStore() *MockStore {
+	return &MockStore{Users: map[string]AdminUser{}}
+}
+
+func (m *MockStore) GetAdminUser(ctx context.Context, username string) (AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.Users[username]
+	if !ok {
+		return AdminUser{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *MockStore) ListAdminUsers(ctx context.Context) ([]AdminUser, error) {
+	result := []AdminUser{}
+	for _, user := range m.Users {
+		result = append(result, user)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	return result, nil
+}
+
+func (m *MockStore) SaveAdminUser(ctx context.Context, user AdminUser) error {
+	m.Users[user.Username] = user
+	return nil
+}
+
+func (m *MockStore) DeleteAdminUser(ctx context.Context, username string) error {
+	if _, ok := m.Users[username]; !ok {
+		return ErrUserNotFound
+	}
+	delete(m.Users, username)
+	return nil
+}
+
+func (m *MockStore) CountAdminUsers(ctx context.Context) (int, error) {
+	return len(m.Users), nil
+}
+
+func (m *MockStore) RecordFailedLogin(ctx context.Context, username string, now time.Time, maxFailedAttempts int, lockUntil time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user := m.Users[username]
+	if user.LockedUntil != nil && !now.Before(*user.LockedUntil) {
+		user.FailedAttempts = 0
+		user.LockedUntil = nil
+	}
+	user.FailedAttempts = user.FailedAttempts + 1
+	if user.FailedAttempts >= maxFailedAttempts {
+		user.LockedUntil = &lockUntil
+	}
+	m.Users[username] = user
+	return user.FailedAttempts, nil
+}
+
+func (m *MockStore) ResetFailedLogins(ctx context.Context, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user := m.Users[username]
+	user.FailedAttempts = 0
+	user.LockedUntil = nil
+	m.Users[username] = user
+	return nil
+}
+
+func newStoreWithUser(t *testing.T, username, password string, role Role) *MockStore {
+	store := NewMockStore()
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("Unable to hash password, error: %v", err)
+	}
+	store.Users[username] = AdminUser{Username: username, PasswordHash: hash, Role: role}
+	return store
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Run("given correct password should authenticate with role", func(t *testing.T) {
+		authenticator := NewAuthenticator(newStoreWithUser(t, "adminTax", "admin!", RoleEditor))
+
+		user, ok, err := authenticator.Authenticate(context.Background(), "adminTax", "admin!")
+		if err != nil || !ok {
+			t.Errorf("expect authenticated but got %v, %v", ok, err)
+		}
+		if user.Role != RoleEditor {
+			t.Errorf("expect role %v but got %v", RoleEditor, user.Role)
+		}
+	})
+
+	t.Run("given wrong password or unknown user should not authenticate", func(t *testing.T) {
+		store := newStoreWithUser(t, "adminTax", "admin!", RoleEditor)
+		authenticator := NewAuthenticator(store)
+
+		if _, ok, _ := authenticator.Authenticate(context.Background(), "adminTax", "wrong"); ok {
+			t.Errorf("expect wrong password to be rejected")
+		}
+		if _, ok, _ := authenticator.Authenticate(context.Background(), "unknown", "admin!"); ok {
+			t.Errorf("expect unknown user to be rejected")
+		}
+		if got := store.Users["adminTax"].FailedAttempts; got != 1 {
+			t.Errorf("expect 1 failed attempt but got %v", got)
+		}
+	})
+
+	t.Run("given too many failed attempts should lock account until lockout expires", func(t *testing.T) {
+		store := newStoreWithUser(t, "adminTax", "admin!", RoleEditor)
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		authenticator := NewAuthenticator(store)
+		authenticator.Now = func() time.Time { return now }
+
+		for i := 0; i < DefaultMaxFailedAttempts; i++ {
+			authenticator.Authenticate(context.Background(), "adminTax", "wrong")
+		}
+		if _, ok, _ := authenticator.Authenticate(context.Background(), "adminTax", "admin!"); ok {
+			t.Errorf("expect locked account to be rejected with correct password")
+		}
+
+		now = now.Add(DefaultLockoutDuration)
+		if _, ok, _ := authenticator.Authenticate(context.Background(), "adminTax", "admin!"); !ok {
+			t.Errorf("expect account to be unlocked after lockout duration")
+		}
+		if got := store.Users["adminTax"]; got.FailedAttempts != 0 || got.LockedUntil != nil {
+			t.Errorf("expect failed attempts to be reset but got %v, %v", got.FailedAttempts, got.LockedUntil)
+		}
+	})
+
+	t.Run("given concurrent failed attempts should count every attempt and lock the account", func(t *testing.T) {
+		store := newStoreWithUser(t, "adminTax", "admin!", RoleEditor)
+		authenticator := NewAuthenticator(store)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2*DefaultMaxFailedAttempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				authenticator.Authenticate(context.Background(), "adminTax", "wrong")
+			}()
+		}
+		wg.Wait()
+
+		got, _ := store.GetAdminUser(context.Background(), "adminTax")
+		if got.FailedAttempts < DefaultMaxFailedAttempts || got.LockedUntil == nil {
+			t.Errorf("expect account to be locked after %v attempts but got %v, %v", DefaultMaxFailedAttempts, got.FailedAttempts, got.LockedUntil)
+		}
+		if _, ok, _ := authenticator.Authenticate(context.Background(), "adminTax", "admin!"); ok {
+			t.Errorf("expect locked account to be rejected with correct password")
+		}
+	})
+
+	t.Run("given role below required should return 403", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		res := httptest.NewRecorder()
+		c := e.NewContext(req, res)
+		c.Set(ContextKeyRole, RoleViewer)
+
+		called := false
+		RequireRole(RoleEditor)(func(c echo.Context) error {
+			called = true
+			return nil
+		})(c)
+
+		if called || res.Result().StatusCode != http.StatusForbidden {
+			t.Errorf("expected status %v but got status %v", http.StatusForbidden, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("given role above required should call next handler", func(t *testing.T) {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		c.Set(ContextKeyRole, RoleSuperuser)
+
+		called := false
+		RequireRole(RoleEditor)(func(c echo.Context) error {
+			called = true
+			return nil
+		})(c)
+
+		if !called {
+			t.Errorf("expect next handler to be called")
+		}
+	})
+}
+
+func TestBootstrap(t *testing.T) {
+	t.Run("given empty store should create superuser", func(t *testing.T) {
+		store := NewMockStore()
+
+		created, err := Bootstrap(context.Background(), store, "adminTax", "admin!")
+		if err != nil || !created {
+			t.Errorf("expect superuser to be created but got %v, %v", created, err)
+		}
+		if got := store.Users["adminTax"].Role; got != RoleSuperuser {
+			t.Errorf("expect role %v but got %v", RoleSuperuser, got)
+		}
+		if _, ok, _ := NewAuthenticator(store).Authenticate(context.Background(), "adminTax", "admin!"); !ok {
+			t.Errorf("expect bootstrapped user to authenticate")
+		}
+	})
+
+	t.Run("given existing admin users should not create superuser", func(t *testing.T) {
+		store := newStoreWithUser(t, "other", "password", RoleViewer)
+
+		created, err := Bootstrap(context.Background(), store, "adminTax", "admin!")
+		if err != nil || created {
+			t.Errorf("expect no superuser to be created but got %v, %v", created, err)
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("given create admin user request should return 201 and store hashed password", func(t *testing.T) {
+		store := NewMockStore()
+		body, _ := json.Marshal(CreateAdminUserRequest{Username: "viewer", Password: "password1", Role: RoleViewer})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Store: store}
+		handler.CreateAdminUser(c)
+
+		if res.Result().StatusCode != http.StatusCreated {
+			t.Errorf("expected status %v but got status %v", http.StatusCreated, res.Result().StatusCode)
+		}
+		want := AdminUserResponse{Username: "viewer", Role: RoleViewer}
+		var got AdminUserResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if hash := store.Users["viewer"].PasswordHash; hash == "" || hash == "password1" {
+			t.Errorf("expect password to be hashed but got %v", hash)
+		}
+	})
+
+	t.Run("given invalid role should return 400", func(t *testing.T) {
+		body, _ := json.Marshal(CreateAdminUserRequest{Username: "viewer", Password: "password1", Role: "root"})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Store: NewMockStore()}
+		handler.CreateAdminUser(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("given existing username should return 409", func(t *testing.T) {
+		body, _ := json.Marshal(CreateAdminUserRequest{Username: "adminTax", Password: "password1", Role: RoleViewer})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Store: newStoreWithUser(t, "adminTax", "admin!", RoleSuperuser)}
+		handler.CreateAdminUser(c)
+
+		if res.Result().StatusCode != http.StatusConflict {
+			t.Errorf("expected status %v but got status %v", http.StatusConflict, res.Result().StatusCode)
+		}
+	})
+
+	t.Run("given delete own admin user should return 400", func(t *testing.T) {
+		store := newStoreWithUser(t, "adminTax", "admin!", RoleSuperuser)
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("username")
+		c.SetParamValues("adminTax")
+		c.Set(ContextKeyUsername, "adminTax")
+
+		handler := Handler{Store: store}
+		handler.DeleteAdminUser(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Result().StatusCode)
+		}
+		if _, ok := store.Users["adminTax"]; !ok {
+			t.Errorf("expect admin user not to be deleted")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ContextKeyClient = "client"
+	HeaderAPIKey     = "X-API-Key"
+)
+
+type ClientMethod string
+
+const (
+	ClientMethodAPIKey ClientMethod = "api-key"
+	ClientMethodJWT    ClientMethod = "jwt"
+)
+
+// Client is the identity of the integration calling the calculation API. Its
+// ID is namespaced by credential type, apikey:<key id> or jwt:<issuer>:<subject>,
+// so that an API key and a token subject never share an identity.
+type Client struct {
+	ID     string
+	Name   string
+	Method ClientMethod
+}
+
+// ClientFromContext returns the client attached by ClientAuthenticator, if any.
+func ClientFromContext(c echo.Context) (Client, bool) {
+	client, ok := c.Get(ContextKeyClient).(Client)
+	return client, ok
+}
+
+// ClientAuthenticator identifies calculation clients by API key or by JWT
+// bearer token. Requests without credentials are let through anonymously
+// unless Required is set, but invalid credentials are always rejected.
+type ClientAuthenticator struct {
+	APIKeys  APIKeyStore
+	JWT      *JWTValidator
+	Required bool
+}
+
+// unauthorizedError is returned for missing or invalid client credentials, as
+// opposed to failures of the key store.
+type unauthorizedError string
+
+func (e unauthorizedError) Error() string {
+	return string(e)
+}
+
+func (a *ClientAuthenticator) authenticateAPIKey(ctx context.Context, key string) (Client, error) {
+	if !looksLikeAPIKey(key) {
+		return Client{}, unauthorizedError("Invalid API key")
+	}
+	apiKey, err := a.APIKeys.GetAPIKeyByHash(ctx, HashAPIKey(key))
+	if errors.Is(err, ErrAPIKeyNotFound) || (err == nil && apiKey.Revoked()) {
+		return Client{}, unauthorizedError("Invalid API key")
+	}
+	if err != nil {
+		return Client{}, err
+	}
+	return Client{ID: "apikey:" + apiKey.ID, Name: apiKey.Name, Method: ClientMethodAPIKey}, nil
+}
+
+func (a *ClientAuthenticator) authenticateBearer(ctx context.Context, token string) (Client, error) {
+	if looksLikeAPIKey(token) {
+		return a.authenticateAPIKey(ctx, token)
+	}
+	if a.JWT == nil {
+		return Client{}, unauthorizedError("Bearer tokens are not accepted")
+	}
+	issuer, subject, err := a.JWT.Validate(token)
+	if err != nil {
+		return Client{}, unauthorizedError("Invalid bearer token")
+	}
+	return Client{ID: "jwt:" + issuer + ":" + subject, Name: subject, Method: ClientMethodJWT}, nil
+}
+
+// Authenticate identifies the client from the X-API-Key and Authorization
+// header values. It reports false for anonymous requests, which are only
+// allowed when credentials are not Required. Missing or invalid credentials
+// are reported by an error for which IsUnauthorized is true.
+func (a *ClientAuthenticator) Authenticate(ctx context.Context, key, authorization string) (Client, bool, error) {
+	var client Client
+	var err error
+	switch {
+	case key != "":
+		client, err = a.authenticateAPIKey(ctx, key)
+	case strings.HasPrefix(authorization, "Bearer "):
+		client, err = a.authenticateBearer(ctx, strings.TrimPrefix(authorization, "Bearer "))
+	case a.Required:
+		err = unauthorizedError("API key or bearer token is required")
+	default:
+		return Client{}, false, nil
+	}
+	if err != nil {
+		return Client{}, false, err
+	}
+	return client, true, nil
+}
+
+// IsUnauthorized reports whether err was caused by missing or invalid client
+// credentials.
+func IsUnauthorized(err error) bool {
+	var unauthorized unauthorizedError
+	return errors.As(err, &unauthorized)
+}
+
+func (a *ClientAuthenticator) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		client, found, err := a.Authenticate(c.Request().Context(), c.Request().Header.Get(HeaderAPIKey), c.Request().Header.Get(echo.HeaderAuthorization))
+		if IsUnauthorized(err) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return c.JSON(http.StatusUnauthorized, Err{Message: err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		}
+		if found {
+			c.Set(ContextKeyClient, client)
+		}
+		return next(c)
+	}
+}
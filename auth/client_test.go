@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type MockAPIKeyStore struct {
+	Keys map[string]APIKey
+}
+
+func NewMockAPIKeyStore() *MockAPIKeyStore {
+	return &MockAPIKeyStore{Keys: map[string]APIKey{}}
+}
+
+func (m *MockAPIKeyStore) CreateAPIKey(ctx context.Context, key APIKey) error {
+	m.Keys[key.ID] = key
+	return nil
+}
+
+func (m *MockAPIKeyStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	for _, key := range m.Keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	result := []APIKey{}
+	for _, key := range m.Keys {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func (m *MockAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	key, ok := m.Keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &revokedAt
+	m.Keys[id] = key
+	return nil
+}
+
+func serveClient(authenticator *ClientAuthenticator, req *http.Request) (*httptest.ResponseRecorder, Client, bool) {
+	res := httptest.NewRecorder()
+	c := echo.New().NewContext(req, res)
+	var client Client
+	var found bool
+	authenticator.Middleware(func(c echo.Context) error {
+		client, found = ClientFromContext(c)
+		return c.NoContent(http.StatusOK)
+	})(c)
+	return res, client, found
+}
+
+func newJWKS(t *testing.T, kid string, key *rsa.PublicKey) []byte {
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	if err != nil {
+		t.Fatalf("Unable to create jwks, error: %v", err)
+	}
+	return data
+}
+
+func TestClientAuthenticator(t *testing.T) {
+	store := NewMockAPIKeyStore()
+	plain, key, err := GenerateAPIKey("payroll-service", "adminTax", time.Now())
+	if err != nil {
+		t.Fatalf("Unable to generate api key, error: %v", err)
+	}
+	store.CreateAPIKey(context.Background(), key)
+	revokedPlain, revokedKey, _ := GenerateAPIKey("old-service", "adminTax", time.Now())
+	store.CreateAPIKey(context.Background(), revokedKey)
+	store.RevokeAPIKey(context.Background(), revokedKey.ID, time.Now())
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate rsa key, error: %v", err)
+	}
+	keys, err := ParseJWKS(newJWKS(t, "key-1", &privateKey.PublicKey))
+	if err != nil {
+		t.Fatalf("Unable to parse jwks, error: %v", err)
+	}
+	validator := &JWTValidator{Keys: keys, Issuer: "https://issuer.example"}
+	signToken := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Unable to sign token, error: %v", err)
+		}
+		return signed
+	}
+
+	authenticator := &ClientAuthenticator{APIKeys: store, JWT: validator}
+
+	t.Run("given valid api key should attach client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(HeaderAPIKey, plain)
+
+		res, client, found := serveClient(authenticator, req)
+
+		if res.Code != http.StatusOK || !found {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+		want := Client{ID: "apikey:" + key.ID, Name: "payroll-service", Method: ClientMethodAPIKey}
+		if client != want {
+			t.Errorf("expected %v but got %v", want, client)
+		}
+	})
+
+	t.Run("given revoked or unknown api key should return 401", func(t *testing.T) {
+		for _, apiKey := range []string{revokedPlain, "tax_0000000000000000_secret", "not-a-key"} {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(HeaderAPIKey, apiKey)
+
+			res, _, _ := serveClient(authenticator, req)
+
+			if res.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %v for key %v but got status %v", http.StatusUnauthorized, apiKey, res.Code)
+			}
+		}
+	})
+
+	t.Run("given no credentials should pass anonymously unless required", func(t *testing.T) {
+		res, _, found := serveClient(authenticator, httptest.NewRequest(http.MethodPost, "/", nil))
+		if res.Code != http.StatusOK || found {
+			t.Errorf("expected anonymous status %v but got status %v", http.StatusOK, res.Code)
+		}
+
+		required := &ClientAuthenticator{APIKeys: store, Required: true}
+		res, _, _ = serveClient(required, httptest.NewRequest(http.MethodPost, "/", nil))
+		if res.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %v but got status %v", http.StatusUnauthorized, res.Code)
+		}
+	})
+
+	t.Run("given valid jwt should attach subject as client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+signToken(jwt.MapClaims{
+			"sub": "accounting-app",
+			"iss": "https://issuer.example",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}))
+
+		res, client, found := serveClient(authenticator, req)
+
+		if res.Code != http.StatusOK || !found {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+		want := Client{ID: "jwt:https://issuer.example:accounting-app", Name: "accounting-app", Method: ClientMethodJWT}
+		if client != want {
+			t.Errorf("expected %v but got %v", want, client)
+		}
+	})
+
+	t.Run("given jwt with the id of an api key as subject should attach another client", func(t *testing.T) {
+		apiKeyReq := httptest.NewRequest(http.MethodPost, "/", nil)
+		apiKeyReq.Header.Set(HeaderAPIKey, plain)
+		jwtReq := httptest.NewRequest(http.MethodPost, "/", nil)
+		jwtReq.Header.Set(echo.HeaderAuthorization, "Bearer "+signToken(jwt.MapClaims{
+			"sub": key.ID,
+			"iss": "https://issuer.example",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}))
+
+		_, apiKeyClient, _ := serveClient(authenticator, apiKeyReq)
+		_, jwtClient, _ := serveClient(authenticator, jwtReq)
+
+		if apiKeyClient.ID == "" || apiKeyClient.ID == jwtClient.ID {
+			t.Errorf("expected distinct client ids but got %v and %v", apiKeyClient.ID, jwtClient.ID)
+		}
+	})
+
+	t.Run("given expired jwt or wrong issuer should return 401", func(t *testing.T) {
+		for _, claims := range []jwt.MapClaims{
+			{"sub": "accounting-app", "iss": "https://issuer.example", "exp": time.Now().Add(-time.Hour).Unix()},
+			{"sub": "accounting-app", "iss": "https://other.example", "exp": time.Now().Add(time.Hour).Unix()},
+			{"sub": "accounting-app", "iss": "https://issuer.example"},
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+signToken(claims))
+
+			res, _, _ := serveClient(authenticator, req)
+
+			if res.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %v for claims %v but got status %v", http.StatusUnauthorized, claims, res.Code)
+			}
+		}
+	})
+}
+
+func TestAPIKeyHandler(t *testing.T) {
+	t.Run("given create api key request should return 201 with plain key and store only its hash", func(t *testing.T) {
+		store := NewMockAPIKeyStore()
+		body, _ := json.Marshal(CreateAPIKeyRequest{Name: "payroll-service"})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.Set(ContextKeyUsername, "adminTax")
+
+		handler := Handler{APIKeys: store}
+		handler.CreateAPIKey(c)
+
+		if res.Result().StatusCode != http.StatusCreated {
+			t.Errorf("expected status %v but got status %v", http.StatusCreated, res.Result().StatusCode)
+		}
+		var got CreateAPIKeyResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		stored, err := store.GetAPIKeyByHash(context.Background(), HashAPIKey(got.Key))
+		if err != nil {
+			t.Errorf("expect key to be stored by hash but got %v", err)
+		}
+		if stored.Name != "payroll-service" || stored.CreatedBy != "adminTax" || stored.ID != got.ID {
+			t.Errorf("unexpected stored key %v", stored)
+		}
+	})
+
+	t.Run("given unknown api key id should return 404 on revoke", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("id")
+		c.SetParamValues("unknown")
+
+		handler := Handler{APIKeys: NewMockAPIKeyStore()}
+		handler.RevokeAPIKey(c)
+
+		if res.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("expected status %v but got status %v", http.StatusNotFound, res.Result().StatusCode)
+		}
+	})
+}
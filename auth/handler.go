@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MinPasswordLength = 8
+
+type Handler struct {
+	Store   Store
+	APIKeys APIKeyStore
+}
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+type CreateAdminUserRequest struct {
+	Username string `json:"username" example:"adminTax"`
+	Password string `json:"password" example:"change-me-please"`
+	Role     Role   `json:"role" example:"editor"`
+}
+
+type UpdateAdminUserRequest struct {
+	Password string `json:"password,omitempty" example:"change-me-please"`
+	Role     Role   `json:"role,omitempty" example:"viewer"`
+}
+
+type AdminUserResponse struct {
+	Username string `json:"username" example:"adminTax"`
+	Role     Role   `json:"role" example:"editor"`
+	Locked   bool   `json:"locked" example:"false"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" example:"payroll-service"`
+}
+
+type APIKeyResponse struct {
+	ID        string     `json:"id" example:"9f86d081884c7d65"`
+	Name      string     `json:"name" example:"payroll-service"`
+	CreatedBy string     `json:"createdBy" example:"adminTax"`
+	CreatedAt time.Time  `json:"createdAt" example:"2024-05-01T10:00:00Z"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"tax_9f86d081884c7d65_2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"`
+}
+
+func newAPIKeyResponse(key APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+func newAdminUserResponse(user AdminUser) AdminUserResponse {
+	return AdminUserResponse{
+		Username: user.Username,
+		Role:     user.Role,
+		Locked:   user.LockedUntil != nil && time.Now().Before(*user.LockedUntil),
+	}
+}
+
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return errors.New("Password must have at least 8 characters")
+	}
+	return nil
+}
+
+// ListAdminUsers
+//
+//	@Summary		List admin users
+//	@Description	List admin users with their roles, superuser only
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}	AdminUserResponse
+//	@Router			/admin/users [get]
+//	@Failure		500	{object}	Err
+//	@Failure		403	{object}	Err
+func (h *Handler) ListAdminUsers(c echo.Context) error {
+	users, err := h.Store.ListAdminUsers(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	response := []AdminUserResponse{}
+	for _, user := range users {
+		response = append(response, newAdminUserResponse(user))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CreateAdminUser
+//
+//	@Summary		Create admin user
+//	@Description	Create admin user with a role, superuser only
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	AdminUserResponse
+//	@Router			/admin/users [post]
+//	@Failure		500	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			CreateAdminUserRequest body CreateAdminUserRequest true "Body for create admin user"
+func (h *Handler) CreateAdminUser(c echo.Context) error {
+	var request CreateAdminUserRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if request.Username == "" {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Username is required"})
+	}
+	if !request.Role.Valid() {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Role must be viewer, editor or superuser"})
+	}
+	if err := validatePassword(request.Password); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	_, err := h.Store.GetAdminUser(c.Request().Context(), request.Username)
+	if err == nil {
+		return c.JSON(http.StatusConflict, Err{Message: "Admin user already exists"})
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+
+	hash, err := HashPassword(request.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	user := AdminUser{Username: request.Username, PasswordHash: hash, Role: request.Role}
+	if err := h.Store.SaveAdminUser(c.Request().Context(), user); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, newAdminUserResponse(user))
+}
+
+// UpdateAdminUser
+//
+//	@Summary		Update admin user
+//	@Description	Change password or role of an admin user and unlock it, superuser only
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	AdminUserResponse
+//	@Router			/admin/users/{username} [put]
+//	@Failure		500	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			username path string true "Admin username"
+//	@Param 			UpdateAdminUserRequest body UpdateAdminUserRequest true "Body for update admin user"
+func (h *Handler) UpdateAdminUser(c echo.Context) error {
+	var request UpdateAdminUserRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	user, err := h.Store.GetAdminUser(c.Request().Context(), c.Param("username"))
+	if errors.Is(err, ErrUserNotFound) {
+		return c.JSON(http.StatusNotFound, Err{Message: err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+
+	if request.Role != "" {
+		if !request.Role.Valid() {
+			return c.JSON(http.StatusBadRequest, Err{Message: "Role must be viewer, editor or superuser"})
+		}
+		if user.Username == c.Get(ContextKeyUsername) && request.Role != RoleSuperuser {
+			return c.JSON(http.StatusBadRequest, Err{Message: "Unable to change own role"})
+		}
+		user.Role = request.Role
+	}
+	if request.Password != "" {
+		if err := validatePassword(request.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		}
+		hash, err := HashPassword(request.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		}
+		user.PasswordHash = hash
+	}
+	user.FailedAttempts = 0
+	user.LockedUntil = nil
+
+	if err := h.Store.SaveAdminUser(c.Request().Context(), user); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// DeleteAdminUser
+//
+//	@Summary		Delete admin user
+//	@Description	Delete admin user, superuser only
+//	@Tags			admin
+//	@Success		204
+//	@Router			/admin/users/{username} [delete]
+//	@Failure		500	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			username path string true "Admin username"
+func (h *Handler) DeleteAdminUser(c echo.Context) error {
+	username := c.Param("username")
+	if username == c.Get(ContextKeyUsername) {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Unable to delete own admin user"})
+	}
+	err := h.Store.DeleteAdminUser(c.Request().Context(), username)
+	if errors.Is(err, ErrUserNotFound) {
+		return c.JSON(http.StatusNotFound, Err{Message: err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListAPIKeys
+//
+//	@Summary		List API keys
+//	@Description	List issued API keys of calculation clients without the keys themselves
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}	APIKeyResponse
+//	@Router			/admin/api-keys [get]
+//	@Failure		500	{object}	Err
+//	@Failure		403	{object}	Err
+func (h *Handler) ListAPIKeys(c echo.Context) error {
+	keys, err := h.APIKeys.ListAPIKeys(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	response := []APIKeyResponse{}
+	for _, key := range keys {
+		response = append(response, newAPIKeyResponse(key))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// CreateAPIKey
+//
+//	@Summary		Issue API key
+//	@Description	Issue API key for a calculation client, the key is only returned once
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		201	{object}	CreateAPIKeyResponse
+//	@Router			/admin/api-keys [post]
+//	@Failure		500	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			CreateAPIKeyRequest body CreateAPIKeyRequest true "Body for issue API key"
+func (h *Handler) CreateAPIKey(c echo.Context) error {
+	var request CreateAPIKeyRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if request.Name == "" {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Name is required"})
+	}
+	createdBy, _ := c.Get(ContextKeyUsername).(string)
+	plain, key, err := GenerateAPIKey(request.Name, createdBy, time.Now().UTC())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	if err := h.APIKeys.CreateAPIKey(c.Request().Context(), key); err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKeyResponse: newAPIKeyResponse(key), Key: plain})
+}
+
+// RevokeAPIKey
+//
+//	@Summary		Revoke API key
+//	@Description	Revoke API key so it can no longer be used
+//	@Tags			admin
+//	@Success		204
+//	@Router			/admin/api-keys/{id} [delete]
+//	@Failure		500	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		403	{object}	Err
+//	@Param 			id path string true "API key id"
+func (h *Handler) RevokeAPIKey(c echo.Context) error {
+	err := h.APIKeys.RevokeAPIKey(c.Request().Context(), c.Param("id"), time.Now().UTC())
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return c.JSON(http.StatusNotFound, Err{Message: err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,399 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apirom9/assessment-tax/ratelimit"
+	"github.com/apirom9/assessment-tax/tracing"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Config is the configuration of the API. Each field is loaded, from lowest
+// to highest precedence, from its default, the YAML file given by --config
+// or CONFIG_FILE, its environment variable or the file named by the variable
+// with a _FILE suffix, and its flag.
+type Config struct {
+	Port                          int           `yaml:"port" env:"PORT" flag:"port" default:"8080" usage:"HTTP port to listen on"`
+	GrpcPort                      int           `yaml:"grpcPort" env:"GRPC_PORT" flag:"grpc-port" default:"9090" usage:"gRPC port to listen on, 0 disables the gRPC API"`
+	DatabaseURL                   string        `yaml:"databaseUrl" env:"DATABASE_URL" flag:"database-url" secret:"true" usage:"Postgres connection string"`
+	AdminUsername                 string        `yaml:"adminUsername" env:"ADMIN_USERNAME" flag:"admin-username" usage:"superuser created when there are no admin users"`
+	AdminPassword                 string        `yaml:"adminPassword" env:"ADMIN_PASSWORD" flag:"admin-password" secret:"true" usage:"password of the bootstrap superuser"`
+	ReportFontPath                string        `yaml:"reportFontPath" env:"REPORT_FONT_PATH" flag:"report-font-path" default:"fonts/FreeSerif.ttf" usage:"TrueType font with Thai glyphs for PDF reports, the API does not start without it"`
+	RateLimitStore                string        `yaml:"rateLimitStore" env:"RATE_LIMIT_STORE" flag:"rate-limit-store" default:"memory" usage:"rate limit and quota store: memory or postgres"`
+	RateLimitCalculations         string        `yaml:"rateLimitCalculations" env:"RATE_LIMIT_CALCULATIONS" flag:"rate-limit-calculations" default:"60/m" usage:"rate limit per client of calculation routes"`
+	RateLimitCsv                  string        `yaml:"rateLimitCsv" env:"RATE_LIMIT_CSV" flag:"rate-limit-csv" default:"10/m" usage:"rate limit per client of CSV uploads"`
+	RateLimitCalculationsByClient string        `yaml:"rateLimitCalculationsByClient" env:"RATE_LIMIT_CALCULATIONS_BY_CLIENT" flag:"rate-limit-calculations-by-client" usage:"comma separated <client id>=<limit> overrides of rateLimitCalculations, client ids are apikey:<key id> or jwt:<issuer>:<subject>"`
+	RateLimitCsvByClient          string        `yaml:"rateLimitCsvByClient" env:"RATE_LIMIT_CSV_BY_CLIENT" flag:"rate-limit-csv-by-client" usage:"comma separated <client id>=<limit> overrides of rateLimitCsv"`
+	TrustedProxies                string        `yaml:"trustedProxies" env:"TRUSTED_PROXIES" flag:"trusted-proxies" usage:"comma separated CIDRs of proxies whose X-Forwarded-For is trusted, the peer address is the client IP without them"`
+	CsvDailyRowQuota              int           `yaml:"csvDailyRowQuota" env:"CSV_DAILY_ROW_QUOTA" flag:"csv-daily-row-quota" default:"10000" usage:"CSV rows per client and day"`
+	CsvDailyRowQuotaByClient      string        `yaml:"csvDailyRowQuotaByClient" env:"CSV_DAILY_ROW_QUOTA_BY_CLIENT" flag:"csv-daily-row-quota-by-client" usage:"comma separated <client id>=<rows> overrides of csvDailyRowQuota"`
+	RulesSigningKey               string        `yaml:"rulesSigningKey" env:"RULES_SIGNING_KEY" flag:"rules-signing-key" secret:"true" usage:"HMAC key of exported rules documents, rules export and import are disabled without it"`
+	IdempotencyTTL                time.Duration `yaml:"idempotencyTtl" env:"IDEMPOTENCY_TTL" flag:"idempotency-ttl" default:"24h" usage:"how long responses are replayed for a repeated Idempotency-Key"`
+	ClientAuthRequired            bool          `yaml:"clientAuthRequired" env:"CLIENT_AUTH_REQUIRED" flag:"client-auth-required" default:"false" usage:"reject calculation requests without API key or bearer token"`
+	JwksFile                      string        `yaml:"jwksFile" env:"JWKS_FILE" flag:"jwks-file" usage:"JWKS file to verify bearer tokens, bearer tokens are rejected without it"`
+	JwtIssuer                     string        `yaml:"jwtIssuer" env:"JWT_ISSUER" flag:"jwt-issuer" usage:"required issuer of bearer tokens"`
+	JwtAudience                   string        `yaml:"jwtAudience" env:"JWT_AUDIENCE" flag:"jwt-audience" usage:"required audience of bearer tokens"`
+	ShutdownTimeout               time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" default:"30s" usage:"time to drain requests on shutdown"`
+	LogLevel                      string        `yaml:"logLevel" env:"LOG_LEVEL" flag:"log-level" default:"info" usage:"debug, info, warn or error"`
+	LogIncome                     bool          `yaml:"logIncome" env:"LOG_INCOME" flag:"log-income" default:"false" usage:"log income amounts and errors of invalid requests instead of redacting them"`
+	TracesExporter                string        `yaml:"tracesExporter" env:"OTEL_TRACES_EXPORTER" flag:"traces-exporter" default:"none" usage:"none, otlp or stdout"`
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(number))
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolean)
+	default:
+		return fmt.Errorf("unsupported config type %v", field.Type())
+	}
+	return nil
+}
+
+// flagValue records a flag given on the command line so that only the flags
+// that were set override the environment.
+type flagValue struct {
+	value   string
+	set     bool
+	boolean bool
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.boolean
+}
+
+// each calls fn with every field of the config and its struct tags.
+func (c *Config) each(fn func(field reflect.Value, tag reflect.StructTag) error) error {
+	value := reflect.ValueOf(c).Elem()
+	for index := 0; index < value.NumField(); index++ {
+		if err := fn(value.Field(index), value.Type().Field(index).Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupEnv returns the variable, or the content of the file named by the
+// variable with the _FILE suffix, so secrets can be mounted as files.
+func lookupEnv(getenv func(string) string, name string) (string, bool, error) {
+	if path := getenv(name + "_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%v_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+	value := getenv(name)
+	return value, value != "", nil
+}
+
+// Load reads the configuration from the arguments and environment. It also
+// reports whether --print-config was given.
+func Load(args []string, getenv func(string) string) (Config, bool, error) {
+	var config Config
+	err := config.each(func(field reflect.Value, tag reflect.StructTag) error {
+		if value, ok := tag.Lookup("default"); ok {
+			return setField(field, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return config, false, err
+	}
+
+	flags := flag.NewFlagSet("tax-api", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	configFile := flags.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	printConfig := flags.Bool("print-config", false, "print the configuration with secrets redacted and exit")
+	flagValues := map[string]*flagValue{}
+	config.each(func(field reflect.Value, tag reflect.StructTag) error {
+		name := tag.Get("flag")
+		flagValues[name] = &flagValue{boolean: field.Kind() == reflect.Bool}
+		flags.Var(flagValues[name], name, fmt.Sprintf("%v (env %v, default %q)", tag.Get("usage"), tag.Get("env"), tag.Get("default")))
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return config, false, err
+	}
+
+	if *configFile != "" {
+		content, err := os.ReadFile(*configFile)
+		if err != nil {
+			return config, false, err
+		}
+		decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return config, false, fmt.Errorf("%v: %w", *configFile, err)
+		}
+	}
+
+	err = config.each(func(field reflect.Value, tag reflect.StructTag) error {
+		name := tag.Get("env")
+		value, ok, err := lookupEnv(getenv, name)
+		if err != nil || !ok {
+			return err
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return config, false, err
+	}
+
+	err = config.each(func(field reflect.Value, tag reflect.StructTag) error {
+		name := tag.Get("flag")
+		if !flagValues[name].set {
+			return nil
+		}
+		if err := setField(field, flagValues[name].value); err != nil {
+			return fmt.Errorf("--%v: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return config, false, err
+	}
+
+	return config, *printConfig, config.Validate()
+}
+
+func oneOf(name, value string, allowed ...string) error {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v must be one of %v but got %q", name, strings.Join(allowed, ", "), value)
+}
+
+// Validate reports all invalid settings at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535 but got %v", c.Port))
+	}
+	if c.GrpcPort < 0 || c.GrpcPort > 65535 || c.GrpcPort == c.Port {
+		errs = append(errs, fmt.Errorf("grpcPort must be 0 or between 1 and 65535 and differ from port but got %v", c.GrpcPort))
+	}
+	if c.DatabaseURL == "" {
+		errs = append(errs, errors.New("databaseUrl is required"))
+	}
+	if (c.AdminUsername == "") != (c.AdminPassword == "") {
+		errs = append(errs, errors.New("adminUsername and adminPassword must be set together"))
+	}
+	if err := oneOf("rateLimitStore", c.RateLimitStore, "memory", "postgres"); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := ratelimit.ParseLimit(c.RateLimitCalculations); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimitCalculations: %w", err))
+	}
+	if _, err := ratelimit.ParseLimit(c.RateLimitCsv); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimitCsv: %w", err))
+	}
+	if _, err := c.TrustedProxyRanges(); err != nil {
+		errs = append(errs, fmt.Errorf("trustedProxies: %w", err))
+	}
+	if _, err := limitOverrides(c.RateLimitCalculationsByClient); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimitCalculationsByClient: %w", err))
+	}
+	if _, err := limitOverrides(c.RateLimitCsvByClient); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimitCsvByClient: %w", err))
+	}
+	if c.CsvDailyRowQuota <= 0 {
+		errs = append(errs, fmt.Errorf("csvDailyRowQuota must be positive but got %v", c.CsvDailyRowQuota))
+	}
+	if _, err := quotaOverrides(c.CsvDailyRowQuotaByClient); err != nil {
+		errs = append(errs, fmt.Errorf("csvDailyRowQuotaByClient: %w", err))
+	}
+	if c.IdempotencyTTL <= 0 {
+		errs = append(errs, fmt.Errorf("idempotencyTtl must be positive but got %v", c.IdempotencyTTL))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdownTimeout must be positive but got %v", c.ShutdownTimeout))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if err := oneOf("tracesExporter", c.TracesExporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// SlogLevel returns the validated log level.
+func (c Config) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.LogLevel))
+	return level
+}
+
+// clientOverrides splits comma separated <client id>=<value> entries. Client
+// ids may contain "=", the value is after the last one.
+func clientOverrides(value string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		index := strings.LastIndex(entry, "=")
+		if index <= 0 {
+			return nil, fmt.Errorf("invalid override %q, expected <client id>=<value>", entry)
+		}
+		overrides[strings.TrimSpace(entry[:index])] = strings.TrimSpace(entry[index+1:])
+	}
+	return overrides, nil
+}
+
+func limitOverrides(value string) (map[string]ratelimit.Limit, error) {
+	overrides, err := clientOverrides(value)
+	if err != nil {
+		return nil, err
+	}
+	limits := map[string]ratelimit.Limit{}
+	for clientID, value := range overrides {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", clientID, err)
+		}
+		limits[clientID] = limit
+	}
+	return limits, nil
+}
+
+func quotaOverrides(value string) (map[string]int, error) {
+	overrides, err := clientOverrides(value)
+	if err != nil {
+		return nil, err
+	}
+	quotas := map[string]int{}
+	for clientID, value := range overrides {
+		quota, err := strconv.Atoi(value)
+		if err != nil || quota <= 0 {
+			return nil, fmt.Errorf("%v: quota must be a positive number but got %q", clientID, value)
+		}
+		quotas[clientID] = quota
+	}
+	return quotas, nil
+}
+
+// CalculationLimits returns the validated rate limits of calculation routes.
+func (c Config) CalculationLimits() ratelimit.Limits {
+	limit, _ := ratelimit.ParseLimit(c.RateLimitCalculations)
+	clients, _ := limitOverrides(c.RateLimitCalculationsByClient)
+	return ratelimit.Limits{Default: limit, Clients: clients}
+}
+
+// CsvLimits returns the validated rate limits of CSV uploads.
+func (c Config) CsvLimits() ratelimit.Limits {
+	limit, _ := ratelimit.ParseLimit(c.RateLimitCsv)
+	clients, _ := limitOverrides(c.RateLimitCsvByClient)
+	return ratelimit.Limits{Default: limit, Clients: clients}
+}
+
+// CsvDailyRowQuotas returns the validated CSV row quotas of clients that do
+// not get CsvDailyRowQuota.
+func (c Config) CsvDailyRowQuotas() map[string]int {
+	quotas, _ := quotaOverrides(c.CsvDailyRowQuotaByClient)
+	return quotas
+}
+
+// TrustedProxyRanges parses the CIDRs of the trusted proxies.
+func (c Config) TrustedProxyRanges() ([]*net.IPNet, error) {
+	var ranges []*net.IPNet
+	for _, cidr := range strings.Split(c.TrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, ipRange)
+	}
+	return ranges, nil
+}
+
+// IPExtractor returns how the client IP, which anonymous clients are rate
+// limited by, is found. Without trusted proxies it is the peer address, so
+// clients cannot choose it with X-Forwarded-For or X-Real-IP.
+func (c Config) IPExtractor() echo.IPExtractor {
+	ranges, _ := c.TrustedProxyRanges()
+	if len(ranges) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range ranges {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// Redacted returns a copy of the config with secrets replaced.
+func (c Config) Redacted() Config {
+	c.each(func(field reflect.Value, tag reflect.StructTag) error {
+		if tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redacted)
+		}
+		return nil
+	})
+	return c
+}
+
+// Print writes the config as YAML with secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
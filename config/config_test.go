@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string {
+		return values[name]
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unable to write file, error: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("given only database url should use defaults", func(t *testing.T) {
+		got, printConfig, err := Load(nil, env(map[string]string{"DATABASE_URL": "host=postgres"}))
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if printConfig {
+			t.Errorf("expected print config to be false")
+		}
+		if got.Port != 8080 || got.RateLimitStore != "memory" || got.CsvDailyRowQuota != 10000 || got.ShutdownTimeout != 30*time.Second || got.ClientAuthRequired {
+			t.Errorf("unexpected defaults %+v", got)
+		}
+	})
+
+	t.Run("given file, env and flags should apply them in order of precedence", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "port: 7000\nlogLevel: debug\nrateLimitCsv: 5/m\nshutdownTimeout: 10s\n")
+
+		got, _, err := Load(
+			[]string{"--config", file, "--log-level", "warn", "--client-auth-required"},
+			env(map[string]string{"DATABASE_URL": "host=postgres", "PORT": "9000", "LOG_LEVEL": "error"}),
+		)
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if got.Port != 9000 || got.LogLevel != "warn" || got.RateLimitCsv != "5/m" || got.ShutdownTimeout != 10*time.Second || !got.ClientAuthRequired {
+			t.Errorf("unexpected config %+v", got)
+		}
+	})
+
+	t.Run("given secret file variable should read the value from the file", func(t *testing.T) {
+		file := writeFile(t, "password", "s3cret!\n")
+
+		got, _, err := Load(nil, env(map[string]string{
+			"DATABASE_URL":        "host=postgres",
+			"ADMIN_USERNAME":      "adminTax",
+			"ADMIN_PASSWORD_FILE": file,
+		}))
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if got.AdminPassword != "s3cret!" {
+			t.Errorf("expected password from file but got %q", got.AdminPassword)
+		}
+	})
+
+	t.Run("given invalid settings should report all of them", func(t *testing.T) {
+		_, _, err := Load([]string{"--port", "70000"}, env(map[string]string{
+			"RATE_LIMIT_STORE":              "redis",
+			"RATE_LIMIT_CSV":                "often",
+			"RATE_LIMIT_CSV_BY_CLIENT":      "partner",
+			"CSV_DAILY_ROW_QUOTA_BY_CLIENT": "partner=-1",
+			"TRUSTED_PROXIES":               "10.0.0.0",
+			"ADMIN_USERNAME":                "adminTax",
+			"OTEL_TRACES_EXPORTER":          "zipkin",
+		}))
+
+		if err == nil {
+			t.Fatalf("expected error but got nil")
+		}
+		for _, want := range []string{"port", "databaseUrl", "adminPassword", "rateLimitStore", "rateLimitCsv", "rateLimitCsvByClient", "csvDailyRowQuotaByClient", "trustedProxies", "tracesExporter"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to mention %v but got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("given overrides by client should return limits and quotas of those clients", func(t *testing.T) {
+		got, _, err := Load(nil, env(map[string]string{
+			"DATABASE_URL":                      "host=postgres",
+			"RATE_LIMIT_CALCULATIONS_BY_CLIENT": "partner=600/m, jwt:issuer=a:user=120/m",
+			"CSV_DAILY_ROW_QUOTA_BY_CLIENT":     "partner=100000",
+		}))
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		limits := got.CalculationLimits()
+		if limits.Default.Burst != 60 || limits.Clients["partner"].Burst != 600 || limits.Clients["jwt:issuer=a:user"].Burst != 120 {
+			t.Errorf("unexpected calculation limits %+v", limits)
+		}
+		if quotas := got.CsvDailyRowQuotas(); quotas["partner"] != 100000 || len(got.CsvLimits().Clients) != 0 {
+			t.Errorf("unexpected row quotas %v and csv limits %+v", quotas, got.CsvLimits())
+		}
+	})
+
+	t.Run("given unknown key in config file should return error", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "prot: 8080\n")
+
+		_, _, err := Load([]string{"--config", file}, env(map[string]string{"DATABASE_URL": "host=postgres"}))
+
+		if err == nil {
+			t.Errorf("expected error but got nil")
+		}
+	})
+}
+
+func TestPrint(t *testing.T) {
+	t.Run("given secrets should redact them", func(t *testing.T) {
+		cfg, printConfig, err := Load([]string{"--print-config"}, env(map[string]string{
+			"DATABASE_URL":   "host=postgres password=postgres",
+			"ADMIN_USERNAME": "adminTax",
+			"ADMIN_PASSWORD": "admin!",
+		}))
+		if err != nil || !printConfig {
+			t.Fatalf("expected print config without error but got %v", err)
+		}
+		var buf bytes.Buffer
+
+		cfg.Print(&buf)
+
+		got := buf.String()
+		if strings.Contains(got, "password=postgres") || strings.Contains(got, "admin!") {
+			t.Errorf("expected secrets to be redacted but got %v", got)
+		}
+		if !strings.Contains(got, "adminUsername: adminTax") || strings.Count(got, redacted) != 2 {
+			t.Errorf("unexpected printed config %v", got)
+		}
+	})
+}
+
+func TestIPExtractor(t *testing.T) {
+	request := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "10.0.0.2:4000"
+		req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+		req.Header.Set(echo.HeaderXRealIP, "203.0.113.9")
+		return req
+	}
+
+	t.Run("given no trusted proxies should ignore forwarded headers", func(t *testing.T) {
+		if got := (Config{}).IPExtractor()(request()); got != "10.0.0.2" {
+			t.Errorf("expected peer address but got %v", got)
+		}
+	})
+
+	t.Run("given request from trusted proxy should use forwarded address", func(t *testing.T) {
+		if got := (Config{TrustedProxies: "10.0.0.0/8"}).IPExtractor()(request()); got != "203.0.113.9" {
+			t.Errorf("expected forwarded address but got %v", got)
+		}
+	})
+
+	t.Run("given request from untrusted proxy should use peer address", func(t *testing.T) {
+		if got := (Config{TrustedProxies: "192.168.0.0/16"}).IPExtractor()(request()); got != "10.0.0.2" {
+			t.Errorf("expected peer address but got %v", got)
+		}
+	})
+}
@@ -15,9 +15,43 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/deductions/k-receipt": {
-            "post": {
-                "description": "Update max k-receipt deduction",
+        "/admin/allowances": {
+            "get": {
+                "description": "List every allowance setting with its bounds under the current rule set, unit, description and ETag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "List allowance settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Description language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.AllowanceSettingsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/allowances/{type}": {
+            "put": {
+                "description": "Update an allowance setting within its bounds if If-Match is the ETag of the current setting",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,23 +61,55 @@ const docTemplate = `{
                 "tags": [
                     "tax"
                 ],
-                "summary": "Update max k-receipt deduction",
+                "summary": "Update allowance setting",
                 "parameters": [
                     {
-                        "description": "Body for update k-receipt deduction",
-                        "name": "UpdateKReceiptRequest",
+                        "type": "string",
+                        "description": "Allowance setting type, for example personal_default or kreceipt_max",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New amount of the setting",
+                        "name": "UpdateAllowanceSettingRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tax.UpdateKReceiptRequest"
+                            "$ref": "#/definitions/tax.UpdateAllowanceSettingRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the setting the update is based on",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replay the first response when the request is retried with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tax.Response"
+                            "$ref": "#/definitions/tax.AllowanceSettingResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated setting"
+                            }
                         }
                     },
                     "400": {
@@ -52,6 +118,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/tax.Err"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/tax.SettingConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -61,9 +157,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/deductions/personal": {
+        "/admin/api-keys": {
+            "get": {
+                "description": "List issued API keys of calculation clients without the keys themselves",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/auth.APIKeyResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Update personal deduction",
+                "description": "Issue API key for a calculation client, the key is only returned once",
                 "consumes": [
                     "application/json"
                 ],
@@ -71,31 +200,104 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tax"
+                    "admin"
                 ],
-                "summary": "Update personal deduction",
+                "summary": "Issue API key",
                 "parameters": [
                     {
-                        "description": "Body for update personal deduction",
-                        "name": "UpdatePersonalDeductionRequest",
+                        "description": "Body for issue API key",
+                        "name": "CreateAPIKeyRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tax.UpdatePersonalDeductionRequest"
+                            "$ref": "#/definitions/auth.CreateAPIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/tax.Response"
+                            "$ref": "#/definitions/auth.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tax.Err"
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "description": "Revoke API key so it can no longer be used",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/deductions": {
+            "get": {
+                "description": "Get current personal deduction and max k-receipt deduction with the ETags to update them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Get deductions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.DeductionsResponse"
                         }
                     },
                     "500": {
@@ -107,9 +309,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/tax/calculations": {
+        "/admin/deductions/k-receipt": {
+            "get": {
+                "description": "Get max k-receipt deduction, its ETag is returned in the ETag header. Alias of the kreceipt_max allowance setting",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Get max k-receipt deduction",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.UpdateKReceiptsResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the setting to send in If-Match"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Calculate Tax",
+                "description": "Update max k-receipt deduction if If-Match is the ETag of the current setting. Alias of the kreceipt_max allowance setting",
                 "consumes": [
                     "application/json"
                 ],
@@ -119,23 +351,48 @@ const docTemplate = `{
                 "tags": [
                     "tax"
                 ],
-                "summary": "Calculate Tax",
+                "summary": "Update max k-receipt deduction",
                 "parameters": [
                     {
-                        "description": "Body for calculation request",
-                        "name": "CalculationRequest",
+                        "description": "Body for update k-receipt deduction",
+                        "name": "UpdateKReceiptRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tax.CalculationRequest"
+                            "$ref": "#/definitions/tax.UpdateKReceiptRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the setting the update is based on",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replay the first response when the request is retried with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tax.Response"
+                            "$ref": "#/definitions/tax.UpdateKReceiptsResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated setting"
+                            }
                         }
                     },
                     "400": {
@@ -144,6 +401,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/tax.Err"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/tax.SettingConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -153,11 +434,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/tax/calculations/upload-csv": {
+        "/admin/deductions/personal": {
+            "get": {
+                "description": "Get personal deduction, its ETag is returned in the ETag header. Alias of the personal_default allowance setting",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Get personal deduction",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.UpdatePersonalDeductionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the setting to send in If-Match"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Calculate Tax for upload CSV file",
+                "description": "Update personal deduction if If-Match is the ETag of the current setting. Alias of the personal_default allowance setting",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
@@ -165,21 +476,48 @@ const docTemplate = `{
                 "tags": [
                     "tax"
                 ],
-                "summary": "Calculate Tax for upload CSV file",
+                "summary": "Update personal deduction",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Uploaded CSV for tax calculation",
-                        "name": "taxes.csv",
-                        "in": "formData",
+                        "description": "Body for update personal deduction",
+                        "name": "UpdatePersonalDeductionRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.UpdatePersonalDeductionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the setting the update is based on",
+                        "name": "If-Match",
+                        "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replay the first response when the request is retried with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tax.Response"
+                            "$ref": "#/definitions/tax.UpdatePersonalDeductionResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated setting"
+                            }
                         }
                     },
                     "400": {
@@ -188,6 +526,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/tax.Err"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/tax.SettingConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -196,23 +558,1556 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "tax.AllowanceRequest": {
-            "type": "object",
-            "properties": {
-                "allowanceType": {
+        },
+        "/admin/rules/export": {
+            "get": {
+                "description": "Export tax brackets, allowance settings and bounds of a tax year as a document signed with the rules signing key, to import it in another environment",
+                "produces": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Export rules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year, default the current tax year",
+                        "name": "year",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format (json or yaml), default json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RulesDocument"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rules/import": {
+            "post": {
+                "description": "Validate a document exported by /admin/rules/export and store the bounds of its tax year and, for the current tax year only, its allowance settings in one transaction. With dryRun only the differences from the current values are returned, with an ETag of their versions which the import must send in If-Match so values changed since the dry run are not overwritten",
+                "consumes": [
+                    "application/json",
+                    "application/yaml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Import rules",
+                "parameters": [
+                    {
+                        "description": "Exported rules document",
+                        "name": "RulesDocument",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.RulesDocument"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return the differences from the current values",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag returned by the dry run, required unless dryRun is set",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replay the first response when the request is retried with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RulesImportResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versions of the current values to send in If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rules/{year}": {
+            "get": {
+                "description": "Get the statutory bounds of a tax year, its ETag is returned in the ETag header",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Get rule set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year, for example 2567",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RuleSetResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the rule set to send in If-Match"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update the statutory bounds of a tax year, which limit the deduction settings and cap allowances in calculations for the year. Send If-None-Match: * instead of If-Match to create the rule set of a year that has none",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Update rule set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tax year, for example 2567",
+                        "name": "year",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Bounds of the tax year",
+                        "name": "RuleSetRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.RuleSetRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the rule set the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "* to create the rule set of a year that has none",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RuleSetResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the updated rule set"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/tax.RuleSetConflictResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users": {
+            "get": {
+                "description": "List admin users with their roles, superuser only",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List admin users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/auth.AdminUserResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create admin user with a role, superuser only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create admin user",
+                "parameters": [
+                    {
+                        "description": "Body for create admin user",
+                        "name": "CreateAdminUserRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.CreateAdminUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/auth.AdminUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{username}": {
+            "put": {
+                "description": "Change password or role of an admin user and unlock it, superuser only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update admin user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Body for update admin user",
+                        "name": "UpdateAdminUserRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.UpdateAdminUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.AdminUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete admin user, superuser only",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete admin user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Report that the process is up, without checking dependencies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Report whether the API can serve requests with the result of each check",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/advice": {
+            "post": {
+                "description": "Recommend how to spend a budget on k-receipt and donation to minimise tax within each cap, with the resulting tax and the headroom left per allowance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Recommend allowance spending",
+                "parameters": [
+                    {
+                        "description": "Body for advice request",
+                        "name": "AdviceRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.AdviceRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.AdviceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations": {
+            "post": {
+                "description": "Calculate Tax",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate Tax",
+                "parameters": [
+                    {
+                        "description": "Body for calculation request",
+                        "name": "CalculationRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.CalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/compare": {
+            "post": {
+                "description": "Calculate a base request and named scenario overrides, returning each result with deltas against the base and the tax saving per baht of extra allowance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Compare what-if scenarios",
+                "parameters": [
+                    {
+                        "description": "Body for compare request",
+                        "name": "CompareRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.CompareRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.CompareResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/household": {
+            "post": {
+                "description": "Calculate separate and joint filing for a taxpayer and spouse and recommend the option with less tax. Tax is after withholding tax, negative for a refund",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate tax for a married couple",
+                "parameters": [
+                    {
+                        "description": "Body for household calculation request",
+                        "name": "HouseholdRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.HouseholdRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.HouseholdResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/inverse": {
+            "post": {
+                "description": "Find the total income that gives the target net income after tax, or the target tax before withholding tax, with the same allowances",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Solve total income for a target net income or tax",
+                "parameters": [
+                    {
+                        "description": "Body for inverse calculation request",
+                        "name": "InverseCalculationRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.InverseCalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.InverseCalculationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/report": {
+            "post": {
+                "description": "Calculate Tax and download PDF summary",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate Tax and download PDF summary",
+                "parameters": [
+                    {
+                        "description": "Body for calculation request",
+                        "name": "CalculationRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.CalculationRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Report language (th or en), default th",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/calculations/upload-csv": {
+            "post": {
+                "description": "Calculate Tax for upload CSV file",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate Tax for upload CSV file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Uploaded CSV for tax calculation",
+                        "name": "taxes.csv",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Replay the first response when the request is retried with the same key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/filings/export": {
+            "post": {
+                "description": "Map a calculation onto the numbered fields of the ภ.ง.ด.91 (salary) or ภ.ง.ด.90 form, as JSON or a filled XML document",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/xml"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Export calculation as PND 91/90 form fields",
+                "parameters": [
+                    {
+                        "description": "Body for filing export request",
+                        "name": "FilingRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.FilingRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format (json or xml), default json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.FilingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        },
+        "/tax/withholding/schedule": {
+            "post": {
+                "description": "Calculate monthly withholding tax by annualizing salary and re-estimating each month, so the schedule adds up to the annual tax",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tax"
+                ],
+                "summary": "Calculate monthly withholding tax schedule",
+                "parameters": [
+                    {
+                        "description": "Body for withholding schedule request",
+                        "name": "WithholdingScheduleRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tax.WithholdingScheduleRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response language (th or en)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tax.WithholdingScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tax.Err"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2024-05-01T10:00:00Z"
+                },
+                "createdBy": {
+                    "type": "string",
+                    "example": "adminTax"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d65"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "payroll-service"
+                },
+                "revokedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.AdminUserResponse": {
+            "type": "object",
+            "properties": {
+                "locked": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "role": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.Role"
+                        }
+                    ],
+                    "example": "editor"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "adminTax"
+                }
+            }
+        },
+        "auth.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "payroll-service"
+                }
+            }
+        },
+        "auth.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2024-05-01T10:00:00Z"
+                },
+                "createdBy": {
+                    "type": "string",
+                    "example": "adminTax"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d65"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "tax_9f86d081884c7d65_2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "payroll-service"
+                },
+                "revokedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.CreateAdminUserRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "change-me-please"
+                },
+                "role": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.Role"
+                        }
+                    ],
+                    "example": "editor"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "adminTax"
+                }
+            }
+        },
+        "auth.Err": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.Role": {
+            "type": "string",
+            "enum": [
+                "viewer",
+                "editor",
+                "superuser"
+            ],
+            "x-enum-varnames": [
+                "RoleViewer",
+                "RoleEditor",
+                "RoleSuperuser"
+            ]
+        },
+        "auth.UpdateAdminUserRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "change-me-please"
+                },
+                "role": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.Role"
+                        }
+                    ],
+                    "example": "viewer"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "dial tcp 127.0.0.1:5432: connect: connection refused"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Response": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "tax.AdviceRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceRequest"
+                    }
+                },
+                "budget": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                },
+                "totalIncome": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 0
+                }
+            }
+        },
+        "tax.AdviceResponse": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceAdviceResponse"
+                    }
+                },
+                "calculation": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "recommendedSpend": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "taxSaving": {
+                    "type": "number",
+                    "example": 10000
+                }
+            }
+        },
+        "tax.AllowanceAdviceResponse": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
+                    "type": "string",
+                    "example": "k-receipt"
+                },
+                "cap": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "claimed": {
+                    "type": "number",
+                    "example": 0
+                },
+                "headroom": {
+                    "type": "number",
+                    "example": 0
+                },
+                "recommended": {
+                    "type": "number",
+                    "example": 50000
+                }
+            }
+        },
+        "tax.AllowanceRequest": {
+            "type": "object",
+            "properties": {
+                "allowanceType": {
                     "type": "string",
                     "example": "donation"
                 },
-                "amount": {
+                "amount": {
+                    "type": "number",
+                    "example": 0
+                }
+            }
+        },
+        "tax.AllowanceSettingResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Personal deduction every taxpayer receives"
+                },
+                "etag": {
+                    "type": "string",
+                    "example": "\"personal_default.1\""
+                },
+                "max": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "min": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "type": {
+                    "type": "string",
+                    "example": "personal_default"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "THB"
+                }
+            }
+        },
+        "tax.AllowanceSettingsResponse": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceSettingResponse"
+                    }
+                }
+            }
+        },
+        "tax.CalculationRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceRequest"
+                    }
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                },
+                "totalIncome": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 0
+                }
+            }
+        },
+        "tax.CompareRequest": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/tax.CalculationRequest"
+                },
+                "scenarios": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.ScenarioRequest"
+                    }
+                }
+            }
+        },
+        "tax.CompareResponse": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "scenarios": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.ScenarioResponse"
+                    }
+                }
+            }
+        },
+        "tax.DeductionsResponse": {
+            "type": "object",
+            "properties": {
+                "kReceipt": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "kReceiptEtag": {
+                    "type": "string",
+                    "example": "\"kreceipt_max.1\""
+                },
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "personalDeductionEtag": {
+                    "type": "string",
+                    "example": "\"personal_default.1\""
+                }
+            }
+        },
+        "tax.Err": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.FilingForm": {
+            "type": "string",
+            "enum": [
+                "PND91",
+                "PND90"
+            ],
+            "x-enum-varnames": [
+                "FormPND91",
+                "FormPND90"
+            ]
+        },
+        "tax.FilingMode": {
+            "type": "string",
+            "enum": [
+                "separate",
+                "joint"
+            ],
+            "x-enum-varnames": [
+                "FilingModeSeparate",
+                "FilingModeJoint"
+            ]
+        },
+        "tax.FilingRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceRequest"
+                    }
+                },
+                "incomeCategory": {
+                    "type": "string",
+                    "example": "40(1)"
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                },
+                "totalIncome": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 0
+                }
+            }
+        },
+        "tax.FilingResponse": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "form": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/tax.FilingForm"
+                        }
+                    ],
+                    "example": "PND91"
+                },
+                "incomeCategory": {
+                    "type": "string",
+                    "example": "40(1)"
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                }
+            }
+        },
+        "tax.HouseholdRequest": {
+            "type": "object",
+            "properties": {
+                "spouse": {
+                    "$ref": "#/definitions/tax.CalculationRequest"
+                },
+                "taxpayer": {
+                    "$ref": "#/definitions/tax.CalculationRequest"
+                }
+            }
+        },
+        "tax.HouseholdResponse": {
+            "type": "object",
+            "properties": {
+                "joint": {
+                    "$ref": "#/definitions/tax.JointFilingResponse"
+                },
+                "recommendation": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/tax.FilingMode"
+                        }
+                    ],
+                    "example": "separate"
+                },
+                "separate": {
+                    "$ref": "#/definitions/tax.SeparateFilingResponse"
+                },
+                "taxSaving": {
                     "type": "number",
                     "example": 0
                 }
             }
         },
-        "tax.CalculationRequest": {
+        "tax.InverseCalculationRequest": {
             "type": "object",
             "properties": {
                 "allowances": {
@@ -221,9 +2116,17 @@ const docTemplate = `{
                         "$ref": "#/definitions/tax.AllowanceRequest"
                     }
                 },
-                "totalIncome": {
+                "targetNetIncome": {
                     "type": "number",
-                    "example": 500000
+                    "example": 400000
+                },
+                "targetTax": {
+                    "type": "number",
+                    "example": 29000
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
                 },
                 "wht": {
                     "type": "number",
@@ -231,17 +2134,44 @@ const docTemplate = `{
                 }
             }
         },
-        "tax.Err": {
+        "tax.InverseCalculationResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string"
+                "calculation": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "netIncome": {
+                    "type": "number",
+                    "example": 471000
+                },
+                "totalIncome": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "totalTax": {
+                    "type": "number",
+                    "example": 29000
+                }
+            }
+        },
+        "tax.JointFilingResponse": {
+            "type": "object",
+            "properties": {
+                "calculation": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 29000
                 }
             }
         },
         "tax.Response": {
             "type": "object",
             "properties": {
+                "rates": {
+                    "$ref": "#/definitions/tax.TaxRateResponse"
+                },
                 "tax": {
                     "type": "number",
                     "example": 29000
@@ -258,6 +2188,248 @@ const docTemplate = `{
                 }
             }
         },
+        "tax.RuleChangeResponse": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "field": {
+                    "type": "string",
+                    "example": "allowances.personal_default"
+                },
+                "imported": {
+                    "type": "number",
+                    "example": 70000
+                }
+            }
+        },
+        "tax.RuleSetConflictResponse": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/tax.RuleSetResponse"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "tax.RuleSetRequest": {
+            "type": "object",
+            "properties": {
+                "donationMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceiptMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceiptMin": {
+                    "type": "number",
+                    "example": 0
+                },
+                "personalDeductionMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "personalDeductionMin": {
+                    "type": "number",
+                    "example": 10000
+                }
+            }
+        },
+        "tax.RuleSetResponse": {
+            "type": "object",
+            "properties": {
+                "donationMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceiptMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "kReceiptMin": {
+                    "type": "number",
+                    "example": 0
+                },
+                "personalDeductionMax": {
+                    "type": "number",
+                    "example": 100000
+                },
+                "personalDeductionMin": {
+                    "type": "number",
+                    "example": 10000
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                }
+            }
+        },
+        "tax.RulesBounds": {
+            "type": "object",
+            "properties": {
+                "donationMax": {
+                    "type": "number"
+                },
+                "kReceiptMax": {
+                    "type": "number"
+                },
+                "kReceiptMin": {
+                    "type": "number"
+                },
+                "personalDeductionMax": {
+                    "type": "number"
+                },
+                "personalDeductionMin": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.RulesBracket": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "tax.RulesDocument": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "bounds": {
+                    "$ref": "#/definitions/tax.RulesBounds"
+                },
+                "brackets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.RulesBracket"
+                    }
+                },
+                "checksum": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "integer"
+                },
+                "taxYear": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tax.RulesImportResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.RuleChangeResponse"
+                    }
+                },
+                "dryRun": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                }
+            }
+        },
+        "tax.ScenarioRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceRequest"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "k-receipt 50,000"
+                },
+                "totalIncome": {
+                    "type": "number",
+                    "example": 500000
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 0
+                }
+            }
+        },
+        "tax.ScenarioResponse": {
+            "type": "object",
+            "properties": {
+                "allowanceDelta": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "calculation": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "k-receipt 50,000"
+                },
+                "taxDelta": {
+                    "type": "number",
+                    "example": -5000
+                },
+                "taxSavingPerBaht": {
+                    "type": "number",
+                    "example": 0.1
+                }
+            }
+        },
+        "tax.SeparateFilingResponse": {
+            "type": "object",
+            "properties": {
+                "spouse": {
+                    "$ref": "#/definitions/tax.Response"
+                },
+                "tax": {
+                    "type": "number",
+                    "example": 29000
+                },
+                "taxpayer": {
+                    "$ref": "#/definitions/tax.Response"
+                }
+            }
+        },
+        "tax.SettingConflictResponse": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "number",
+                    "example": 60000
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
         "tax.TaxLevelResponse": {
             "type": "object",
             "properties": {
@@ -271,6 +2443,48 @@ const docTemplate = `{
                 }
             }
         },
+        "tax.TaxRateResponse": {
+            "type": "object",
+            "properties": {
+                "effectiveRate": {
+                    "type": "number",
+                    "example": 5.8
+                },
+                "effectiveRateOnNetIncome": {
+                    "type": "number",
+                    "example": 6.59
+                },
+                "incomeToNextLevel": {
+                    "type": "number",
+                    "example": 210001
+                },
+                "marginalLevel": {
+                    "type": "string",
+                    "example": "150,001 - 500,000"
+                },
+                "marginalRate": {
+                    "type": "number",
+                    "example": 10
+                },
+                "nextLevelThreshold": {
+                    "type": "number",
+                    "example": 650001
+                },
+                "taxableIncome": {
+                    "type": "number",
+                    "example": 440000
+                }
+            }
+        },
+        "tax.UpdateAllowanceSettingRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 70000
+                }
+            }
+        },
         "tax.UpdateKReceiptRequest": {
             "type": "object",
             "properties": {
@@ -280,6 +2494,15 @@ const docTemplate = `{
                 }
             }
         },
+        "tax.UpdateKReceiptsResponse": {
+            "type": "object",
+            "properties": {
+                "kReceipt": {
+                    "type": "number",
+                    "example": 29000
+                }
+            }
+        },
         "tax.UpdatePersonalDeductionRequest": {
             "type": "object",
             "properties": {
@@ -288,6 +2511,89 @@ const docTemplate = `{
                     "example": 29000
                 }
             }
+        },
+        "tax.UpdatePersonalDeductionResponse": {
+            "type": "object",
+            "properties": {
+                "personalDeduction": {
+                    "type": "number",
+                    "example": 29000
+                }
+            }
+        },
+        "tax.WithholdingMonthResponse": {
+            "type": "object",
+            "properties": {
+                "estimatedAnnualIncome": {
+                    "type": "number",
+                    "example": 600000
+                },
+                "estimatedAnnualTax": {
+                    "type": "number",
+                    "example": 39000
+                },
+                "income": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "month": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "wht": {
+                    "type": "number",
+                    "example": 3250
+                }
+            }
+        },
+        "tax.WithholdingScheduleRequest": {
+            "type": "object",
+            "properties": {
+                "allowances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.AllowanceRequest"
+                    }
+                },
+                "monthlyIncomes": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    },
+                    "example": [
+                        50000,
+                        50000,
+                        60000
+                    ]
+                },
+                "taxYear": {
+                    "type": "integer",
+                    "example": 2567
+                }
+            }
+        },
+        "tax.WithholdingScheduleResponse": {
+            "type": "object",
+            "properties": {
+                "annualIncome": {
+                    "type": "number",
+                    "example": 600000
+                },
+                "annualTax": {
+                    "type": "number",
+                    "example": 39000
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/tax.WithholdingMonthResponse"
+                    }
+                },
+                "totalWht": {
+                    "type": "number",
+                    "example": 39000
+                }
+            }
         }
     }
 }`
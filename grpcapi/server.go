@@ -0,0 +1,390 @@
+// Package grpcapi serves TaxService over gRPC with the same business logic,
+// credentials and store as the REST API.
+package grpcapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/apirom9/assessment-tax/ratelimit"
+	"github.com/apirom9/assessment-tax/tax"
+	"github.com/apirom9/assessment-tax/taxpb"
+	"github.com/apirom9/assessment-tax/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	metadataAPIKey         = "x-api-key"
+	metadataAuthorization  = "authorization"
+	metadataAcceptLanguage = "accept-language"
+	metadataRetryAfter     = "retry-after"
+)
+
+// adminRoles are the roles required by the admin methods, the same as their
+// REST counterparts. Other methods are authenticated as calculation clients.
+var adminRoles = map[string]auth.Role{
+	taxpb.TaxService_GetSettings_FullMethodName:     auth.RoleViewer,
+	taxpb.TaxService_UpdateDeduction_FullMethodName: auth.RoleEditor,
+}
+
+// limitedRoutes are the REST routes whose rate limit buckets the methods
+// share. Every request of a stream takes a token.
+var limitedRoutes = map[string]string{
+	taxpb.TaxService_Calculate_FullMethodName:      "calculations",
+	taxpb.TaxService_CalculateBatch_FullMethodName: "calculations",
+}
+
+// quotaMethods are the methods whose every request is a row of the daily CSV
+// row quota, like a row of an uploaded CSV file.
+var quotaMethods = map[string]bool{
+	taxpb.TaxService_CalculateBatch_FullMethodName: true,
+}
+
+// deductionSettings are the allowance settings of the deduction types.
+var deductionSettings = map[taxpb.DeductionType]string{
+	taxpb.DeductionType_DEDUCTION_TYPE_PERSONAL:  tax.SettingPersonalDeduction,
+	taxpb.DeductionType_DEDUCTION_TYPE_K_RECEIPT: tax.SettingMaxKReceipt,
+}
+
+type contextKey string
+
+const contextKeyClient contextKey = "client"
+
+// ClientFromContext returns the calculation client authenticated for the
+// call, if any.
+func ClientFromContext(ctx context.Context) (auth.Client, bool) {
+	client, ok := ctx.Value(contextKeyClient).(auth.Client)
+	return client, ok
+}
+
+type Server struct {
+	taxpb.UnimplementedTaxServiceServer
+	Service *tax.TaxService
+	Clients *auth.ClientAuthenticator
+	Admins  *auth.Authenticator
+	Logger  *slog.Logger
+	// Limiter and RowQuota, when set, limit the calculation methods with the
+	// same buckets and quota as the REST API.
+	Limiter           *ratelimit.Limiter
+	CalculationLimits ratelimit.Limits
+	RowQuota          *ratelimit.Quota
+}
+
+// NewGRPCServer returns a gRPC server with the tax service registered behind
+// authentication and tracing.
+func NewGRPCServer(s *Server) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	)
+	taxpb.RegisterTaxServiceServer(server, s)
+	return server
+}
+
+// Shutdown stops accepting calls and waits for in-flight calls until ctx is
+// done, then closes the remaining connections.
+func Shutdown(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func locale(ctx context.Context) tax.Locale {
+	return tax.GetLocale(tax.ParseAcceptLanguage(metadataValue(ctx, metadataAcceptLanguage)))
+}
+
+func parseBasicAuth(authorization string) (string, string, bool) {
+	encoded, found := strings.CutPrefix(authorization, "Basic ")
+	if !found {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if required, ok := adminRoles[method]; ok {
+		username, password, ok := parseBasicAuth(metadataValue(ctx, metadataAuthorization))
+		if !ok {
+			return ctx, status.Error(codes.Unauthenticated, "Basic credentials are required")
+		}
+		user, ok, err := s.Admins.Authenticate(ctx, username, password)
+		if err != nil {
+			return ctx, s.internalError(ctx, err)
+		}
+		if !ok {
+			return ctx, status.Error(codes.Unauthenticated, "Invalid credentials")
+		}
+		if !user.Role.Allows(required) {
+			return ctx, status.Error(codes.PermissionDenied, "Role "+string(required)+" is required")
+		}
+		return ctx, nil
+	}
+
+	client, found, err := s.Clients.Authenticate(ctx, metadataValue(ctx, metadataAPIKey), metadataValue(ctx, metadataAuthorization))
+	if auth.IsUnauthorized(err) {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return ctx, s.internalError(ctx, err)
+	}
+	if found {
+		ctx = context.WithValue(ctx, contextKeyClient, client)
+	}
+	return ctx, nil
+}
+
+// callerKey returns the rate limit key of the caller, the same as the one of
+// a REST request of the client or from the address.
+func callerKey(ctx context.Context) string {
+	if client, ok := ClientFromContext(ctx); ok {
+		return ratelimit.Key(client.ID, "")
+	}
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	return ratelimit.Key("", ip)
+}
+
+func exhausted(ctx context.Context, retryAfter time.Duration, message string) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	grpc.SetTrailer(ctx, metadata.Pairs(metadataRetryAfter, strconv.Itoa(seconds)))
+	return status.Error(codes.ResourceExhausted, message)
+}
+
+// limit takes a token of the method's bucket and a row of the quota for a
+// request of the method.
+func (s *Server) limit(ctx context.Context, method string) error {
+	if route, ok := limitedRoutes[method]; ok && s.Limiter != nil {
+		decision, err := s.Limiter.Take(route, callerKey(ctx), s.CalculationLimits)
+		if err != nil {
+			return s.internalError(ctx, err)
+		}
+		if !decision.Allowed {
+			return exhausted(ctx, decision.RetryAfter, "Too many requests")
+		}
+	}
+	if quotaMethods[method] && s.RowQuota != nil {
+		decision, err := s.RowQuota.Consume(callerKey(ctx), 1)
+		if err != nil {
+			return s.internalError(ctx, err)
+		}
+		if !decision.Allowed {
+			return exhausted(ctx, decision.RetryAfter, locale(ctx).Message(tax.MsgCsvQuotaExceeded))
+		}
+	}
+	return nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticatedStream carries the authenticated context of a stream and
+// limits every request received on it.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	server *Server
+	method string
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s authenticatedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.server.limit(s.ctx, s.method)
+}
+
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, authenticatedStream{ServerStream: stream, ctx: ctx, server: s, method: info.FullMethod})
+}
+
+func (s *Server) internalError(ctx context.Context, err error) error {
+	s.Logger.ErrorContext(ctx, "grpc call failed", slog.String("error", err.Error()),
+		slog.String("trace_id", tracing.TraceID(ctx)), slog.String("span_id", tracing.SpanID(ctx)))
+	return status.Error(codes.Internal, err.Error())
+}
+
+// callError maps invalid input to InvalidArgument, unknown settings to
+// NotFound and stale versions to Aborted, with a localized message.
+func (s *Server) callError(ctx context.Context, err error) error {
+	switch tax.ErrorKindOf(err) {
+	case tax.ErrorKindInvalidInput:
+		return status.Error(codes.InvalidArgument, locale(ctx).ErrorMessage(err))
+	case tax.ErrorKindNotFound:
+		return status.Error(codes.NotFound, locale(ctx).ErrorMessage(err))
+	case tax.ErrorKindConflict:
+		return status.Error(codes.Aborted, locale(ctx).ErrorMessage(err))
+	}
+	return s.internalError(ctx, err)
+}
+
+func fromCalculateRequest(req *taxpb.CalculateRequest) tax.CalculationRequest {
+	request := tax.CalculationRequest{
+		TotalIncome:    req.GetTotalIncome(),
+		WithHoldingTax: req.GetWht(),
+		TaxYear:        int(req.GetTaxYear()),
+	}
+	for _, allowance := range req.GetAllowances() {
+		request.Allowances = append(request.Allowances, tax.AllowanceRequest{
+			Type:   allowance.GetAllowanceType(),
+			Amount: allowance.GetAmount(),
+		})
+	}
+	return request
+}
+
+func toCalculateResponse(response tax.Response) *taxpb.CalculateResponse {
+	result := &taxpb.CalculateResponse{
+		Tax:       response.Tax,
+		TaxRefund: response.TaxRefund,
+		Rates: &taxpb.TaxRates{
+			TaxableIncome:            response.Rates.TaxableIncome,
+			EffectiveRate:            response.Rates.EffectiveRate,
+			EffectiveRateOnNetIncome: response.Rates.EffectiveRateOnNet,
+			MarginalLevel:            response.Rates.MarginalLevel,
+			MarginalRate:             response.Rates.MarginalRate,
+			NextLevelThreshold:       response.Rates.NextLevelThreshold,
+			IncomeToNextLevel:        response.Rates.IncomeToNextLevel,
+		},
+	}
+	for _, level := range response.TaxLevelResponses {
+		result.TaxLevels = append(result.TaxLevels, &taxpb.TaxLevel{Level: level.Level, Tax: level.TaxAmount})
+	}
+	return result
+}
+
+func (s *Server) Calculate(ctx context.Context, req *taxpb.CalculateRequest) (*taxpb.CalculateResponse, error) {
+	calculation, err := s.Service.Calculate(ctx, fromCalculateRequest(req))
+	if err != nil {
+		return nil, s.callError(ctx, err)
+	}
+	return toCalculateResponse(tax.NewResponseFromResult(calculation.Calculator, calculation.Result, locale(ctx))), nil
+}
+
+func (s *Server) CalculateBatch(stream taxpb.TaxService_CalculateBatchServer) error {
+	ctx := stream.Context()
+	calculators := map[int]tax.Calulator{}
+	for index := int32(0); ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		response := &taxpb.CalculateBatchResponse{Index: index}
+		request := fromCalculateRequest(req)
+		calculator, ok := calculators[request.CalculationYear()]
+		if !ok {
+			calculator, err = s.Service.NewCalculator(ctx, request.CalculationYear())
+			if err == nil {
+				calculators[request.CalculationYear()] = calculator
+			}
+		}
+		var calculation tax.Calculation
+		if err == nil {
+			calculation, err = s.Service.CalculateWith(ctx, calculator, request)
+		}
+		switch {
+		case tax.ErrorKindOf(err) == tax.ErrorKindInvalidInput:
+			response.Outcome = &taxpb.CalculateBatchResponse_Error{Error: locale(ctx).ErrorMessage(err)}
+		case err != nil:
+			return s.internalError(ctx, err)
+		default:
+			response.Outcome = &taxpb.CalculateBatchResponse_Result{
+				Result: toCalculateResponse(tax.NewResponseFromResult(calculation.Calculator, calculation.Result, locale(ctx))),
+			}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) settings(ctx context.Context) (*taxpb.Settings, error) {
+	deductions, err := s.Service.Deductions(ctx)
+	if err != nil {
+		return nil, s.internalError(ctx, err)
+	}
+	return &taxpb.Settings{
+		PersonalDeduction:     deductions.PersonalDeduction,
+		KReceipt:              deductions.KReceipt,
+		PersonalDeductionEtag: tax.SettingETag(tax.SettingPersonalDeduction, deductions.PersonalDeductionVersion),
+		KReceiptEtag:          tax.SettingETag(tax.SettingMaxKReceipt, deductions.KReceiptVersion),
+	}, nil
+}
+
+func (s *Server) GetSettings(ctx context.Context, req *taxpb.GetSettingsRequest) (*taxpb.Settings, error) {
+	return s.settings(ctx)
+}
+
+func (s *Server) UpdateDeduction(ctx context.Context, req *taxpb.UpdateDeductionRequest) (*taxpb.Settings, error) {
+	if req.GetEtag() == "" {
+		return nil, status.Error(codes.FailedPrecondition, locale(ctx).Message(tax.MsgIfMatchRequired))
+	}
+	settingType, ok := deductionSettings[req.GetType()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown deduction type %v", req.GetType())
+	}
+	_, err := s.Service.UpdateAllowanceSetting(ctx, settingType, req.GetAmount(), tax.ParseSettingETag(settingType, req.GetEtag()))
+	if err != nil {
+		return nil, s.callError(ctx, err)
+	}
+	return s.settings(ctx)
+}
@@ -0,0 +1,387 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/apirom9/assessment-tax/ratelimit"
+	"github.com/apirom9/assessment-tax/tax"
+	"github.com/apirom9/assessment-tax/taxpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type MockTaxStore struct {
+	PersonalDeduction tax.Setting
+	KReceipt          tax.Setting
+	Reads             int
+}
+
+func (m *MockTaxStore) GetRuleSet(ctx context.Context, taxYear int) (tax.RuleSet, error) {
+	if taxYear != tax.DefaultRuleSet.TaxYear {
+		return tax.RuleSet{}, tax.ErrRuleSetNotFound
+	}
+	return tax.DefaultRuleSet, nil
+}
+
+func (m *MockTaxStore) UpdateRuleSet(ctx context.Context, rules tax.RuleSet, version int) (tax.RuleSet, bool, error) {
+	return tax.RuleSet{}, false, errors.New("not implemented")
+}
+
+func (m *MockTaxStore) CreateRuleSet(ctx context.Context, rules tax.RuleSet) (tax.RuleSet, bool, error) {
+	return tax.RuleSet{}, false, errors.New("not implemented")
+}
+
+func (m *MockTaxStore) ApplyRules(ctx context.Context, rules tax.RuleSet, allowances map[string]float64, versions tax.RulesVersions) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (m *MockTaxStore) setting(allowanceType string) *tax.Setting {
+	if allowanceType == tax.SettingMaxKReceipt {
+		return &m.KReceipt
+	}
+	return &m.PersonalDeduction
+}
+
+func (m *MockTaxStore) GetAllowanceSetting(ctx context.Context, allowanceType string) (tax.Setting, error) {
+	m.Reads++
+	return *m.setting(allowanceType), nil
+}
+
+func (m *MockTaxStore) UpdateAllowanceSetting(ctx context.Context, allowanceType string, value float64, version int) (tax.Setting, bool, error) {
+	setting := m.setting(allowanceType)
+	if version != setting.Version {
+		return *setting, false, nil
+	}
+	*setting = tax.Setting{Amount: value, Version: version + 1}
+	return *setting, true, nil
+}
+
+type MockAdminStore struct {
+	Users map[string]auth.AdminUser
+}
+
+func (m *MockAdminStore) GetAdminUser(ctx context.Context, username string) (auth.AdminUser, error) {
+	user, ok := m.Users[username]
+	if !ok {
+		return auth.AdminUser{}, auth.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *MockAdminStore) ListAdminUsers(ctx context.Context) ([]auth.AdminUser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAdminStore) SaveAdminUser(ctx context.Context, user auth.AdminUser) error {
+	m.Users[user.Username] = user
+	return nil
+}
+
+func (m *MockAdminStore) DeleteAdminUser(ctx context.Context, username string) error {
+	delete(m.Users, username)
+	return nil
+}
+
+func (m *MockAdminStore) CountAdminUsers(ctx context.Context) (int, error) {
+	return len(m.Users), nil
+}
+
+func (m *MockAdminStore) RecordFailedLogin(ctx context.Context, username string, now time.Time, maxFailedAttempts int, lockUntil time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *MockAdminStore) ResetFailedLogins(ctx context.Context, username string) error {
+	return nil
+}
+
+func newClient(t *testing.T, clients *auth.ClientAuthenticator, options ...func(*Server)) (taxpb.TaxServiceClient, *MockTaxStore) {
+	store := &MockTaxStore{PersonalDeduction: tax.Setting{Amount: 60000, Version: 1}, KReceipt: tax.Setting{Amount: 50000, Version: 1}}
+	admins := &MockAdminStore{Users: map[string]auth.AdminUser{}}
+	for username, role := range map[string]auth.Role{"viewer": auth.RoleViewer, "editor": auth.RoleEditor} {
+		hash, err := auth.HashPassword("password")
+		if err != nil {
+			t.Fatalf("Unable to hash password, error: %v", err)
+		}
+		admins.SaveAdminUser(context.Background(), auth.AdminUser{Username: username, PasswordHash: hash, Role: role})
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	s := &Server{
+		Service: &tax.TaxService{Store: store},
+		Clients: clients,
+		Admins:  auth.NewAuthenticator(admins),
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	server := NewGRPCServer(s)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Unable to dial, error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return taxpb.NewTaxServiceClient(conn), store
+}
+
+func withBasicAuth(ctx context.Context, username string) context.Context {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":password"))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+credentials)
+}
+
+func TestCalculate(t *testing.T) {
+	client, _ := newClient(t, &auth.ClientAuthenticator{})
+
+	t.Run("given request with allowances should return the same result as the REST API", func(t *testing.T) {
+		got, err := client.Calculate(context.Background(), &taxpb.CalculateRequest{
+			TotalIncome: 500000,
+			Allowances:  []*taxpb.Allowance{{AllowanceType: "donation", Amount: 200000}},
+		})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if got.Tax != 19000 || len(got.TaxLevels) != 5 || got.TaxLevels[1].Tax != 19000 || got.Rates.MarginalRate != 10 {
+			t.Errorf("unexpected response %v", got)
+		}
+	})
+
+	t.Run("given unknown allowance should return InvalidArgument in the requested language", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "en")
+		_, err := client.Calculate(ctx, &taxpb.CalculateRequest{
+			TotalIncome: 500000,
+			Allowances:  []*taxpb.Allowance{{AllowanceType: "lottery", Amount: 100}},
+		})
+
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected code %v but got %v", codes.InvalidArgument, err)
+		}
+		want := tax.GetLocale(tax.LanguageEnglish).Message(tax.MsgUnknownAllowanceType, "lottery")
+		if status.Convert(err).Message() != want {
+			t.Errorf("expected message %q but got %q", want, status.Convert(err).Message())
+		}
+	})
+
+	t.Run("given client credentials are required should return Unauthenticated without them", func(t *testing.T) {
+		required, _ := newClient(t, &auth.ClientAuthenticator{Required: true})
+
+		_, err := required.Calculate(context.Background(), &taxpb.CalculateRequest{TotalIncome: 500000})
+
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected code %v but got %v", codes.Unauthenticated, err)
+		}
+	})
+}
+
+func TestCalculateBatch(t *testing.T) {
+	t.Run("given stream of requests should answer each in order and report invalid ones", func(t *testing.T) {
+		client, _ := newClient(t, &auth.ClientAuthenticator{})
+		stream, err := client.CalculateBatch(context.Background())
+		if err != nil {
+			t.Fatalf("Unable to open stream, error: %v", err)
+		}
+		requests := []*taxpb.CalculateRequest{
+			{TotalIncome: 500000},
+			{TotalIncome: 500000, Allowances: []*taxpb.Allowance{{AllowanceType: "lottery", Amount: 100}}},
+			{TotalIncome: 500000, Wht: 25000},
+			{TotalIncome: 500000, TaxYear: 2568},
+		}
+		for _, request := range requests {
+			if err := stream.Send(request); err != nil {
+				t.Fatalf("Unable to send, error: %v", err)
+			}
+		}
+		stream.CloseSend()
+
+		var got []*taxpb.CalculateBatchResponse
+		for {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unable to receive, error: %v", err)
+			}
+			got = append(got, response)
+		}
+
+		if len(got) != 4 {
+			t.Fatalf("expected 4 responses but got %v", len(got))
+		}
+		if got[0].GetIndex() != 0 || got[0].GetResult().GetTax() != 29000 {
+			t.Errorf("unexpected first response %v", got[0])
+		}
+		if got[1].GetIndex() != 1 || got[1].GetError() == "" {
+			t.Errorf("expected error for second request but got %v", got[1])
+		}
+		if got[2].GetIndex() != 2 || got[2].GetResult().GetTax() != 4000 {
+			t.Errorf("unexpected third response %v", got[2])
+		}
+		if got[3].GetIndex() != 3 || got[3].GetError() == "" {
+			t.Errorf("expected error for tax year without rules but got %v", got[3])
+		}
+	})
+
+	t.Run("given stream beyond the row quota should read settings once and stop with resource exhausted", func(t *testing.T) {
+		client, store := newClient(t, &auth.ClientAuthenticator{}, func(s *Server) {
+			s.RowQuota = ratelimit.NewQuota(ratelimit.NewMemoryStore(), "csv-rows", 2)
+		})
+		stream, err := client.CalculateBatch(context.Background())
+		if err != nil {
+			t.Fatalf("Unable to open stream, error: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			stream.Send(&taxpb.CalculateRequest{TotalIncome: 500000})
+		}
+		stream.CloseSend()
+
+		var responses int
+		for {
+			_, err = stream.Recv()
+			if err != nil {
+				break
+			}
+			responses++
+		}
+
+		if responses != 2 || status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected 2 responses and code %v but got %v and %v", codes.ResourceExhausted, responses, err)
+		}
+		if store.Reads != 2 {
+			t.Errorf("expected both settings to be read once but got %v reads", store.Reads)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("given calls beyond the calculation limit should return resource exhausted with retry after", func(t *testing.T) {
+		client, _ := newClient(t, &auth.ClientAuthenticator{}, func(s *Server) {
+			s.Limiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+			s.CalculationLimits = ratelimit.Limits{Default: ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}}
+		})
+
+		var err error
+		var trailer metadata.MD
+		for i := 0; i < 3 && err == nil; i++ {
+			_, err = client.Calculate(context.Background(), &taxpb.CalculateRequest{TotalIncome: 500000}, grpc.Trailer(&trailer))
+		}
+
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected code %v but got %v", codes.ResourceExhausted, err)
+		}
+		if got := trailer.Get("retry-after"); len(got) != 1 || got[0] != "60" {
+			t.Errorf("expected retry-after 60 but got %v", got)
+		}
+	})
+}
+
+func TestSettings(t *testing.T) {
+	t.Run("given viewer should get settings", func(t *testing.T) {
+		client, _ := newClient(t, &auth.ClientAuthenticator{})
+
+		got, err := client.GetSettings(withBasicAuth(context.Background(), "viewer"), &taxpb.GetSettingsRequest{})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if got.PersonalDeduction != 60000 || got.KReceipt != 50000 {
+			t.Errorf("unexpected settings %v", got)
+		}
+	})
+
+	t.Run("given no credentials should return Unauthenticated", func(t *testing.T) {
+		client, _ := newClient(t, &auth.ClientAuthenticator{})
+
+		_, err := client.GetSettings(context.Background(), &taxpb.GetSettingsRequest{})
+
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected code %v but got %v", codes.Unauthenticated, err)
+		}
+	})
+
+	t.Run("given viewer should not update deduction", func(t *testing.T) {
+		client, _ := newClient(t, &auth.ClientAuthenticator{})
+
+		_, err := client.UpdateDeduction(withBasicAuth(context.Background(), "viewer"), &taxpb.UpdateDeductionRequest{
+			Type:   taxpb.DeductionType_DEDUCTION_TYPE_PERSONAL,
+			Amount: 70000,
+		})
+
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected code %v but got %v", codes.PermissionDenied, err)
+		}
+	})
+
+	t.Run("given editor should update deduction within bounds", func(t *testing.T) {
+		client, store := newClient(t, &auth.ClientAuthenticator{})
+		ctx := withBasicAuth(context.Background(), "editor")
+
+		got, err := client.UpdateDeduction(ctx, &taxpb.UpdateDeductionRequest{
+			Type:   taxpb.DeductionType_DEDUCTION_TYPE_K_RECEIPT,
+			Amount: 70000,
+			Etag:   `"kreceipt_max.1"`,
+		})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if got.KReceipt != 70000 || store.KReceipt.Amount != 70000 || got.KReceiptEtag != `"kreceipt_max.2"` {
+			t.Errorf("expected k-receipt to be updated but got %v", got)
+		}
+
+		_, err = client.UpdateDeduction(ctx, &taxpb.UpdateDeductionRequest{
+			Type:   taxpb.DeductionType_DEDUCTION_TYPE_PERSONAL,
+			Amount: 5000,
+			Etag:   `"personal_default.1"`,
+		})
+
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected code %v but got %v", codes.InvalidArgument, err)
+		}
+	})
+
+	t.Run("given stale or missing etag should not update deduction", func(t *testing.T) {
+		client, store := newClient(t, &auth.ClientAuthenticator{})
+		ctx := withBasicAuth(context.Background(), "editor")
+
+		_, err := client.UpdateDeduction(ctx, &taxpb.UpdateDeductionRequest{
+			Type:   taxpb.DeductionType_DEDUCTION_TYPE_PERSONAL,
+			Amount: 70000,
+			Etag:   `"personal_default.0"`,
+		})
+
+		if status.Code(err) != codes.Aborted {
+			t.Errorf("expected code %v but got %v", codes.Aborted, err)
+		}
+
+		_, err = client.UpdateDeduction(ctx, &taxpb.UpdateDeductionRequest{
+			Type:   taxpb.DeductionType_DEDUCTION_TYPE_PERSONAL,
+			Amount: 70000,
+		})
+
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected code %v but got %v", codes.FailedPrecondition, err)
+		}
+		if store.PersonalDeduction.Amount != 60000 {
+			t.Errorf("expected personal deduction to stay 60000 but got %v", store.PersonalDeduction.Amount)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+const DefaultCheckTimeout = 2 * time.Second
+
+// CheckFunc reports why a dependency is not ready, or nil when it is.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty" example:"dial tcp 127.0.0.1:5432: connect: connection refused"`
+}
+
+type Response struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs the readiness checks. Checks run concurrently, each within
+// Timeout.
+type Checker struct {
+	Timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker() *Checker {
+	return &Checker{Timeout: DefaultCheckTimeout}
+}
+
+func (h *Checker) Add(name string, check CheckFunc) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+func (h *Checker) Run(ctx context.Context) Response {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	response := Response{Status: StatusOK, Checks: map[string]CheckResult{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+			result := CheckResult{Status: StatusOK}
+			if err := check.check(ctx); err != nil {
+				result = CheckResult{Status: StatusUnavailable, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.name] = result
+			if result.Status != StatusOK {
+				response.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+	return response
+}
+
+// Healthz
+//
+//	@Summary		Liveness
+//	@Description	Report that the process is up, without checking dependencies
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	Response
+//	@Router			/healthz [get]
+func (h *Checker) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, Response{Status: StatusOK})
+}
+
+// Readyz
+//
+//	@Summary		Readiness
+//	@Description	Report whether the API can serve requests with the result of each check
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	Response
+//	@Failure		503	{object}	Response
+//	@Router			/readyz [get]
+func (h *Checker) Readyz(c echo.Context) error {
+	response := h.Run(c.Request().Context())
+	if response.Status != StatusOK {
+		return c.JSON(http.StatusServiceUnavailable, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// Flag is a check that fails until it is set, for startup work running in
+// the background.
+type Flag struct {
+	done    atomic.Bool
+	pending string
+}
+
+func NewFlag(pending string) *Flag {
+	return &Flag{pending: pending}
+}
+
+func (f *Flag) Set() {
+	f.done.Store(true)
+}
+
+func (f *Flag) Check(ctx context.Context) error {
+	if !f.done.Load() {
+		return errors.New(f.pending)
+	}
+	return nil
+}
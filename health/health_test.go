@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("given all checks pass should return 200 with each check", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error { return nil })
+		checker.Add("migrations", func(ctx context.Context) error { return nil })
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), res)
+
+		checker.Readyz(c)
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+		want := Response{Status: StatusOK, Checks: map[string]CheckResult{
+			"database":   {Status: StatusOK},
+			"migrations": {Status: StatusOK},
+		}}
+		var got Response
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given a failing check should return 503 with its error", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error { return errors.New("connection refused") })
+		startup := NewFlag("waiting for database")
+		checker.Add("startup", startup.Check)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), res)
+
+		checker.Readyz(c)
+
+		if res.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %v but got status %v", http.StatusServiceUnavailable, res.Code)
+		}
+		want := Response{Status: StatusUnavailable, Checks: map[string]CheckResult{
+			"database": {Status: StatusUnavailable, Error: "connection refused"},
+			"startup":  {Status: StatusUnavailable, Error: "waiting for database"},
+		}}
+		var got Response
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+
+		startup.Set()
+		if err := startup.Check(context.Background()); err != nil {
+			t.Errorf("expect flag check to pass once set but got %v", err)
+		}
+	})
+
+	t.Run("given healthz should return 200 without running checks", func(t *testing.T) {
+		checker := NewChecker()
+		checker.Add("database", func(ctx context.Context) error {
+			t.Errorf("expect checks not to run for healthz")
+			return nil
+		})
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/healthz", nil), res)
+
+		checker.Healthz(c)
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+	})
+}
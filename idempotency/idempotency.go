@@ -0,0 +1,236 @@
+// Package idempotency replays the first response to a request carrying an
+// Idempotency-Key header, so clients can safely retry mutations after a
+// network failure.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/apirom9/assessment-tax/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	HeaderIfMatch            = "If-Match"
+	HeaderETag               = "ETag"
+
+	MaxKeyLength       = 255
+	DefaultTTL         = 24 * time.Hour
+	DefaultLockTimeout = 5 * time.Minute
+)
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+// Record is the first request made with a key and, once it completed, its
+// response.
+type Record struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	// StatusCode is zero while the first request is in progress.
+	StatusCode  int
+	ContentType string
+	// ETag and ContentDisposition are replayed with the body, so a client
+	// retrying an update still learns the version to send in If-Match.
+	ETag               string
+	ContentDisposition string
+	Body               []byte
+	ExpiresAt          time.Time
+}
+
+func (r Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+type Store interface {
+	// ReserveIdempotencyKey saves the record unless a record of the same scope
+	// and key that has not expired at now exists, which is returned instead.
+	// It reports whether the record was saved.
+	ReserveIdempotencyKey(ctx context.Context, record Record, now time.Time) (Record, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, record Record) error
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error
+}
+
+// Idempotency stores responses for TTL. A request still in progress blocks
+// retries for at most LockTimeout, in case the replica handling it died.
+type Idempotency struct {
+	Store       Store
+	TTL         time.Duration
+	LockTimeout time.Duration
+	Now         func() time.Time
+}
+
+func New(store Store, ttl time.Duration) *Idempotency {
+	return &Idempotency{Store: store, TTL: ttl, LockTimeout: DefaultLockTimeout, Now: time.Now}
+}
+
+// scope keeps the keys of different routes and callers apart.
+func scope(c echo.Context) string {
+	caller := ratelimit.ClientKey(c)
+	if username, ok := c.Get(auth.ContextKeyUsername).(string); ok {
+		caller = "admin:" + username
+	}
+	return c.Request().Method + " " + c.Path() + " " + caller
+}
+
+// RequestHash returns the hash of the request body and If-Match header and
+// restores the body for the handler. The same update made against another
+// version is another request. Multipart boundaries are chosen anew by
+// clients on every attempt, so multipart bodies are hashed part by part
+// instead.
+func RequestHash(req *http.Request) (string, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.New()
+	if ifMatch := req.Header.Get(HeaderIfMatch); ifMatch != "" {
+		fmt.Fprintf(hash, "If-Match: %q\n", ifMatch)
+	}
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		hash.Write(body)
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return hex.EncodeToString(hash.Sum(nil)), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%q %q %d\n", part.FormName(), part.FileName(), len(content))
+		hash.Write(content)
+	}
+}
+
+// cacheable reports whether a response is final for the request. Server
+// errors, conflicts, failed preconditions and rejected rate limits or quotas
+// may succeed on retry, for preconditions with a new If-Match.
+func cacheable(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusPreconditionFailed, http.StatusTooEarly,
+		http.StatusTooManyRequests, http.StatusPreconditionRequired:
+		return false
+	}
+	return status < 500
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// Middleware replays the stored response when a request is retried with the
+// same Idempotency-Key, body and If-Match. Reusing a key with another request
+// is rejected with 422 and retrying while the first request runs with 409.
+// Requests without the header are not affected.
+func (i *Idempotency) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > MaxKeyLength {
+			return c.JSON(http.StatusBadRequest, Err{Message: fmt.Sprintf("Idempotency-Key must be at most %v characters", MaxKeyLength)})
+		}
+		requestHash, err := RequestHash(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		}
+
+		ctx := c.Request().Context()
+		now := i.Now()
+		record := Record{Scope: scope(c), Key: key, RequestHash: requestHash, ExpiresAt: now.Add(i.LockTimeout)}
+		existing, reserved, err := i.Store.ReserveIdempotencyKey(ctx, record, now)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		}
+		if !reserved {
+			if existing.RequestHash != requestHash {
+				return c.JSON(http.StatusUnprocessableEntity, Err{Message: "Idempotency-Key was already used with a different request"})
+			}
+			if !existing.Completed() {
+				return c.JSON(http.StatusConflict, Err{Message: "A request with this Idempotency-Key is in progress"})
+			}
+			c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+			if existing.ETag != "" {
+				c.Response().Header().Set(HeaderETag, existing.ETag)
+			}
+			if existing.ContentDisposition != "" {
+				c.Response().Header().Set(echo.HeaderContentDisposition, existing.ContentDisposition)
+			}
+			return c.Blob(existing.StatusCode, existing.ContentType, existing.Body)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+		if err != nil {
+			c.Error(err)
+		}
+
+		// the outcome is stored even when the client has gone away
+		ctx = context.WithoutCancel(ctx)
+		status := c.Response().Status
+		if !cacheable(status) {
+			if releaseErr := i.Store.ReleaseIdempotencyKey(ctx, record.Scope, record.Key); releaseErr != nil {
+				return errors.Join(err, releaseErr)
+			}
+			return err
+		}
+		record.StatusCode = status
+		record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+		record.ETag = c.Response().Header().Get(HeaderETag)
+		record.ContentDisposition = c.Response().Header().Get(echo.HeaderContentDisposition)
+		record.Body = recorder.body.Bytes()
+		record.ExpiresAt = i.Now().Add(i.TTL)
+		return errors.Join(err, i.Store.CompleteIdempotencyKey(ctx, record))
+	}
+}
+
+// Sweep deletes expired records every interval until ctx is done.
+func (i *Idempotency) Sweep(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.Store.DeleteExpiredIdempotencyKeys(ctx, i.Now()); err != nil {
+				logger.WarnContext(ctx, "unable to delete expired idempotency keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
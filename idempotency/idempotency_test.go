@@ -0,0 +1,200 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) Handle(c echo.Context) error {
+	h.calls++
+	return c.JSON(h.status, map[string]int{"call": h.calls})
+}
+
+func serve(idempotency *Idempotency, handler echo.HandlerFunc, key, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin/deductions/personal", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for index := 0; index+1 < len(headers); index += 2 {
+		req.Header.Set(headers[index], headers[index+1])
+	}
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	res := httptest.NewRecorder()
+	c := echo.New().NewContext(req, res)
+	c.SetPath("/admin/deductions/personal")
+	idempotency.Middleware(handler)(c)
+	return res
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("given retry with same key and body should replay first response without running handler", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusOK}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		first := serve(idempotency, handler.Handle, "key-1", `{"amount": 70000}`)
+		retry := serve(idempotency, handler.Handle, "key-1", `{"amount": 70000}`)
+
+		if handler.calls != 1 {
+			t.Errorf("expected handler to run once but ran %v times", handler.calls)
+		}
+		if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+			t.Errorf("expected replay of %v %v but got %v %v", first.Code, first.Body, retry.Code, retry.Body)
+		}
+		if retry.Header().Get(HeaderIdempotentReplayed) != "true" || retry.Header().Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) {
+			t.Errorf("unexpected replay headers %v", retry.Header())
+		}
+	})
+
+	t.Run("given same key with different body should return 422", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusOK}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		serve(idempotency, handler.Handle, "key-1", `{"amount": 70000}`)
+		res := serve(idempotency, handler.Handle, "key-1", `{"amount": 80000}`)
+
+		if res.Code != http.StatusUnprocessableEntity || handler.calls != 1 {
+			t.Errorf("expected status %v but got %v after %v calls", http.StatusUnprocessableEntity, res.Code, handler.calls)
+		}
+	})
+
+	t.Run("given retry of update should replay its ETag and Content-Disposition", func(t *testing.T) {
+		calls := 0
+		handler := func(c echo.Context) error {
+			calls++
+			c.Response().Header().Set(HeaderETag, `"personal_default.2"`)
+			c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="rules-2567.json"`)
+			return c.JSON(http.StatusOK, map[string]int{"call": calls})
+		}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		serve(idempotency, handler, "key-1", `{"amount": 70000}`)
+		retry := serve(idempotency, handler, "key-1", `{"amount": 70000}`)
+
+		if calls != 1 || retry.Header().Get(HeaderETag) != `"personal_default.2"` {
+			t.Errorf("expected replayed ETag after 1 call but got %v after %v calls", retry.Header().Get(HeaderETag), calls)
+		}
+		if retry.Header().Get(echo.HeaderContentDisposition) != `attachment; filename="rules-2567.json"` {
+			t.Errorf("expected replayed Content-Disposition but got %v", retry.Header().Get(echo.HeaderContentDisposition))
+		}
+	})
+
+	t.Run("given same key and body with different If-Match should return 422", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusOK}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		serve(idempotency, handler.Handle, "key-1", `{"amount": 70000}`, HeaderIfMatch, `"personal_default.1"`)
+		res := serve(idempotency, handler.Handle, "key-1", `{"amount": 70000}`, HeaderIfMatch, `"personal_default.2"`)
+
+		if res.Code != http.StatusUnprocessableEntity || handler.calls != 1 {
+			t.Errorf("expected status %v but got %v after %v calls", http.StatusUnprocessableEntity, res.Code, handler.calls)
+		}
+	})
+
+	t.Run("given first request still in progress should return 409", func(t *testing.T) {
+		store := NewMemoryStore()
+		idempotency := New(store, time.Hour)
+		var retry *httptest.ResponseRecorder
+		inProgress := func(c echo.Context) error {
+			retry = serve(idempotency, (&countingHandler{status: http.StatusOK}).Handle, "key-1", `{}`)
+			return c.NoContent(http.StatusOK)
+		}
+
+		serve(idempotency, inProgress, "key-1", `{}`)
+
+		if retry.Code != http.StatusConflict {
+			t.Errorf("expected status %v but got %v", http.StatusConflict, retry.Code)
+		}
+	})
+
+	t.Run("given server error should let retry run handler again", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusInternalServerError}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		serve(idempotency, handler.Handle, "key-1", `{}`)
+		handler.status = http.StatusOK
+		res := serve(idempotency, handler.Handle, "key-1", `{}`)
+
+		if res.Code != http.StatusOK || handler.calls != 2 {
+			t.Errorf("expected handler to run again but got status %v after %v calls", res.Code, handler.calls)
+		}
+	})
+
+	t.Run("given expired key should run handler again", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusOK}
+		now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		idempotency := New(NewMemoryStore(), time.Hour)
+		idempotency.Now = func() time.Time { return now }
+
+		serve(idempotency, handler.Handle, "key-1", `{}`)
+		now = now.Add(2 * time.Hour)
+		serve(idempotency, handler.Handle, "key-1", `{}`)
+
+		if handler.calls != 2 {
+			t.Errorf("expected handler to run twice but ran %v times", handler.calls)
+		}
+	})
+
+	t.Run("given no key should always run handler", func(t *testing.T) {
+		handler := &countingHandler{status: http.StatusOK}
+		idempotency := New(NewMemoryStore(), time.Hour)
+
+		serve(idempotency, handler.Handle, "", `{}`)
+		serve(idempotency, handler.Handle, "", `{}`)
+
+		if handler.calls != 2 {
+			t.Errorf("expected handler to run twice but ran %v times", handler.calls)
+		}
+	})
+}
+
+func TestRequestHash(t *testing.T) {
+	t.Run("given same multipart upload with different boundaries should return same hash", func(t *testing.T) {
+		hash := func(boundary string) string {
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			writer.SetBoundary(boundary)
+			part, _ := writer.CreateFormFile("taxes.csv", "taxes.csv")
+			part.Write([]byte("totalIncome,wht,donation\n500000,0,0\n"))
+			writer.Close()
+			req := httptest.NewRequest(http.MethodPost, "/", &body)
+			req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+			got, err := RequestHash(req)
+			if err != nil {
+				t.Fatalf("Unable to hash request, error: %v", err)
+			}
+			return got
+		}
+
+		if hash("boundary-one") != hash("boundary-two") {
+			t.Errorf("expected same hash for both boundaries")
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("given expired records should delete only them", func(t *testing.T) {
+		store := NewMemoryStore()
+		now := time.Now()
+		store.ReserveIdempotencyKey(context.Background(), Record{Scope: "s", Key: "old", ExpiresAt: now.Add(-time.Minute)}, now.Add(-time.Hour))
+		store.ReserveIdempotencyKey(context.Background(), Record{Scope: "s", Key: "new", ExpiresAt: now.Add(time.Minute)}, now)
+
+		store.DeleteExpiredIdempotencyKeys(context.Background(), now)
+
+		if len(store.records) != 1 {
+			t.Errorf("expected 1 record but got %v", len(store.records))
+		}
+	})
+}
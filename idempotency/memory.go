@@ -0,0 +1,58 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in process memory, which is enough for a single
+// replica and for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func recordKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+func (m *MemoryStore) ReserveIdempotencyKey(ctx context.Context, record Record, now time.Time) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.records[recordKey(record.Scope, record.Key)]
+	if ok && now.Before(existing.ExpiresAt) {
+		return existing, false, nil
+	}
+	m.records[recordKey(record.Scope, record.Key)] = record
+	return record, true, nil
+}
+
+func (m *MemoryStore) CompleteIdempotencyKey(ctx context.Context, record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[recordKey(record.Scope, record.Key)] = record
+	return nil
+}
+
+func (m *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, recordKey(scope, key))
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, record := range m.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(m.records, key)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,49 @@
 -- Active: 1713667983422@@127.0.0.1@5432@ktaxes
 CREATE TABLE IF NOT EXISTS allowance (
-    allowance_type VARCHAR(255) PRIMARY KEY, allowance_amount DECIMAL(10, 2) NOT NULL
+    allowance_type VARCHAR(255) PRIMARY KEY, allowance_amount DECIMAL(10, 2) NOT NULL, version INT NOT NULL DEFAULT 1
 );
 
+ALTER TABLE allowance ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
 INSERT INTO
     allowance (
         allowance_type, allowance_amount
     )
 VALUES ('personal_default', 60000.00),
-    ('kreceipt_max', 50000.00);
\ No newline at end of file
+    ('kreceipt_max', 50000.00)
+ON CONFLICT (allowance_type) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS tax_rule_set (
+    tax_year INT PRIMARY KEY, personal_deduction_min DECIMAL(10, 2) NOT NULL, personal_deduction_max DECIMAL(10, 2) NOT NULL, k_receipt_min DECIMAL(10, 2) NOT NULL, k_receipt_max DECIMAL(10, 2) NOT NULL, donation_max DECIMAL(10, 2) NOT NULL, version INT NOT NULL DEFAULT 1
+);
+
+INSERT INTO
+    tax_rule_set (
+        tax_year, personal_deduction_min, personal_deduction_max, k_receipt_min, k_receipt_max, donation_max
+    )
+VALUES (2567, 10000.00, 100000.00, 0.00, 100000.00, 100000.00)
+ON CONFLICT (tax_year) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS admin_user (
+    username VARCHAR(255) PRIMARY KEY, password_hash TEXT NOT NULL, role VARCHAR(32) NOT NULL, failed_attempts INT NOT NULL DEFAULT 0, locked_until TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id VARCHAR(32) PRIMARY KEY, name VARCHAR(255) NOT NULL, key_hash CHAR(64) NOT NULL UNIQUE, created_by VARCHAR(255) NOT NULL, created_at TIMESTAMPTZ NOT NULL, revoked_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    bucket_key VARCHAR(512) PRIMARY KEY, tokens DOUBLE PRECISION NOT NULL, updated_at TIMESTAMPTZ NOT NULL, full_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE rate_limit_bucket ADD COLUMN IF NOT EXISTS full_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE IF NOT EXISTS usage_quota (
+    quota_key VARCHAR(512) NOT NULL, quota_day DATE NOT NULL, used INT NOT NULL, PRIMARY KEY (quota_key, quota_day)
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_record (
+    scope VARCHAR(1024) NOT NULL, idempotency_key VARCHAR(255) NOT NULL, request_hash CHAR(64) NOT NULL, status_code INT, content_type VARCHAR(255), etag VARCHAR(1024), content_disposition VARCHAR(1024), body BYTEA, expires_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (scope, idempotency_key)
+);
+
+ALTER TABLE idempotency_record ADD COLUMN IF NOT EXISTS etag VARCHAR(1024), ADD COLUMN IF NOT EXISTS content_disposition VARCHAR(1024);
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/apirom9/assessment-tax/tracing"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	contextKeyLogger = "logger"
+	contextKeyFields = "logFields"
+	redacted         = "[REDACTED]"
+)
+
+// RequestErrorKey holds the error of a request rejected as invalid. Such
+// errors, from binding JSON or parsing CSV, may quote the submitted amounts.
+const RequestErrorKey = "request_error"
+
+// IncomeKeys are the attribute keys holding income amounts of taxpayers,
+// which are redacted unless income logging is enabled.
+var IncomeKeys = map[string]bool{
+	RequestErrorKey:   true,
+	"total_income":    true,
+	"withholding_tax": true,
+	"donation":        true,
+	"k_receipt":       true,
+	"tax":             true,
+	"tax_refund":      true,
+	"amount":          true,
+}
+
+type Options struct {
+	Level slog.Level
+	// LogIncome disables the redaction of income amounts.
+	LogIncome bool
+}
+
+// New creates a JSON logger writing to w.
+func New(w io.Writer, options Options) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: options.Level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if !options.LogIncome && IncomeKeys[attr.Key] {
+				return slog.String(attr.Key, redacted)
+			}
+			return attr
+		},
+	}))
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Discard is a logger dropping everything, used when none is injected.
+var Discard = slog.New(discardHandler{})
+
+// FromContext returns the request logger set by Middleware, which carries the
+// request and trace ids, or fallback when there is none.
+func FromContext(c echo.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := c.Get(contextKeyLogger).(*slog.Logger); ok {
+		return logger
+	}
+	if fallback == nil {
+		return Discard
+	}
+	return fallback
+}
+
+// AddFields adds attributes to the access log line of the request, such as
+// the number of CSV rows processed.
+func AddFields(c echo.Context, attrs ...slog.Attr) {
+	if fields, ok := c.Get(contextKeyFields).(*[]slog.Attr); ok {
+		*fields = append(*fields, attrs...)
+	}
+}
+
+// Middleware logs one line per request with its route, client, status and
+// duration. It must run after the request id and tracing middlewares.
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			requestLogger := logger.With(
+				slog.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+			)
+			if traceID := tracing.TraceID(c.Request().Context()); traceID != "" {
+				requestLogger = requestLogger.With(
+					slog.String("trace_id", traceID),
+					slog.String("span_id", tracing.SpanID(c.Request().Context())),
+				)
+			}
+			fields := []slog.Attr{}
+			c.Set(contextKeyLogger, requestLogger)
+			c.Set(contextKeyFields, &fields)
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", c.Request().Method),
+				slog.String("route", c.Path()),
+				slog.Int("status", c.Response().Status),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", c.RealIP()),
+			}
+			if client, ok := auth.ClientFromContext(c); ok {
+				attrs = append(attrs, slog.String("client_id", client.ID))
+			}
+			if username, ok := c.Get(auth.ContextKeyUsername).(string); ok {
+				attrs = append(attrs, slog.String("admin", username))
+			}
+			attrs = append(attrs, fields...)
+			level := slog.LevelInfo
+			if c.Response().Status >= 500 {
+				level = slog.LevelError
+			}
+			if err != nil && c.Response().Status < 500 {
+				attrs = append(attrs, slog.String(RequestErrorKey, err.Error()))
+			} else if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			requestLogger.LogAttrs(c.Request().Context(), level, "request", attrs...)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func decodeLines(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	decoder := json.NewDecoder(buffer)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Unable to decode log line, error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestNew(t *testing.T) {
+	t.Run("given income amounts should redact them by default", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := New(&buffer, Options{Level: slog.LevelDebug})
+
+		logger.Debug("tax calculated", slog.Float64("total_income", 500000.0), slog.Float64("tax", 29000.0), slog.Int("csv_rows", 3))
+
+		line := decodeLines(t, &buffer)[0]
+		if line["total_income"] != redacted || line["tax"] != redacted {
+			t.Errorf("expected income amounts to be redacted but got %v", line)
+		}
+		if line["csv_rows"] != 3.0 {
+			t.Errorf("expected csv rows 3 but got %v", line["csv_rows"])
+		}
+	})
+
+	t.Run("given income logging enabled should keep amounts", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := New(&buffer, Options{LogIncome: true})
+
+		logger.Info("tax calculated", slog.Float64("total_income", 500000.0))
+
+		if got := decodeLines(t, &buffer)[0]["total_income"]; got != 500000.0 {
+			t.Errorf("expected total income 500000.0 but got %v", got)
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("given request should log route, status, client, request id and added fields", func(t *testing.T) {
+		var buffer bytes.Buffer
+		e := echo.New()
+		e.Use(middleware.RequestID())
+		e.Use(Middleware(New(&buffer, Options{})))
+		e.POST("/tax/calculations/upload-csv", func(c echo.Context) error {
+			c.Set(auth.ContextKeyClient, auth.Client{ID: "apikey:9f86d081884c7d65"})
+			FromContext(c, nil).Info("handling upload")
+			AddFields(c, slog.Int("csv_rows", 3))
+			return c.NoContent(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", nil)
+		req.Header.Set(echo.HeaderXRequestID, "request-1")
+
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := decodeLines(t, &buffer)
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 log lines but got %v", len(lines))
+		}
+		if lines[0]["request_id"] != "request-1" {
+			t.Errorf("expected handler log with request id but got %v", lines[0])
+		}
+		want := map[string]any{
+			"msg":        "request",
+			"request_id": "request-1",
+			"route":      "/tax/calculations/upload-csv",
+			"status":     200.0,
+			"client_id":  "apikey:9f86d081884c7d65",
+			"csv_rows":   3.0,
+		}
+		for key, value := range want {
+			if lines[1][key] != value {
+				t.Errorf("expected %v to be %v but got %v", key, value, lines[1][key])
+			}
+		}
+	})
+
+	t.Run("given handler error should log its status as error", func(t *testing.T) {
+		var buffer bytes.Buffer
+		e := echo.New()
+		e.Use(Middleware(New(&buffer, Options{})))
+		e.GET("/", func(c echo.Context) error {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "down")
+		})
+		res := httptest.NewRecorder()
+
+		e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		line := decodeLines(t, &buffer)[0]
+		if res.Code != http.StatusServiceUnavailable || line["status"] != 503.0 || line["level"] != "ERROR" {
+			t.Errorf("expected error log with status 503 but got %v", line)
+		}
+	})
+	t.Run("given invalid request error should redact it unless income logging is enabled", func(t *testing.T) {
+		for _, logIncome := range []bool{false, true} {
+			var buffer bytes.Buffer
+			e := echo.New()
+			e.Use(Middleware(New(&buffer, Options{LogIncome: logIncome})))
+			e.POST("/", func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid amount 512345.67 in row 2")
+			})
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+			line := decodeLines(t, &buffer)[0]
+			redactedError := line[RequestErrorKey] == redacted
+			if redactedError == logIncome || line["error"] != nil {
+				t.Errorf("expected request error to be redacted %v but got %v", !logIncome, line)
+			}
+		}
+	})
+	t.Run("given traced request should log its trace and span ids", func(t *testing.T) {
+		var buffer bytes.Buffer
+		e := echo.New()
+		e.Use(Middleware(New(&buffer, Options{})))
+		e.GET("/", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+		defer span.End()
+
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		line := decodeLines(t, &buffer)[0]
+		if line["trace_id"] != span.SpanContext().TraceID().String() || line["span_id"] != span.SpanContext().SpanID().String() {
+			t.Errorf("expected trace id %v and span id %v but got %v", span.SpanContext().TraceID(), span.SpanContext().SpanID(), line)
+		}
+	})
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/apirom9/assessment-tax/config"
+	"github.com/apirom9/assessment-tax/grpcapi"
+	"github.com/apirom9/assessment-tax/health"
+	"github.com/apirom9/assessment-tax/idempotency"
+	"github.com/apirom9/assessment-tax/logging"
+	"github.com/apirom9/assessment-tax/metrics"
 	"github.com/apirom9/assessment-tax/postgres"
+	"github.com/apirom9/assessment-tax/ratelimit"
 	"github.com/apirom9/assessment-tax/tax"
+	"github.com/apirom9/assessment-tax/tracing"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"google.golang.org/grpc"
 
 	docs "github.com/apirom9/assessment-tax/docs"
 )
@@ -20,45 +38,254 @@ import (
 // @description	Tax API
 func main() {
 
-	registerGracefulShutdown()
-
-	dbUrl := os.Getenv("DATABASE_URL")
-	store, err := postgres.NewPostgres(dbUrl)
+	cfg, printConfig, err := config.Load(os.Args[1:], os.Getenv)
+	if printConfig {
+		cfg.Print(os.Stdout)
+	}
 	if err != nil {
-		fmt.Printf("Unable to create store DB, error: %v", err)
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if printConfig {
 		return
 	}
 
-	handler := tax.Handler{Store: store}
+	logger := logging.New(os.Stdout, logging.Options{
+		Level:     cfg.SlogLevel(),
+		LogIncome: cfg.LogIncome,
+	})
+
+	store, err := postgres.NewPostgres(cfg.DatabaseURL, logger)
+	if err != nil {
+		fatal(logger, "unable to create store DB", err)
+	}
+
+	reportFont, err := os.ReadFile(cfg.ReportFontPath)
+	if err != nil {
+		fatal(logger, "unable to load report font", err)
+	}
+	if err := tax.CheckReportFont(reportFont); err != nil {
+		fatal(logger, "unable to render reports with report font", err)
+	}
+
+	var limiterStore interface {
+		ratelimit.Store
+		ratelimit.QuotaStore
+		ratelimit.Sweeper
+	} = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		limiterStore = store
+	}
+	limiter := ratelimit.NewLimiter(limiterStore)
+
+	apiMetrics := metrics.New(store.Db)
+	store.ObserveQuery = apiMetrics.ObserveQuery
+
+	service := &tax.TaxService{Store: store, Metrics: apiMetrics, RulesSigningKey: []byte(cfg.RulesSigningKey)}
+	csvQuota := ratelimit.NewQuota(limiterStore, "csv-rows", cfg.CsvDailyRowQuota)
+	csvQuota.Clients = cfg.CsvDailyRowQuotas()
+	handler := tax.Handler{
+		Service:    service,
+		ReportFont: reportFont,
+		CsvQuota:   csvQuota,
+		Logger:     logger,
+	}
+
+	clientAuthenticator := auth.ClientAuthenticator{
+		APIKeys:  store,
+		Required: cfg.ClientAuthRequired,
+	}
+	if cfg.JwksFile != "" {
+		clientAuthenticator.JWT, err = auth.NewJWTValidatorFromFile(cfg.JwksFile, cfg.JwtIssuer, cfg.JwtAudience)
+		if err != nil {
+			fatal(logger, "unable to load JWKS file", err)
+		}
+	}
+
+	startup := health.NewFlag("waiting for database and admin bootstrap")
+	checker := health.NewChecker()
+	checker.Add("database", store.Ping)
+	checker.Add("migrations", store.CheckMigrations)
+	checker.Add("settings", store.CheckSettings)
+	checker.Add("startup", startup.Check)
+
+	// background work is stopped and waited for before the store is closed
+	background, stopBackground := context.WithCancel(context.Background())
+	var backgroundWork sync.WaitGroup
+	runInBackground := func(work func()) {
+		backgroundWork.Add(1)
+		go func() {
+			defer backgroundWork.Done()
+			work()
+		}()
+	}
+	idempotent := idempotency.New(store, cfg.IdempotencyTTL)
+	runInBackground(func() { idempotent.Sweep(background, time.Hour, logger) })
+	runInBackground(func() { ratelimit.Sweep(background, limiterStore, time.Hour, logger) })
+	runInBackground(func() {
+		if err := store.WaitForConnection(background, 5*time.Second); err != nil {
+			return
+		}
+		created, err := auth.Bootstrap(background, store, cfg.AdminUsername, cfg.AdminPassword)
+		if err != nil {
+			logger.Error("unable to bootstrap admin user", slog.String("error", err.Error()))
+			return
+		}
+		if created {
+			logger.Info("created superuser from ADMIN_USERNAME", slog.String("admin", cfg.AdminUsername))
+		}
+		startup.Set()
+	})
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		fatal(logger, "unable to set up tracing", err)
+	}
 
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.IPExtractor = cfg.IPExtractor()
+	e.Use(middleware.RequestID())
+	e.Use(otelecho.Middleware(tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		return c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == "/metrics"
+	})))
+	e.Use(tracing.TraceIDHeader)
+	e.Use(logging.Middleware(logger))
+	e.Use(apiMetrics.Middleware)
+	e.GET("/metrics", apiMetrics.Handler())
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
-	e.POST("/tax/calculations", handler.CalculateTax)
-	e.POST("/tax/calculations/upload-csv", handler.CalculateTaxCsv)
-
-	adminUserName := os.Getenv("ADMIN_USERNAME")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
-	g := e.Group("")
-	g.Use(middleware.BasicAuth(func(user, password string, ctx echo.Context) (bool, error) {
-		if user == adminUserName && password == adminPassword {
-			return true, nil
+	e.GET("/healthz", checker.Healthz)
+	e.GET("/readyz", checker.Readyz)
+
+	t := e.Group("/tax")
+	t.Use(clientAuthenticator.Middleware)
+	t.POST("/calculations", handler.CalculateTax, limiter.Middleware("calculations", cfg.CalculationLimits()))
+	t.POST("/calculations/upload-csv", handler.CalculateTaxCsv, limiter.Middleware("upload-csv", cfg.CsvLimits()), idempotent.Middleware)
+	t.POST("/calculations/report", handler.CalculateTaxReport, limiter.Middleware("report", cfg.CalculationLimits()))
+	t.POST("/calculations/inverse", handler.CalculateTaxInverse, limiter.Middleware("inverse", cfg.CalculationLimits()))
+	t.POST("/calculations/compare", handler.CompareTaxScenarios, limiter.Middleware("compare", cfg.CalculationLimits()))
+	t.POST("/calculations/household", handler.CalculateHouseholdTax, limiter.Middleware("household", cfg.CalculationLimits()))
+	t.POST("/withholding/schedule", handler.CalculateWithholdingSchedule, limiter.Middleware("withholding", cfg.CalculationLimits()))
+	t.POST("/advice", handler.AdviseAllowances, limiter.Middleware("advice", cfg.CalculationLimits()))
+	t.POST("/filings/export", handler.ExportFiling, limiter.Middleware("filings", cfg.CalculationLimits()))
+
+	authenticator := auth.NewAuthenticator(store)
+	authHandler := auth.Handler{Store: store, APIKeys: store}
+	g := e.Group("/admin")
+	g.Use(middleware.BasicAuth(authenticator.Validate))
+	g.GET("/deductions", handler.GetDeductions, auth.RequireRole(auth.RoleViewer))
+	g.GET("/deductions/personal", handler.GetPersonalDeduction, auth.RequireRole(auth.RoleViewer))
+	g.POST("/deductions/personal", handler.UpdatePersonalDeduction, auth.RequireRole(auth.RoleEditor), idempotent.Middleware)
+	g.GET("/deductions/k-receipt", handler.GetKReceipt, auth.RequireRole(auth.RoleViewer))
+	g.POST("/deductions/k-receipt", handler.UpdateKReceipt, auth.RequireRole(auth.RoleEditor), idempotent.Middleware)
+	g.GET("/allowances", handler.GetAllowanceSettings, auth.RequireRole(auth.RoleViewer))
+	g.PUT("/allowances/:type", handler.UpdateAllowanceSetting, auth.RequireRole(auth.RoleEditor), idempotent.Middleware)
+	g.GET("/rules/:year", handler.GetRules, auth.RequireRole(auth.RoleViewer))
+	g.PUT("/rules/:year", handler.UpdateRules, auth.RequireRole(auth.RoleSuperuser))
+	g.GET("/rules/export", handler.ExportRules, auth.RequireRole(auth.RoleViewer))
+	g.POST("/rules/import", handler.ImportRules, auth.RequireRole(auth.RoleSuperuser), idempotent.Middleware)
+	g.GET("/users", authHandler.ListAdminUsers, auth.RequireRole(auth.RoleSuperuser))
+	g.POST("/users", authHandler.CreateAdminUser, auth.RequireRole(auth.RoleSuperuser))
+	g.PUT("/users/:username", authHandler.UpdateAdminUser, auth.RequireRole(auth.RoleSuperuser))
+	g.DELETE("/users/:username", authHandler.DeleteAdminUser, auth.RequireRole(auth.RoleSuperuser))
+	g.GET("/api-keys", authHandler.ListAPIKeys, auth.RequireRole(auth.RoleViewer))
+	g.POST("/api-keys", authHandler.CreateAPIKey, auth.RequireRole(auth.RoleEditor))
+	g.DELETE("/api-keys/:id", authHandler.RevokeAPIKey, auth.RequireRole(auth.RoleEditor))
+
+	var grpcServer *grpc.Server
+	if cfg.GrpcPort != 0 {
+		grpcServer = grpcapi.NewGRPCServer(&grpcapi.Server{
+			Service: service,
+			Clients: &clientAuthenticator,
+			Admins:  authenticator,
+			Logger:  logger,
+
+			Limiter:           limiter,
+			CalculationLimits: cfg.CalculationLimits(),
+			RowQuota:          csvQuota,
+		})
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GrpcPort))
+		if err != nil {
+			fatal(logger, "unable to listen for gRPC", err)
 		}
-		return false, nil
-	}))
-	g.POST("/admin/deductions/personal", handler.UpdatePersonalDeduction)
-	g.POST("/admin/deductions/k-receipt", handler.UpdateKReceipt)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("unable to serve gRPC", slog.String("error", err.Error()))
+			}
+		}()
+		logger.Info("gRPC server started", slog.String("address", listener.Addr().String()))
+	}
 
-	port := os.Getenv("PORT")
+	port := strconv.Itoa(cfg.Port)
 	docs.SwaggerInfo.Host = "localhost:" + port
-	e.Logger.Fatal(e.Start(":" + port))
+	cleanup := func(ctx context.Context) error {
+		var grpcErr error
+		if grpcServer != nil {
+			grpcErr = grpcapi.Shutdown(ctx, grpcServer)
+		}
+		stopBackground()
+		return errors.Join(grpcErr, waitFor(ctx, &backgroundWork), shutdownTracing(ctx), store.Close())
+	}
+	os.Exit(serveUntilShutdown(e, ":"+port, cfg.ShutdownTimeout, cleanup, logger))
 }
 
-func registerGracefulShutdown() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+// waitFor waits for the work to finish or ctx to be done, whichever is first.
+func waitFor(ctx context.Context, work *sync.WaitGroup) error {
+	done := make(chan struct{})
 	go func() {
-		<-c
-		fmt.Println("shutting down the server")
-		os.Exit(0)
+		work.Wait()
+		close(done)
 	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background work: %w", ctx.Err())
+	}
+}
+
+func fatal(logger *slog.Logger, message string, err error) {
+	logger.Error(message, slog.String("error", err.Error()))
+	os.Exit(1)
+}
+
+// serveUntilShutdown serves until SIGINT or SIGTERM, then stops accepting
+// connections and waits up to timeout for in-flight requests before running
+// cleanup, which drains gRPC calls, stops background work, flushes traces
+// and closes the database. It returns the process exit code, which is
+// non-zero when the server failed or draining timed out.
+func serveUntilShutdown(e *echo.Echo, address string, timeout time.Duration, cleanup func(context.Context) error, logger *slog.Logger) int {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- e.Start(address)
+	}()
+	logger.Info("server started", slog.String("address", address))
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		logger.Error("unable to start server", slog.String("error", err.Error()))
+		exitCode = 1
+	case <-quit:
+		logger.Info("shutting down the server")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if exitCode == 0 {
+		if err := e.Shutdown(ctx); err != nil {
+			logger.Error("unable to drain requests", slog.Duration("timeout", timeout), slog.String("error", err.Error()))
+			exitCode = 1
+		}
+	}
+
+	if err := cleanup(ctx); err != nil {
+		logger.Error("unable to clean up", slog.String("error", err.Error()))
+		exitCode = 1
+	}
+	return exitCode
 }
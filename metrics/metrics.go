@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/apirom9/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tax_api"
+
+// Metrics holds the Prometheus collectors of the API on its own registry.
+type Metrics struct {
+	Registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	calculations    *prometheus.CounterVec
+	brackets        *prometheus.CounterVec
+	csvRows         *prometheus.CounterVec
+	storeDuration   *prometheus.HistogramVec
+}
+
+// New creates the collectors. When db is not nil its connection pool stats
+// are exported as well.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		calculations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "calculations_total",
+			Help:      "Tax calculations by result type, tax to pay or refund.",
+		}, []string{"result"}),
+		brackets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "calculation_brackets_total",
+			Help:      "Tax calculations by the highest tax bracket reached.",
+		}, []string{"level"}),
+		csvRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "csv_rows_total",
+			Help:      "Uploaded CSV rows by outcome: processed, invalid or rejected by quota.",
+		}, []string{"outcome"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_query_duration_seconds",
+			Help:      "Store query latency by store method.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method"}),
+	}
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.calculations, m.brackets, m.csvRows, m.storeDuration,
+	)
+	if db != nil {
+		m.Registry.MustRegister(collectors.NewDBStatsCollector(db, "ktaxes"))
+	}
+	return m
+}
+
+// Middleware counts requests and their latency by route template, so paths
+// with parameters do not create a series per value.
+func (m *Metrics) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		status := c.Response().Status
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) {
+			status = httpError.Code
+		}
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := prometheus.Labels{"method": c.Request().Method, "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (m *Metrics) Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry}))
+}
+
+func (m *Metrics) ObserveCalculation(result tax.Result) {
+	if result.Amount < 0 {
+		m.calculations.WithLabelValues("refund").Inc()
+	} else {
+		m.calculations.WithLabelValues("tax").Inc()
+	}
+	m.brackets.WithLabelValues(tax.DefaultLocale.LevelLabel(result.MarginalLevel)).Inc()
+}
+
+func (m *Metrics) ObserveCsvRows(outcome string, rows int) {
+	m.csvRows.WithLabelValues(outcome).Add(float64(rows))
+}
+
+func (m *Metrics) ObserveQuery(method string, duration time.Duration) {
+	m.storeDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
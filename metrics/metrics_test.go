@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apirom9/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("given requests should count them by route template and status", func(t *testing.T) {
+		m := New(nil)
+		e := echo.New()
+		e.Use(m.Middleware)
+		e.DELETE("/admin/users/:username", func(c echo.Context) error {
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		for _, path := range []string{"/admin/users/a", "/admin/users/b", "/unknown"} {
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, path, nil))
+		}
+
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodDelete, "/admin/users/:username", "204")); got != 2 {
+			t.Errorf("expected 2 requests but got %v", got)
+		}
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodDelete, "unmatched", "404")); got != 1 {
+			t.Errorf("expected 1 unmatched request but got %v", got)
+		}
+	})
+
+	t.Run("given calculations should count result type and bracket reached", func(t *testing.T) {
+		m := New(nil)
+		calculator := tax.NewTaxCalulator(60000.0, 50000.0)
+		calculator.TotalIncome = 500000.0
+		m.ObserveCalculation(calculator.CalculateTaxResult())
+		calculator.WitholdingTax = 40000.0
+		m.ObserveCalculation(calculator.CalculateTaxResult())
+
+		if got := testutil.ToFloat64(m.calculations.WithLabelValues("tax")); got != 1 {
+			t.Errorf("expected 1 tax calculation but got %v", got)
+		}
+		if got := testutil.ToFloat64(m.calculations.WithLabelValues("refund")); got != 1 {
+			t.Errorf("expected 1 refund calculation but got %v", got)
+		}
+		if got := testutil.ToFloat64(m.brackets.WithLabelValues("150,001 - 500,000")); got != 2 {
+			t.Errorf("expected 2 calculations in bracket 150,001 - 500,000 but got %v", got)
+		}
+	})
+
+	t.Run("given metrics endpoint should expose csv rows and store latency", func(t *testing.T) {
+		m := New(nil)
+		m.ObserveCsvRows(tax.CsvRowsProcessed, 3)
+		m.ObserveQuery("GetMaxKReceipt", 2*time.Millisecond)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/metrics", nil), res)
+
+		m.Handler()(c)
+
+		for _, want := range []string{
+			`tax_api_csv_rows_total{outcome="processed"} 3`,
+			`tax_api_store_query_duration_seconds_count{method="GetMaxKReceipt"} 1`,
+		} {
+			if !strings.Contains(res.Body.String(), want) {
+				t.Errorf("expected metrics to contain %v", want)
+			}
+		}
+	})
+}
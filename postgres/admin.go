@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+)
+
+func (p *Postgres) GetAdminUser(ctx context.Context, username string) (user auth.AdminUser, err error) {
+	ctx, end := p.trace(ctx, "GetAdminUser")
+	defer func() { end(err) }()
+	var role string
+	var lockedUntil sql.NullTime
+	sqlStr := "SELECT username, password_hash, role, failed_attempts, locked_until FROM admin_user WHERE username=$1"
+	err = p.Db.QueryRowContext(ctx, sqlStr, username).Scan(&user.Username, &user.PasswordHash, &role, &user.FailedAttempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, auth.ErrUserNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	user.Role = auth.Role(role)
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	return user, nil
+}
+
+func (p *Postgres) ListAdminUsers(ctx context.Context) (result []auth.AdminUser, err error) {
+	ctx, end := p.trace(ctx, "ListAdminUsers")
+	defer func() { end(err) }()
+	result = []auth.AdminUser{}
+	sqlStr := "SELECT username, password_hash, role, failed_attempts, locked_until FROM admin_user ORDER BY username"
+	rows, err := p.Db.QueryContext(ctx, sqlStr)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user auth.AdminUser
+		var role string
+		var lockedUntil sql.NullTime
+		err = rows.Scan(&user.Username, &user.PasswordHash, &role, &user.FailedAttempts, &lockedUntil)
+		if err != nil {
+			return result, err
+		}
+		user.Role = auth.Role(role)
+		if lockedUntil.Valid {
+			user.LockedUntil = &lockedUntil.Time
+		}
+		result = append(result, user)
+	}
+	return result, rows.Err()
+}
+
+func (p *Postgres) SaveAdminUser(ctx context.Context, user auth.AdminUser) (err error) {
+	ctx, end := p.trace(ctx, "SaveAdminUser")
+	defer func() { end(err) }()
+	sqlStr := `INSERT INTO admin_user (username, password_hash, role, failed_attempts, locked_until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username) DO UPDATE SET
+			password_hash=EXCLUDED.password_hash,
+			role=EXCLUDED.role,
+			failed_attempts=EXCLUDED.failed_attempts,
+			locked_until=EXCLUDED.locked_until`
+	_, err = p.Db.ExecContext(ctx, sqlStr, user.Username, user.PasswordHash, string(user.Role), user.FailedAttempts, user.LockedUntil)
+	return err
+}
+
+func (p *Postgres) DeleteAdminUser(ctx context.Context, username string) (err error) {
+	ctx, end := p.trace(ctx, "DeleteAdminUser")
+	defer func() { end(err) }()
+	result, err := p.Db.ExecContext(ctx, "DELETE FROM admin_user WHERE username=$1", username)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return auth.ErrUserNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) CountAdminUsers(ctx context.Context) (count int, err error) {
+	ctx, end := p.trace(ctx, "CountAdminUsers")
+	defer func() { end(err) }()
+	err = p.Db.QueryRowContext(ctx, "SELECT COUNT(*) FROM admin_user").Scan(&count)
+	return count, err
+}
+
+// RecordFailedLogin increments and locks in one statement, so concurrent
+// failed logins are all counted.
+func (p *Postgres) RecordFailedLogin(ctx context.Context, username string, now time.Time, maxFailedAttempts int, lockUntil time.Time) (failedAttempts int, err error) {
+	ctx, end := p.trace(ctx, "RecordFailedLogin")
+	defer func() { end(err) }()
+	sqlStr := `UPDATE admin_user SET
+		failed_attempts = CASE WHEN locked_until <= $2 THEN 1 ELSE failed_attempts + 1 END,
+		locked_until = CASE
+			WHEN (CASE WHEN locked_until <= $2 THEN 1 ELSE failed_attempts + 1 END) >= $3 THEN $4
+			WHEN locked_until <= $2 THEN NULL
+			ELSE locked_until
+		END
+		WHERE username=$1
+		RETURNING failed_attempts`
+	err = p.Db.QueryRowContext(ctx, sqlStr, username, now, maxFailedAttempts, lockUntil).Scan(&failedAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, auth.ErrUserNotFound
+	}
+	return failedAttempts, err
+}
+
+func (p *Postgres) ResetFailedLogins(ctx context.Context, username string) (err error) {
+	ctx, end := p.trace(ctx, "ResetFailedLogins")
+	defer func() { end(err) }()
+	sqlStr := "UPDATE admin_user SET failed_attempts=0, locked_until=NULL WHERE username=$1"
+	_, err = p.Db.ExecContext(ctx, sqlStr, username)
+	return err
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+)
+
+func (p *Postgres) CreateAPIKey(ctx context.Context, key auth.APIKey) (err error) {
+	ctx, end := p.trace(ctx, "CreateAPIKey")
+	defer func() { end(err) }()
+	sqlStr := "INSERT INTO api_key (id, name, key_hash, created_by, created_at) VALUES ($1, $2, $3, $4, $5)"
+	_, err = p.Db.ExecContext(ctx, sqlStr, key.ID, key.Name, key.KeyHash, key.CreatedBy, key.CreatedAt)
+	return err
+}
+
+func scanAPIKey(scan func(dest ...any) error) (auth.APIKey, error) {
+	var key auth.APIKey
+	var revokedAt sql.NullTime
+	err := scan(&key.ID, &key.Name, &key.KeyHash, &key.CreatedBy, &key.CreatedAt, &revokedAt)
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, err
+}
+
+func (p *Postgres) GetAPIKeyByHash(ctx context.Context, keyHash string) (key auth.APIKey, err error) {
+	ctx, end := p.trace(ctx, "GetAPIKeyByHash")
+	defer func() { end(err) }()
+	sqlStr := "SELECT id, name, key_hash, created_by, created_at, revoked_at FROM api_key WHERE key_hash=$1"
+	key, err = scanAPIKey(p.Db.QueryRowContext(ctx, sqlStr, keyHash).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, auth.ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (p *Postgres) ListAPIKeys(ctx context.Context) (result []auth.APIKey, err error) {
+	ctx, end := p.trace(ctx, "ListAPIKeys")
+	defer func() { end(err) }()
+	result = []auth.APIKey{}
+	sqlStr := "SELECT id, name, key_hash, created_by, created_at, revoked_at FROM api_key ORDER BY created_at"
+	rows, err := p.Db.QueryContext(ctx, sqlStr)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key, err := scanAPIKey(rows.Scan)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, key)
+	}
+	return result, rows.Err()
+}
+
+func (p *Postgres) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) (err error) {
+	ctx, end := p.trace(ctx, "RevokeAPIKey")
+	defer func() { end(err) }()
+	sqlStr := "UPDATE api_key SET revoked_at=COALESCE(revoked_at, $2) WHERE id=$1"
+	result, err := p.Db.ExecContext(ctx, sqlStr, id, revokedAt)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return auth.ErrAPIKeyNotFound
+	}
+	return nil
+}
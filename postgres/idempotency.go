@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/apirom9/assessment-tax/idempotency"
+)
+
+// ReserveIdempotencyKey inserts the record, replacing an expired one, with a
+// single upsert so concurrent retries cannot both reserve the key.
+func (p *Postgres) ReserveIdempotencyKey(ctx context.Context, record idempotency.Record, now time.Time) (existing idempotency.Record, reserved bool, err error) {
+	ctx, end := p.trace(ctx, "ReserveIdempotencyKey")
+	defer func() { end(err) }()
+	sqlStr := `INSERT INTO idempotency_record (scope, idempotency_key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET request_hash=EXCLUDED.request_hash, status_code=NULL,
+			content_type=NULL, etag=NULL, content_disposition=NULL, body=NULL, expires_at=EXCLUDED.expires_at
+		WHERE idempotency_record.expires_at <= $5
+		RETURNING scope`
+	var scope string
+	err = p.Db.QueryRowContext(ctx, sqlStr, record.Scope, record.Key, record.RequestHash, record.ExpiresAt, now).Scan(&scope)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return existing, false, err
+	}
+
+	var statusCode sql.NullInt64
+	var contentType, etag, contentDisposition sql.NullString
+	sqlStr = "SELECT request_hash, status_code, content_type, etag, content_disposition, body, expires_at FROM idempotency_record WHERE scope=$1 AND idempotency_key=$2"
+	err = p.Db.QueryRowContext(ctx, sqlStr, record.Scope, record.Key).Scan(&existing.RequestHash, &statusCode, &contentType, &etag, &contentDisposition, &existing.Body, &existing.ExpiresAt)
+	if err != nil {
+		return existing, false, err
+	}
+	existing.Scope = record.Scope
+	existing.Key = record.Key
+	existing.StatusCode = int(statusCode.Int64)
+	existing.ContentType = contentType.String
+	existing.ETag = etag.String
+	existing.ContentDisposition = contentDisposition.String
+	return existing, false, nil
+}
+
+func (p *Postgres) CompleteIdempotencyKey(ctx context.Context, record idempotency.Record) (err error) {
+	ctx, end := p.trace(ctx, "CompleteIdempotencyKey")
+	defer func() { end(err) }()
+	sqlStr := `UPDATE idempotency_record SET status_code=$3, content_type=$4, etag=$5, content_disposition=$6, body=$7, expires_at=$8
+		WHERE scope=$1 AND idempotency_key=$2`
+	_, err = p.Db.ExecContext(ctx, sqlStr, record.Scope, record.Key, record.StatusCode, record.ContentType, record.ETag, record.ContentDisposition, record.Body, record.ExpiresAt)
+	return err
+}
+
+func (p *Postgres) ReleaseIdempotencyKey(ctx context.Context, scope, key string) (err error) {
+	ctx, end := p.trace(ctx, "ReleaseIdempotencyKey")
+	defer func() { end(err) }()
+	_, err = p.Db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE scope=$1 AND idempotency_key=$2", scope, key)
+	return err
+}
+
+func (p *Postgres) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (err error) {
+	ctx, end := p.trace(ctx, "DeleteExpiredIdempotencyKeys")
+	defer func() { end(err) }()
+	_, err = p.Db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE expires_at <= $1", now)
+	return err
+}
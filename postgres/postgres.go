@@ -1,87 +1,177 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/apirom9/assessment-tax/tax"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Postgres struct {
-	Db *sql.DB
+	Db     *sql.DB
+	Logger *slog.Logger
+	// ObserveQuery, when set, receives the duration of each store method.
+	ObserveQuery func(method string, duration time.Duration)
 }
 
-func NewPostgres(dbUrl string) (*Postgres, error) {
+func (p *Postgres) observe(method string, start time.Time) {
+	if p.ObserveQuery != nil {
+		p.ObserveQuery(method, time.Since(start))
+	}
+}
+
+var tracer = otel.Tracer("github.com/apirom9/assessment-tax/postgres")
+
+// trace starts a span for the store method and returns the function ending
+// it, which also records the query duration.
+func (p *Postgres) trace(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "Store."+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")))
+	return ctx, func(err error) {
+		p.observe(method, start)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// NewPostgres opens the connection pool without waiting for the database, so
+// the API can start and report readiness while the database comes up.
+func NewPostgres(dbUrl string, logger *slog.Logger) (*Postgres, error) {
 	db, err := sql.Open("postgres", dbUrl)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
-	err = db.Ping()
-	if err != nil {
-		for i := 1; i <= 5; i++ {
-			err = db.Ping()
-			if err == nil {
-				break
-			}
-			time.Sleep(5 * time.Second)
+	return &Postgres{Db: db, Logger: logger}, nil
+}
+
+// WaitForConnection pings the database every interval until it answers or ctx
+// is done.
+func (p *Postgres) WaitForConnection(ctx context.Context, interval time.Duration) error {
+	for {
+		err := p.Db.PingContext(ctx)
+		if err == nil {
+			return nil
 		}
-		if err != nil {
-			log.Fatal(err)
+		p.Logger.WarnContext(ctx, "waiting for database", slog.String("error", err.Error()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
 		}
 	}
-	return &Postgres{Db: db}, nil
 }
 
-func (p *Postgres) UpdateDefaultPersonalDeduction(value float64) error {
-	sqlStr := "UPDATE allowance SET allowance_amount=$1 WHERE allowance_type='personal_default'"
-	_, err := p.Db.Query(sqlStr, value)
-	if err != nil {
-		return err
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.Db.PingContext(ctx)
+}
+
+var requiredTables = []string{"allowance", "admin_user", "api_key", "rate_limit_bucket", "usage_quota", "idempotency_record", "tax_rule_set"}
+
+// requiredColumns are the columns init.sql adds to tables of databases
+// created before them.
+var requiredColumns = []struct{ table, column string }{
+	{"allowance", "version"},
+	{"idempotency_record", "etag"},
+	{"idempotency_record", "content_disposition"},
+	{"rate_limit_bucket", "full_at"},
+}
+
+// CheckMigrations reports the first table or added column of init.sql that
+// does not exist.
+func (p *Postgres) CheckMigrations(ctx context.Context) error {
+	for _, table := range requiredTables {
+		var name sql.NullString
+		if err := p.Db.QueryRowContext(ctx, "SELECT to_regclass($1)::text", table).Scan(&name); err != nil {
+			return err
+		}
+		if !name.Valid {
+			return fmt.Errorf("table %v does not exist", table)
+		}
+	}
+	for _, required := range requiredColumns {
+		var exists bool
+		err := p.Db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=$1 AND column_name=$2)", required.table, required.column).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("column %v.%v does not exist", required.table, required.column)
+		}
 	}
 	return nil
 }
 
-func (p *Postgres) GetDefaultPersonalDeduction() (float64, error) {
-	result := 0.0
-	sqlStr := "SELECT allowance_amount FROM allowance WHERE allowance_type='personal_default'"
-	rows, err := p.Db.Query(sqlStr)
-	if err != nil {
-		return result, err
+var requiredSettings = []string{tax.SettingPersonalDeduction, tax.SettingMaxKReceipt}
+
+// CheckSettings reports allowance settings and the rule set of the current
+// tax year that calculations rely on but are missing.
+func (p *Postgres) CheckSettings(ctx context.Context) error {
+	if _, err := p.getRuleSet(ctx, tax.FilingTaxYear); err != nil {
+		return fmt.Errorf("rule set of tax year %v: %w", tax.FilingTaxYear, err)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		err = rows.Scan(&result)
+	for _, setting := range requiredSettings {
+		var amount float64
+		err := p.Db.QueryRowContext(ctx, "SELECT allowance_amount FROM allowance WHERE allowance_type=$1", setting).Scan(&amount)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("allowance setting %v does not exist", setting)
+		}
 		if err != nil {
-			return result, err
+			return err
 		}
 	}
-	return result, nil
+	return nil
 }
 
-func (p *Postgres) UpdateMaxKReceipt(value float64) error {
-	sqlStr := "UPDATE allowance SET allowance_amount=$1 WHERE allowance_type='kreceipt_max'"
-	_, err := p.Db.Query(sqlStr, value)
-	if err != nil {
-		return err
+// UpdateAllowanceSetting stores the value if the current version of the
+// setting is version, incrementing the version. Otherwise it returns the
+// current setting.
+func (p *Postgres) UpdateAllowanceSetting(ctx context.Context, allowanceType string, value float64, version int) (result tax.Setting, updated bool, err error) {
+	ctx, end := p.trace(ctx, "UpdateAllowanceSetting")
+	defer func() { end(err) }()
+	sqlStr := "UPDATE allowance SET allowance_amount=$1, version=version+1 WHERE allowance_type=$2 AND version=$3 RETURNING allowance_amount, version"
+	err = p.Db.QueryRowContext(ctx, sqlStr, value, allowanceType, version).Scan(&result.Amount, &result.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		result, err = p.getAllowanceSetting(ctx, allowanceType)
+		return result, false, err
 	}
-	return nil
+	return result, err == nil, err
 }
 
-func (p *Postgres) GetMaxKReceipt() (float64, error) {
-	result := 0.0
-	sqlStr := "SELECT allowance_amount FROM allowance WHERE allowance_type='kreceipt_max'"
-	rows, err := p.Db.Query(sqlStr)
+func (p *Postgres) getAllowanceSetting(ctx context.Context, allowanceType string) (result tax.Setting, err error) {
+	sqlStr := "SELECT allowance_amount, version FROM allowance WHERE allowance_type=$1"
+	rows, err := p.Db.QueryContext(ctx, sqlStr, allowanceType)
 	if err != nil {
 		return result, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		err = rows.Scan(&result)
+		err = rows.Scan(&result.Amount, &result.Version)
 		if err != nil {
 			return result, err
 		}
 	}
 	return result, nil
 }
+
+func (p *Postgres) GetAllowanceSetting(ctx context.Context, allowanceType string) (result tax.Setting, err error) {
+	ctx, end := p.trace(ctx, "GetAllowanceSetting")
+	defer func() { end(err) }()
+	return p.getAllowanceSetting(ctx, allowanceType)
+}
+
+func (p *Postgres) Close() error {
+	return p.Db.Close()
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/apirom9/assessment-tax/ratelimit"
+)
+
+// Take runs the token bucket of the key inside a transaction holding a row
+// lock, so replicas sharing the database share the same buckets.
+func (p *Postgres) Take(key string, limit ratelimit.Limit, cost int, now time.Time) (ratelimit.Decision, error) {
+	defer p.observe("Take", time.Now())
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return ratelimit.Decision{}, err
+	}
+	defer tx.Rollback()
+
+	bucket := ratelimit.NewBucket(limit, now)
+	sqlStr := "INSERT INTO rate_limit_bucket (bucket_key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $3) ON CONFLICT (bucket_key) DO NOTHING"
+	if _, err := tx.Exec(sqlStr, key, bucket.Tokens, bucket.UpdatedAt); err != nil {
+		return ratelimit.Decision{}, err
+	}
+	sqlStr = "SELECT tokens, updated_at FROM rate_limit_bucket WHERE bucket_key=$1 FOR UPDATE"
+	if err := tx.QueryRow(sqlStr, key).Scan(&bucket.Tokens, &bucket.UpdatedAt); err != nil {
+		return ratelimit.Decision{}, err
+	}
+	decision := bucket.Take(limit, cost, now)
+	sqlStr = "UPDATE rate_limit_bucket SET tokens=$2, updated_at=$3, full_at=$4 WHERE bucket_key=$1"
+	if _, err := tx.Exec(sqlStr, key, bucket.Tokens, bucket.UpdatedAt, bucket.FullAt(limit)); err != nil {
+		return ratelimit.Decision{}, err
+	}
+	return decision, tx.Commit()
+}
+
+func (p *Postgres) usedQuota(key string, day time.Time) (int, error) {
+	used := 0
+	err := p.Db.QueryRow("SELECT used FROM usage_quota WHERE quota_key=$1 AND quota_day=$2", key, day).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return used, err
+}
+
+// Consume adds to the usage with a single conditional upsert, no row is
+// returned when the quota would be exceeded. Refunds stop at zero.
+func (p *Postgres) Consume(key string, day time.Time, amount int, limit int) (int, bool, error) {
+	defer p.observe("Consume", time.Now())
+	if amount > limit {
+		used, err := p.usedQuota(key, day)
+		return used, false, err
+	}
+	sqlStr := `INSERT INTO usage_quota (quota_key, quota_day, used) VALUES ($1, $2, GREATEST($3, 0))
+		ON CONFLICT (quota_key, quota_day) DO UPDATE SET used=GREATEST(usage_quota.used+$3, 0)
+		WHERE usage_quota.used+$3 <= $4
+		RETURNING used`
+	used := 0
+	err := p.Db.QueryRow(sqlStr, key, day, amount, limit).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		used, err = p.usedQuota(key, day)
+		return used, false, err
+	}
+	if err != nil {
+		return used, false, err
+	}
+	return used, true, nil
+}
+
+// DeleteExpired deletes the buckets that have been refilled, they would be
+// recreated full, and the usage of past days.
+func (p *Postgres) DeleteExpired(now time.Time) error {
+	defer p.observe("DeleteExpired", time.Now())
+	if _, err := p.Db.Exec("DELETE FROM rate_limit_bucket WHERE full_at <= $1", now); err != nil {
+		return err
+	}
+	_, err := p.Db.Exec("DELETE FROM usage_quota WHERE quota_day < $1", ratelimit.QuotaDay(now))
+	return err
+}
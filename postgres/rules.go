@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/apirom9/assessment-tax/tax"
+)
+
+const ruleSetColumns = "tax_year, personal_deduction_min, personal_deduction_max, k_receipt_min, k_receipt_max, donation_max, version"
+
+func scanRuleSet(row *sql.Row) (tax.RuleSet, error) {
+	var rules tax.RuleSet
+	err := row.Scan(&rules.TaxYear, &rules.PersonalDeductionMin, &rules.PersonalDeductionMax,
+		&rules.KReceiptMin, &rules.KReceiptMax, &rules.DonationMax, &rules.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return rules, tax.ErrRuleSetNotFound
+	}
+	return rules, err
+}
+
+func (p *Postgres) getRuleSet(ctx context.Context, taxYear int) (tax.RuleSet, error) {
+	sqlStr := "SELECT " + ruleSetColumns + " FROM tax_rule_set WHERE tax_year=$1"
+	return scanRuleSet(p.Db.QueryRowContext(ctx, sqlStr, taxYear))
+}
+
+func (p *Postgres) GetRuleSet(ctx context.Context, taxYear int) (result tax.RuleSet, err error) {
+	ctx, end := p.trace(ctx, "GetRuleSet")
+	defer func() { end(err) }()
+	return p.getRuleSet(ctx, taxYear)
+}
+
+// UpdateRuleSet stores the rule set if the current version of its tax year is
+// version, incrementing the version. Otherwise it returns the current rule set.
+func (p *Postgres) UpdateRuleSet(ctx context.Context, rules tax.RuleSet, version int) (result tax.RuleSet, updated bool, err error) {
+	ctx, end := p.trace(ctx, "UpdateRuleSet")
+	defer func() { end(err) }()
+	sqlStr := `UPDATE tax_rule_set SET personal_deduction_min=$1, personal_deduction_max=$2, k_receipt_min=$3, k_receipt_max=$4, donation_max=$5, version=version+1
+		WHERE tax_year=$6 AND version=$7 RETURNING ` + ruleSetColumns
+	result, err = scanRuleSet(p.Db.QueryRowContext(ctx, sqlStr, rules.PersonalDeductionMin, rules.PersonalDeductionMax,
+		rules.KReceiptMin, rules.KReceiptMax, rules.DonationMax, rules.TaxYear, version))
+	if errors.Is(err, tax.ErrRuleSetNotFound) {
+		result, err = p.getRuleSet(ctx, rules.TaxYear)
+		return result, false, err
+	}
+	return result, err == nil, err
+}
+
+// CreateRuleSet stores the rule set at version 1 if its tax year has none.
+// Otherwise it returns the current rule set.
+func (p *Postgres) CreateRuleSet(ctx context.Context, rules tax.RuleSet) (result tax.RuleSet, created bool, err error) {
+	ctx, end := p.trace(ctx, "CreateRuleSet")
+	defer func() { end(err) }()
+	sqlStr := `INSERT INTO tax_rule_set (tax_year, personal_deduction_min, personal_deduction_max, k_receipt_min, k_receipt_max, donation_max)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tax_year) DO NOTHING RETURNING ` + ruleSetColumns
+	result, err = scanRuleSet(p.Db.QueryRowContext(ctx, sqlStr, rules.TaxYear, rules.PersonalDeductionMin, rules.PersonalDeductionMax,
+		rules.KReceiptMin, rules.KReceiptMax, rules.DonationMax))
+	if errors.Is(err, tax.ErrRuleSetNotFound) {
+		result, err = p.getRuleSet(ctx, rules.TaxYear)
+		return result, false, err
+	}
+	return result, err == nil, err
+}
+
+// ApplyRules upserts the rule set and the allowance settings in one
+// transaction if their versions, read with the rows locked, are versions.
+// Rows whose values do not change keep their version.
+func (p *Postgres) ApplyRules(ctx context.Context, rules tax.RuleSet, allowances map[string]float64, versions tax.RulesVersions) (applied bool, err error) {
+	ctx, end := p.trace(ctx, "ApplyRules")
+	defer func() { end(err) }()
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current := tax.RulesVersions{Allowances: map[string]int{}}
+	err = tx.QueryRowContext(ctx, "SELECT version FROM tax_rule_set WHERE tax_year=$1 FOR UPDATE", rules.TaxYear).Scan(&current.RuleSet)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	for allowanceType := range allowances {
+		var version int
+		err = tx.QueryRowContext(ctx, "SELECT version FROM allowance WHERE allowance_type=$1 FOR UPDATE", allowanceType).Scan(&version)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		current.Allowances[allowanceType] = version
+	}
+	if !current.Equal(versions) {
+		return false, nil
+	}
+
+	// A year without a rule set has no row to lock, so a rule set created
+	// since the versions were read makes the insert a conflict.
+	sqlStr := `INSERT INTO tax_rule_set (tax_year, personal_deduction_min, personal_deduction_max, k_receipt_min, k_receipt_max, donation_max)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tax_year) DO NOTHING`
+	if versions.RuleSet != 0 {
+		sqlStr = `UPDATE tax_rule_set SET personal_deduction_min=$2, personal_deduction_max=$3, k_receipt_min=$4, k_receipt_max=$5, donation_max=$6, version=version+1
+			WHERE tax_year=$1 AND (personal_deduction_min, personal_deduction_max, k_receipt_min, k_receipt_max, donation_max) IS DISTINCT FROM ($2, $3, $4, $5, $6)`
+	}
+	result, err := tx.ExecContext(ctx, sqlStr, rules.TaxYear, rules.PersonalDeductionMin, rules.PersonalDeductionMax, rules.KReceiptMin, rules.KReceiptMax, rules.DonationMax)
+	if err != nil {
+		return false, err
+	}
+	if versions.RuleSet == 0 {
+		if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+			return false, err
+		}
+	}
+	sqlStr = `INSERT INTO allowance (allowance_type, allowance_amount) VALUES ($1, $2)
+		ON CONFLICT (allowance_type) DO UPDATE SET allowance_amount=EXCLUDED.allowance_amount, version=allowance.version+1
+		WHERE allowance.allowance_amount <> EXCLUDED.allowance_amount`
+	for allowanceType, amount := range allowances {
+		if _, err = tx.ExecContext(ctx, sqlStr, allowanceType, amount); err != nil {
+			return false, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
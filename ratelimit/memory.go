@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const sweepThreshold = 10000
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// MemoryStore keeps buckets and quotas in process memory, which is enough for
+// a single replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	usage   map[string]int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}, usage: map[string]int{}}
+}
+
+func (m *MemoryStore) Take(key string, limit Limit, cost int, now time.Time) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.buckets) >= sweepThreshold {
+		m.sweep(now)
+	}
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{Bucket: NewBucket(limit, now), limit: limit}
+		m.buckets[key] = bucket
+	}
+	bucket.limit = limit
+	return bucket.Take(limit, cost, now), nil
+}
+
+// sweep drops buckets that have been refilled, they would be recreated full.
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, bucket := range m.buckets {
+		if bucket.Full(bucket.limit, now) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// DeleteExpired drops full buckets and the usage of past days.
+func (m *MemoryStore) DeleteExpired(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	today := QuotaDay(now).Format(time.DateOnly)
+	for usageKey := range m.usage {
+		if day, _, _ := strings.Cut(usageKey, ":"); day < today {
+			delete(m.usage, usageKey)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Consume(key string, day time.Time, amount int, limit int) (int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dayKey := day.Format(time.DateOnly) + ":" + key
+	used := m.usage[dayKey]
+	if used+amount > limit {
+		return used, false, nil
+	}
+	if len(m.usage) >= sweepThreshold {
+		prefix := day.Format(time.DateOnly) + ":"
+		for usageKey := range m.usage {
+			if !strings.HasPrefix(usageKey, prefix) {
+				delete(m.usage, usageKey)
+			}
+		}
+	}
+	used = max(used+amount, 0)
+	m.usage[dayKey] = used
+	return used, true, nil
+}
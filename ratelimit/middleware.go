@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderQuotaLimit         = "X-Quota-Limit"
+	HeaderQuotaRemaining     = "X-Quota-Remaining"
+)
+
+// contextKeyQuotaDay prefixes the name of a quota to hold the day the rows of
+// the request were charged to.
+const contextKeyQuotaDay = "quota_day:"
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+const clientKeyPrefix = "client:"
+
+// Key identifies a caller by the id of its authenticated client, falling
+// back to its IP address when clientID is empty. REST and gRPC callers share
+// the buckets and quotas of their key.
+func Key(clientID, ip string) string {
+	if clientID != "" {
+		return clientKeyPrefix + clientID
+	}
+	return "ip:" + ip
+}
+
+// ClientKey returns the Key of the caller of the request.
+func ClientKey(c echo.Context) string {
+	if client, ok := auth.ClientFromContext(c); ok {
+		return Key(client.ID, "")
+	}
+	return Key("", c.RealIP())
+}
+
+func seconds(duration time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(duration.Seconds())))
+}
+
+type Limiter struct {
+	Store Store
+	Now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{Store: store, Now: time.Now}
+}
+
+// Take takes a token of the route's bucket of the caller key, which is
+// limited to the limit of the key.
+func (l *Limiter) Take(route, key string, limits Limits) (Decision, error) {
+	return l.Store.Take(route+":"+key, limits.For(key), 1, l.Now())
+}
+
+// Middleware limits each client to its limit on the route. Every response
+// carries the RateLimit-* headers and rejected requests get a 429 with
+// Retry-After.
+func (l *Limiter) Middleware(route string, limits Limits) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			decision, err := l.Take(route, ClientKey(c), limits)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+			}
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(decision.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(decision.Remaining))
+			header.Set(HeaderRateLimitReset, seconds(decision.Reset))
+			if !decision.Allowed {
+				header.Set(echo.HeaderRetryAfter, seconds(decision.RetryAfter))
+				return c.JSON(http.StatusTooManyRequests, Err{Message: "Too many requests"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// Quota is a daily allowance of units, such as CSV rows, per client. Days
+// start at midnight UTC. Clients holds the allowance of clients, keyed by
+// client id, that do not get Limit.
+type Quota struct {
+	Store   QuotaStore
+	Name    string
+	Limit   int
+	Clients map[string]int
+	Now     func() time.Time
+}
+
+func NewQuota(store QuotaStore, name string, limit int) *Quota {
+	return &Quota{Store: store, Name: name, Limit: limit, Now: time.Now}
+}
+
+// QuotaDecision is the outcome of consuming a quota on Day.
+type QuotaDecision struct {
+	Day        time.Time
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// limit returns the daily allowance of the caller key.
+func (q *Quota) limit(key string) int {
+	return clientLimit(key, q.Clients, q.Limit)
+}
+
+// Consume takes rows from the daily quota of the caller key. It consumes
+// nothing when the rows do not fit into what is left for the day, in which
+// case RetryAfter is the time until the next day.
+func (q *Quota) Consume(key string, rows int) (QuotaDecision, error) {
+	now := q.Now().UTC()
+	day := QuotaDay(now)
+	limit := q.limit(key)
+	used, ok, err := q.Store.Consume(q.Name+":"+key, day, rows, limit)
+	if err != nil {
+		return QuotaDecision{}, err
+	}
+	decision := QuotaDecision{Day: day, Allowed: ok, Limit: limit, Remaining: limit - used}
+	if !ok {
+		decision.RetryAfter = day.AddDate(0, 0, 1).Sub(now)
+	}
+	return decision, nil
+}
+
+// ConsumeRows takes rows from the daily quota of the client of the request
+// and sets the quota headers. It reports false without consuming anything
+// when the rows do not fit into what is left for the day.
+func (q *Quota) ConsumeRows(c echo.Context, rows int) (bool, error) {
+	decision, err := q.Consume(ClientKey(c), rows)
+	if err != nil {
+		return false, err
+	}
+	if decision.Allowed {
+		c.Set(contextKeyQuotaDay+q.Name, decision.Day)
+	}
+	header := c.Response().Header()
+	header.Set(HeaderQuotaLimit, strconv.Itoa(decision.Limit))
+	header.Set(HeaderQuotaRemaining, strconv.Itoa(decision.Remaining))
+	if !decision.Allowed {
+		header.Set(echo.HeaderRetryAfter, seconds(decision.RetryAfter))
+	}
+	return decision.Allowed, nil
+}
+
+// RefundRows gives rows consumed by the request back to the quota of the
+// day ConsumeRows charged them to, which is today if it charged none.
+func (q *Quota) RefundRows(c echo.Context, rows int) error {
+	day, ok := c.Get(contextKeyQuotaDay + q.Name).(time.Time)
+	if !ok {
+		day = QuotaDay(q.Now())
+	}
+	key := ClientKey(c)
+	_, _, err := q.Store.Consume(q.Name+":"+key, day, -rows, q.limit(key))
+	return err
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that holds up to Burst tokens and is refilled with
+// Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit such as "60/m", meaning a burst of 60 requests
+// refilled over one minute. The unit is one of s, m, h or d.
+func ParseLimit(value string) (Limit, error) {
+	count, unit, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<unit>", value)
+	}
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit count %q", count)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit unit %q", unit)
+	}
+	return Limit{Rate: float64(burst) / period.Seconds(), Burst: burst}, nil
+}
+
+// Limits is the limit of every client except those in Clients, which are
+// keyed by client id and have their own limit.
+type Limits struct {
+	Default Limit
+	Clients map[string]Limit
+}
+
+// For returns the limit of the caller key.
+func (l Limits) For(key string) Limit {
+	return clientLimit(key, l.Clients, l.Default)
+}
+
+// clientLimit returns the limit of the client of the caller key in clients,
+// or fallback for other clients and callers known by IP address.
+func clientLimit[T any](key string, clients map[string]T, fallback T) T {
+	if clientID, ok := strings.CutPrefix(key, clientKeyPrefix); ok {
+		if limit, ok := clients[clientID]; ok {
+			return limit
+		}
+	}
+	return fallback
+}
+
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds)) * time.Second
+}
+
+// Take refills the bucket up to now and takes cost tokens from it when there
+// are enough.
+func (b *Bucket) Take(limit Limit, cost int, now time.Time) Decision {
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+		b.UpdatedAt = now
+	}
+	decision := Decision{Limit: limit.Burst}
+	if b.Tokens >= float64(cost) {
+		b.Tokens = b.Tokens - float64(cost)
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((float64(cost) - b.Tokens) / limit.Rate)
+	}
+	decision.Remaining = int(math.Floor(b.Tokens))
+	decision.Reset = secondsToDuration((float64(limit.Burst) - b.Tokens) / limit.Rate)
+	return decision
+}
+
+// Full reports whether the bucket would be full at now, in which case it can
+// be forgotten without changing any decision.
+func (b Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+now.Sub(b.UpdatedAt).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+// FullAt returns when the bucket will have been refilled to its burst.
+func (b Bucket) FullAt(limit Limit) time.Time {
+	missing := math.Max(float64(limit.Burst)-b.Tokens, 0)
+	return b.UpdatedAt.Add(time.Duration(math.Ceil(missing / limit.Rate * float64(time.Second))))
+}
+
+// QuotaDay returns the quota day of now, which starts at midnight UTC.
+func QuotaDay(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Store keeps the token buckets. Take must be atomic per key so that
+// concurrent requests cannot spend the same tokens.
+type Store interface {
+	Take(key string, limit Limit, cost int, now time.Time) (Decision, error)
+}
+
+// QuotaStore counts usage per key and day. Consume adds amount to the usage
+// only when the result stays within limit and returns the usage afterwards.
+// A negative amount refunds usage, which never drops below zero.
+type QuotaStore interface {
+	Consume(key string, day time.Time, amount int, limit int) (int, bool, error)
+}
+
+// Sweeper deletes what a store no longer needs at now: buckets that would be
+// full and the usage of days before the quota day of now.
+type Sweeper interface {
+	DeleteExpired(now time.Time) error
+}
+
+// Sweep deletes expired buckets and quota usage of store every interval until
+// ctx is done.
+func Sweep(ctx context.Context, store Sweeper, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.DeleteExpired(time.Now()); err != nil {
+				logger.WarnContext(ctx, "unable to delete expired rate limits", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apirom9/assessment-tax/auth"
+	"github.com/labstack/echo/v4"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Run("given 60/m should allow burst 60 refilled at 1 per second", func(t *testing.T) {
+		got, err := ParseLimit("60/m")
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		want := Limit{Rate: 1, Burst: 60}
+		if got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given invalid limits should return error", func(t *testing.T) {
+		for _, value := range []string{"60", "0/m", "x/m", "60/w"} {
+			if _, err := ParseLimit(value); err == nil {
+				t.Errorf("expect error for %v but got nil", value)
+			}
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("given burst used up should reject until tokens are refilled", func(t *testing.T) {
+		store := NewMemoryStore()
+		limit := Limit{Rate: 1, Burst: 2}
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 2; i++ {
+			if decision, _ := store.Take("key", limit, 1, now); !decision.Allowed {
+				t.Errorf("expect request %v to be allowed", i+1)
+			}
+		}
+		decision, _ := store.Take("key", limit, 1, now)
+		want := Decision{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second}
+		if decision != want {
+			t.Errorf("expected %v but got %v", want, decision)
+		}
+		if decision, _ := store.Take("other", limit, 1, now); !decision.Allowed {
+			t.Errorf("expect other key to have its own bucket")
+		}
+		if decision, _ := store.Take("key", limit, 1, now.Add(time.Second)); !decision.Allowed {
+			t.Errorf("expect request to be allowed after refill")
+		}
+	})
+
+	t.Run("given daily quota should reject rows over the limit and reset next day", func(t *testing.T) {
+		store := NewMemoryStore()
+		day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+		if used, ok, _ := store.Consume("key", day, 8, 10); !ok || used != 8 {
+			t.Errorf("expect 8 rows to be consumed but got %v, %v", used, ok)
+		}
+		if used, ok, _ := store.Consume("key", day, 3, 10); ok || used != 8 {
+			t.Errorf("expect 3 rows to be rejected but got %v, %v", used, ok)
+		}
+		if used, ok, _ := store.Consume("key", day.AddDate(0, 0, 1), 3, 10); !ok || used != 3 {
+			t.Errorf("expect quota to reset next day but got %v, %v", used, ok)
+		}
+	})
+
+	t.Run("given full buckets and usage of past days should delete them when expired", func(t *testing.T) {
+		store := NewMemoryStore()
+		limit := Limit{Rate: 1, Burst: 2}
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		store.Take("spent", limit, 2, now)
+		store.Take("refilled", limit, 1, now.Add(-time.Minute))
+		store.Consume("key", QuotaDay(now).AddDate(0, 0, -1), 3, 10)
+		store.Consume("key", QuotaDay(now), 5, 10)
+
+		if err := store.DeleteExpired(now); err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if _, ok := store.buckets["refilled"]; ok || len(store.buckets) != 1 {
+			t.Errorf("expect only the spent bucket to be kept but got %v", store.buckets)
+		}
+		if want := map[string]int{"2024-05-01:key": 5}; !reflect.DeepEqual(store.usage, want) {
+			t.Errorf("expected usage %v but got %v", want, store.usage)
+		}
+	})
+}
+
+func TestBucketFullAt(t *testing.T) {
+	t.Run("given bucket with 1.5 tokens missing at rate 0.5 should be full after 3 seconds", func(t *testing.T) {
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		bucket := Bucket{Tokens: 2.5, UpdatedAt: now}
+
+		got := bucket.FullAt(Limit{Rate: 0.5, Burst: 4})
+		if want := now.Add(3 * time.Second); !got.Equal(want) || !bucket.Full(Limit{Rate: 0.5, Burst: 4}, got) {
+			t.Errorf("expected bucket to be full at %v but got %v", want, got)
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	limiter := &Limiter{Store: NewMemoryStore(), Now: func() time.Time { return now }}
+	limits := Limits{Default: Limit{Rate: 0.5, Burst: 1}, Clients: map[string]Limit{"partner": {Rate: 1, Burst: 5}}}
+	handler := limiter.Middleware("calculations", limits)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	serve := func(client *auth.Client) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		if client != nil {
+			c.Set(auth.ContextKeyClient, *client)
+		}
+		handler(c)
+		return res
+	}
+
+	t.Run("given request within limit should set rate limit headers", func(t *testing.T) {
+		res := serve(nil)
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+		if got := res.Header().Get(HeaderRateLimitLimit); got != "1" {
+			t.Errorf("expected limit header 1 but got %v", got)
+		}
+		if got := res.Header().Get(HeaderRateLimitRemaining); got != "0" {
+			t.Errorf("expected remaining header 0 but got %v", got)
+		}
+		if got := res.Header().Get(HeaderRateLimitReset); got != "2" {
+			t.Errorf("expected reset header 2 but got %v", got)
+		}
+	})
+
+	t.Run("given request over limit should return 429 with retry after", func(t *testing.T) {
+		res := serve(nil)
+
+		if res.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status %v but got status %v", http.StatusTooManyRequests, res.Code)
+		}
+		if got := res.Header().Get(echo.HeaderRetryAfter); got != "2" {
+			t.Errorf("expected retry after 2 but got %v", got)
+		}
+	})
+
+	t.Run("given api key client from same ip should be limited separately", func(t *testing.T) {
+		res := serve(&auth.Client{ID: "apikey:9f86d081884c7d65", Method: auth.ClientMethodAPIKey})
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+	})
+	t.Run("given client with its own limit should set its limit header", func(t *testing.T) {
+		res := serve(&auth.Client{ID: "partner", Method: auth.ClientMethodAPIKey})
+
+		if got := res.Header().Get(HeaderRateLimitLimit); res.Code != http.StatusOK || got != "5" {
+			t.Errorf("expected status %v with limit header 5 but got status %v with %v", http.StatusOK, res.Code, got)
+		}
+	})
+}
+
+func TestQuota(t *testing.T) {
+	t.Run("given rows over daily quota should not consume and set retry after until midnight", func(t *testing.T) {
+		quota := NewQuota(NewMemoryStore(), "csv-rows", 5)
+		quota.Now = func() time.Time { return time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC) }
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+		if ok, _ := quota.ConsumeRows(c, 3); !ok {
+			t.Errorf("expect 3 rows to be within quota")
+		}
+		ok, _ := quota.ConsumeRows(c, 3)
+		if ok {
+			t.Errorf("expect 3 more rows to exceed quota")
+		}
+		header := c.Response().Header()
+		if got := header.Get(HeaderQuotaRemaining); got != "2" {
+			t.Errorf("expected remaining 2 but got %v", got)
+		}
+		if got := header.Get(echo.HeaderRetryAfter); got != "3600" {
+			t.Errorf("expected retry after 3600 but got %v", got)
+		}
+	})
+
+	t.Run("given refunded rows should be consumable again", func(t *testing.T) {
+		quota := NewQuota(NewMemoryStore(), "csv-rows", 5)
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+		quota.ConsumeRows(c, 5)
+		if err := quota.RefundRows(c, 5); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if ok, _ := quota.ConsumeRows(c, 5); !ok {
+			t.Errorf("expect refunded rows to be within quota")
+		}
+	})
+
+	t.Run("given client with its own quota should consume within its quota", func(t *testing.T) {
+		quota := NewQuota(NewMemoryStore(), "csv-rows", 5)
+		quota.Clients = map[string]int{"partner": 20}
+
+		decision, err := quota.Consume(Key("partner", ""), 10)
+		if err != nil || !decision.Allowed || decision.Limit != 20 || decision.Remaining != 10 {
+			t.Errorf("expected 10 of 20 rows to remain but got %+v, %v", decision, err)
+		}
+		if decision, _ := quota.Consume(Key("", "192.0.2.1"), 10); decision.Allowed {
+			t.Errorf("expected 10 rows to exceed the default quota of 5")
+		}
+	})
+
+	t.Run("given rows refunded after midnight should refund the day they were charged to", func(t *testing.T) {
+		store := NewMemoryStore()
+		quota := NewQuota(store, "csv-rows", 5)
+		now := time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC)
+		quota.Now = func() time.Time { return now }
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+		quota.ConsumeRows(c, 5)
+		now = now.Add(2 * time.Second)
+		if err := quota.RefundRows(c, 5); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		want := map[string]int{"2024-05-01:csv-rows:ip:192.0.2.1": 0}
+		if !reflect.DeepEqual(store.usage, want) {
+			t.Errorf("expected usage %v but got %v", want, store.usage)
+		}
+	})
+
+	t.Run("given refund of more rows than used should clamp usage at zero", func(t *testing.T) {
+		store := NewMemoryStore()
+		day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		store.Consume("key", day, 2, 10)
+
+		if used, ok, _ := store.Consume("key", day, -5, 10); !ok || used != 0 {
+			t.Errorf("expect usage to be clamped at 0 but got %v, %v", used, ok)
+		}
+	})
+}
+
+func TestClientKey(t *testing.T) {
+	t.Run("given forwarded headers and direct IP extractor should key by peer address", func(t *testing.T) {
+		e := echo.New()
+		e.IPExtractor = echo.ExtractIPDirect()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+		req.Header.Set(echo.HeaderXRealIP, "203.0.113.10")
+
+		if got := ClientKey(e.NewContext(req, httptest.NewRecorder())); got != "ip:192.0.2.1" {
+			t.Errorf("expected key of peer address but got %v", got)
+		}
+	})
+}
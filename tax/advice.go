@@ -0,0 +1,109 @@
+package tax
+
+import "math"
+
+const (
+	AllowanceTypeDonation = "donation"
+	AllowanceTypeKReceipt = "k-receipt"
+)
+
+// AdvisedAllowanceTypes are the allowances a taxpayer can buy into, in the
+// order the advisor fills them. Every allowance reduces taxable income baht
+// for baht, so k-receipt comes first as the money buys goods for the
+// taxpayer.
+var AdvisedAllowanceTypes = []string{AllowanceTypeKReceipt, AllowanceTypeDonation}
+
+type AllowanceAdvice struct {
+	Type        string
+	Claimed     float64
+	Recommended float64
+	Cap         float64
+	Headroom    float64
+}
+
+func (t *Calulator) allowanceClaim(allowanceType string) (claimed, cap float64) {
+	switch allowanceType {
+	case AllowanceTypeKReceipt:
+		return t.AllowanceKReceipt, t.MaxAllowanceKReceipt
+	case AllowanceTypeDonation:
+		return t.AllowanceDonation, t.MaxAllowanceDonation
+	}
+	return 0, 0
+}
+
+func (t *Calulator) addAllowance(allowanceType string, amount float64) {
+	switch allowanceType {
+	case AllowanceTypeKReceipt:
+		t.AllowanceKReceipt = t.AllowanceKReceipt + amount
+	case AllowanceTypeDonation:
+		t.AllowanceDonation = t.AllowanceDonation + amount
+	}
+}
+
+// withExtraAllowances spends up to amount on the advised allowances in order,
+// never beyond each cap, and returns the calculator with the spend applied and
+// the amount spent per allowance type.
+func (t *Calulator) withExtraAllowances(amount float64) (Calulator, map[string]float64) {
+	calculator := *t
+	spent := map[string]float64{}
+	for _, allowanceType := range AdvisedAllowanceTypes {
+		claimed, cap := calculator.allowanceClaim(allowanceType)
+		spend := math.Min(math.Max(cap-claimed, 0), amount)
+		calculator.addAllowance(allowanceType, spend)
+		spent[allowanceType] = spend
+		amount = amount - spend
+	}
+	return calculator, spent
+}
+
+// AdviseAllowances recommends how to spend a budget on deductible items to
+// minimise tax. It spends only as much as still lowers the tax, so once
+// taxable income falls into the tax-free level the rest of the budget is kept.
+func (t *Calulator) AdviseAllowances(budget float64) ([]AllowanceAdvice, Calulator, error) {
+	if budget < 0 {
+		return nil, *t, NewLocalizedError(MsgAdviceBudgetNegative)
+	}
+
+	usable := 0.0
+	for _, allowanceType := range AdvisedAllowanceTypes {
+		claimed, cap := t.allowanceClaim(allowanceType)
+		usable = usable + math.Max(cap-claimed, 0)
+	}
+	usable = math.Min(usable, budget)
+
+	best, _ := t.withExtraAllowances(usable)
+	minimumTax := best.CalculateTaxResult().Amount
+	low, high := 0.0, usable
+	for high-low > 0.005 {
+		middle := (low + high) / 2
+		calculator, _ := t.withExtraAllowances(middle)
+		if calculator.CalculateTaxResult().Amount <= minimumTax {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	satang := math.Floor(low * 100)
+	for satang/100 < usable {
+		calculator, _ := t.withExtraAllowances(satang / 100)
+		if calculator.CalculateTaxResult().Amount <= minimumTax {
+			break
+		}
+		satang = satang + 1
+	}
+
+	calculator, spent := t.withExtraAllowances(math.Min(satang/100, usable))
+	var advice []AllowanceAdvice
+	for _, allowanceType := range AdvisedAllowanceTypes {
+		claimed, cap := t.allowanceClaim(allowanceType)
+		advice = append(advice, AllowanceAdvice{
+			Type:        allowanceType,
+			Claimed:     claimed,
+			Recommended: spent[allowanceType],
+			Cap:         cap,
+			Headroom:    math.Max(cap-claimed-spent[allowanceType], 0),
+		})
+	}
+	return advice, calculator, nil
+}
@@ -0,0 +1,75 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdviseAllowances(t *testing.T) {
+	t.Run("given total income 500000.0 and budget 100000.0 should fill k-receipt then donation", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+
+		got, advised, err := taxCalulator.AdviseAllowances(100000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		want := []AllowanceAdvice{
+			{Type: "k-receipt", Claimed: 0.00, Recommended: 50000.00, Cap: 50000.00, Headroom: 0.00},
+			{Type: "donation", Claimed: 0.00, Recommended: 50000.00, Cap: 100000.00, Headroom: 50000.00},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if tax := advised.CalculateTaxResult().Amount; tax != 19000.00 {
+			t.Errorf("expect tax = %v but got %v", 19000.00, tax)
+		}
+	})
+
+	t.Run("given total income 300000.0 and budget 150000.0 should spend only 90000.0 to reach zero tax", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 300000.00
+
+		got, advised, err := taxCalulator.AdviseAllowances(150000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		want := []AllowanceAdvice{
+			{Type: "k-receipt", Claimed: 0.00, Recommended: 50000.00, Cap: 50000.00, Headroom: 0.00},
+			{Type: "donation", Claimed: 0.00, Recommended: 40000.00, Cap: 100000.00, Headroom: 60000.00},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if tax := advised.CalculateTaxResult().Amount; tax != 0.00 {
+			t.Errorf("expect tax = %v but got %v", 0.00, tax)
+		}
+	})
+
+	t.Run("given existing k-receipt claim 30000.0 should only recommend remaining headroom", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 1000000.00
+		taxCalulator.AllowanceKReceipt = 30000.00
+
+		got, _, err := taxCalulator.AdviseAllowances(10000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		want := []AllowanceAdvice{
+			{Type: "k-receipt", Claimed: 30000.00, Recommended: 10000.00, Cap: 50000.00, Headroom: 10000.00},
+			{Type: "donation", Claimed: 0.00, Recommended: 0.00, Cap: 100000.00, Headroom: 100000.00},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given negative budget should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		_, _, err := taxCalulator.AdviseAllowances(-1.00)
+		if err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+}
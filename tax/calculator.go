@@ -16,6 +16,7 @@ type Calulator struct {
 	AllowancePersonal    float64
 	AllowanceDonation    float64
 	AllowanceKReceipt    float64
+	AllowanceSpouse      float64
 	MaxAllowancePersonal float64
 	MaxAllowanceDonation float64
 	MaxAllowanceKReceipt float64
@@ -26,9 +27,22 @@ type LevelAmount struct {
 	Amount float64
 }
 
+// Result holds the tax after withholding tax in Amount, the tax per level and
+// the rates derived from them. Rates are percentages. The marginal level is
+// the one the last baht of taxable income is taxed in and the next level
+// threshold is the first whole baht of taxable income taxed in the next one.
 type Result struct {
-	Amount       float64
-	LevelAmounts []LevelAmount
+	Amount                 float64
+	LevelAmounts           []LevelAmount
+	TotalTax               float64
+	TaxableIncome          float64
+	EffectiveRateOnGross   float64
+	EffectiveRateOnNet     float64
+	MarginalLevel          Level
+	MarginalRatePercentage float64
+	HasNextLevel           bool
+	NextLevelThreshold     float64
+	IncomeToNextLevel      float64
 }
 
 func CreateLevels() []Level {
@@ -42,14 +56,20 @@ func CreateLevels() []Level {
 }
 
 func NewTaxCalulator(defaultAllowancePersonal, maxAllowanceKReceipt float64) Calulator {
+	return NewTaxCalulatorWithRules(DefaultRuleSet, defaultAllowancePersonal, maxAllowanceKReceipt)
+}
+
+// NewTaxCalulatorWithRules returns a calculator capping allowances with the
+// bounds of rules.
+func NewTaxCalulatorWithRules(rules RuleSet, defaultAllowancePersonal, maxAllowanceKReceipt float64) Calulator {
 	return Calulator{
 		Levels:               CreateLevels(),
 		TotalIncome:          0.00,
 		AllowancePersonal:    defaultAllowancePersonal,
 		AllowanceDonation:    0.00,
 		AllowanceKReceipt:    0.00,
-		MaxAllowancePersonal: 100000.00,
-		MaxAllowanceDonation: 100000.00,
+		MaxAllowancePersonal: rules.PersonalDeductionMax,
+		MaxAllowanceDonation: rules.DonationMax,
 		MaxAllowanceKReceipt: maxAllowanceKReceipt,
 	}
 }
@@ -61,6 +81,15 @@ func (t *Calulator) GetAllowancePersonal() float64 {
 	return t.AllowancePersonal
 }
 
+// GetAllowanceSpouse returns the allowance for a spouse without income, which
+// is capped like the personal allowance.
+func (t *Calulator) GetAllowanceSpouse() float64 {
+	if t.AllowanceSpouse > t.MaxAllowancePersonal {
+		return t.MaxAllowancePersonal
+	}
+	return t.AllowanceSpouse
+}
+
 func (t *Calulator) GetAllowanceDonation() float64 {
 	if t.AllowanceDonation > t.MaxAllowanceDonation {
 		return t.MaxAllowanceDonation
@@ -86,16 +115,29 @@ func (t *Calulator) CalculateTax(remainIncome, taxLevelMaxAmount, taxLevelPercen
 }
 
 func (t *Calulator) CalculateIncomeAfterAllowances() float64 {
-	return t.TotalIncome - t.GetAllowancePersonal() - t.GetAllowanceDonation() - t.GetAllowanceKReceipt()
+	return t.TotalIncome - t.GetAllowancePersonal() - t.GetAllowanceSpouse() - t.GetAllowanceDonation() - t.GetAllowanceKReceipt()
+}
+
+func percentageOf(amount, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(amount/total*10000) / 100
 }
 
 func (t *Calulator) CalculateTaxResult() Result {
 
 	var totalTaxAmount float64
 	var taxAmountLevels []LevelAmount
-	remainIncome := t.CalculateIncomeAfterAllowances()
-
-	for _, taxLevel := range t.Levels {
+	taxableIncome := t.CalculateIncomeAfterAllowances()
+	remainIncome := taxableIncome
+	// Each level taxes the next MaxAmount of income, so the marginal level
+	// is the first whose band ends at or above the taxable income.
+	marginalIndex := -1
+	bandEnd := 0.0
+	var marginalBandEnd float64
+
+	for index, taxLevel := range t.Levels {
 		taxAmountLevel := LevelAmount{Level: taxLevel.Level}
 		if remainIncome > 0.0 {
 			taxAmountLevel.Amount = t.CalculateTax(remainIncome, taxLevel.MaxAmount, taxLevel.TaxRatePercentage)
@@ -103,12 +145,37 @@ func (t *Calulator) CalculateTaxResult() Result {
 		}
 		taxAmountLevels = append(taxAmountLevels, taxAmountLevel)
 		remainIncome = remainIncome - taxLevel.MaxAmount
+		bandEnd = bandEnd + taxLevel.MaxAmount
+		if marginalIndex < 0 && taxableIncome <= bandEnd {
+			marginalIndex, marginalBandEnd = index, bandEnd
+		}
 	}
 
-	totalTaxAmount = totalTaxAmount - t.WitholdingTax
-
-	return Result{
-		Amount:       totalTaxAmount,
-		LevelAmounts: taxAmountLevels,
+	result := Result{
+		Amount:        totalTaxAmount - t.WitholdingTax,
+		LevelAmounts:  taxAmountLevels,
+		TotalTax:      totalTaxAmount,
+		TaxableIncome: math.Max(taxableIncome, 0),
 	}
+	result.EffectiveRateOnGross = percentageOf(totalTaxAmount, t.TotalIncome)
+	result.EffectiveRateOnNet = percentageOf(totalTaxAmount, result.TaxableIncome)
+	if marginalIndex >= 0 {
+		result.MarginalLevel = t.Levels[marginalIndex]
+		result.MarginalRatePercentage = result.MarginalLevel.TaxRatePercentage
+		if marginalIndex < len(t.Levels)-1 {
+			result.HasNextLevel = true
+			result.NextLevelThreshold = marginalBandEnd + 1
+			result.IncomeToNextLevel = result.NextLevelThreshold - result.TaxableIncome
+		}
+	}
+	return result
+}
+
+// CalculateTotalTaxFor returns the tax on the given total income with the
+// calculator's allowances, before withholding tax is credited.
+func (t *Calulator) CalculateTotalTaxFor(totalIncome float64) float64 {
+	calculator := *t
+	calculator.TotalIncome = totalIncome
+	calculator.WitholdingTax = 0
+	return calculator.CalculateTaxResult().Amount
 }
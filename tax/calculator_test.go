@@ -149,3 +149,86 @@ func TestTaxCalculation(t *testing.T) {
 		}
 	})
 }
+
+func TestTaxRates(t *testing.T) {
+	t.Run("given total income 500000.0 should return effective rates and marginal level 150,001 - 500,000", func(t *testing.T) {
+
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		taxCalulator.WitholdingTax = 25000.00
+
+		got := taxCalulator.CalculateTaxResult()
+		if got.TotalTax != 29000.00 || got.TaxableIncome != 440000.00 {
+			t.Errorf("expect total tax 29000.0 and taxable income 440000.0 but got %v and %v", got.TotalTax, got.TaxableIncome)
+		}
+		if got.EffectiveRateOnGross != 5.8 || got.EffectiveRateOnNet != 6.59 {
+			t.Errorf("expect effective rates 5.8 and 6.59 but got %v and %v", got.EffectiveRateOnGross, got.EffectiveRateOnNet)
+		}
+		if got.MarginalLevel.Level != "150,001 - 500,000" || got.MarginalRatePercentage != 10 {
+			t.Errorf("expect marginal level 150,001 - 500,000 at 10%% but got %v", got.MarginalLevel)
+		}
+		if !got.HasNextLevel || got.NextLevelThreshold != 650001.00 || got.IncomeToNextLevel != 210001.00 {
+			t.Errorf("expect next level at 650001.0 after 210001.0 but got %v after %v", got.NextLevelThreshold, got.IncomeToNextLevel)
+		}
+	})
+
+	t.Run("given allowances above total income should return marginal level 0 - 150,000 and zero rates", func(t *testing.T) {
+
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 50000.00
+
+		got := taxCalulator.CalculateTaxResult()
+		if got.TaxableIncome != 0.00 || got.EffectiveRateOnGross != 0 || got.EffectiveRateOnNet != 0 {
+			t.Errorf("expect zero taxable income and rates but got %v", got)
+		}
+		if got.MarginalLevel.Level != "0 - 150,000" || got.IncomeToNextLevel != 150001.00 {
+			t.Errorf("expect marginal level 0 - 150,000 with 150001.0 to next level but got %v with %v", got.MarginalLevel, got.IncomeToNextLevel)
+		}
+	})
+
+	t.Run("given taxable income at the ends of a band should return the level the last baht is taxed in", func(t *testing.T) {
+
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		for taxableIncome, want := range map[float64]string{
+			150000.00: "0 - 150,000",
+			150000.50: "150,001 - 500,000",
+			650000.00: "150,001 - 500,000",
+			650000.50: "500,001 - 1,000,000",
+		} {
+			taxCalulator.TotalIncome = taxableIncome + DEFAULT_PERSONAL_ALLOWANCE
+
+			got := taxCalulator.CalculateTaxResult()
+			if got.MarginalLevel.Level != want {
+				t.Errorf("expect marginal level %v for taxable income %v but got %v", want, taxableIncome, got.MarginalLevel.Level)
+			}
+		}
+	})
+
+	t.Run("given taxable income 600000.0 should return the marginal level of the taxed band", func(t *testing.T) {
+
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 600000.00 + DEFAULT_PERSONAL_ALLOWANCE
+
+		got := taxCalulator.CalculateTaxResult()
+		if got.TotalTax != 45000.00 || got.LevelAmounts[2].Amount != 0.00 {
+			t.Errorf("expect total tax 45000.0 taxed only up to 10%% but got %v", got.LevelAmounts)
+		}
+		if got.MarginalLevel.Level != "150,001 - 500,000" || got.MarginalRatePercentage != 10 {
+			t.Errorf("expect marginal level 150,001 - 500,000 at 10%% but got %v", got.MarginalLevel)
+		}
+		if !got.HasNextLevel || got.NextLevelThreshold != 650001.00 || got.IncomeToNextLevel != 50001.00 {
+			t.Errorf("expect next level at 650001.0 after 50001.0 but got %v after %v", got.NextLevelThreshold, got.IncomeToNextLevel)
+		}
+	})
+
+	t.Run("given total income 5000000.0 should return top marginal level without next level", func(t *testing.T) {
+
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 5000000.00
+
+		got := taxCalulator.CalculateTaxResult()
+		if got.MarginalLevel.Level != "2,000,001 ขึ้นไป" || got.MarginalRatePercentage != 35 || got.HasNextLevel {
+			t.Errorf("expect top marginal level 35%% without next level but got %v", got)
+		}
+	})
+}
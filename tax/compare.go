@@ -0,0 +1,48 @@
+package tax
+
+// WithScenario returns the request with the scenario's overrides applied.
+// Allowances in the scenario replace base allowances of the same type and
+// other base allowances are kept.
+func (r CalculationRequest) WithScenario(scenario ScenarioRequest) CalculationRequest {
+	request := CalculationRequest{
+		TotalIncome:    r.TotalIncome,
+		WithHoldingTax: r.WithHoldingTax,
+		TaxYear:        r.TaxYear,
+	}
+	if scenario.TotalIncome != nil {
+		request.TotalIncome = *scenario.TotalIncome
+	}
+	if scenario.WithHoldingTax != nil {
+		request.WithHoldingTax = *scenario.WithHoldingTax
+	}
+
+	overridden := map[string]bool{}
+	for _, allowance := range scenario.Allowances {
+		overridden[allowance.Type] = true
+	}
+	for _, allowance := range r.Allowances {
+		if !overridden[allowance.Type] {
+			request.Allowances = append(request.Allowances, allowance)
+		}
+	}
+	request.Allowances = append(request.Allowances, scenario.Allowances...)
+	return request
+}
+
+// ClaimedAllowances returns the donation and k-receipt allowances the way the
+// calculator deducts them, capped at their maximums.
+func (t *Calulator) ClaimedAllowances() float64 {
+	return t.GetAllowanceDonation() + t.GetAllowanceKReceipt()
+}
+
+// CompareScenario computes the differences of a scenario against the base.
+// The saving per baht is the tax saved for each extra baht of allowance
+// deducted, and zero when the scenario deducts the same amount.
+func CompareScenario(base, scenario Result, baseClaimed, scenarioClaimed float64) (taxDelta, allowanceDelta, savingPerBaht float64) {
+	taxDelta = scenario.Amount - base.Amount
+	allowanceDelta = scenarioClaimed - baseClaimed
+	if allowanceDelta != 0 {
+		savingPerBaht = -taxDelta / allowanceDelta
+	}
+	return taxDelta, allowanceDelta, savingPerBaht
+}
@@ -0,0 +1,62 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareScenario(t *testing.T) {
+	t.Run("given scenario with donation override should replace base donation and keep other allowances", func(t *testing.T) {
+		income := 600000.0
+		base := CalculationRequest{
+			TotalIncome: 500000.0,
+			Allowances: []AllowanceRequest{
+				{Type: "donation", Amount: 10000.0},
+				{Type: "k-receipt", Amount: 20000.0},
+			},
+		}
+
+		got := base.WithScenario(ScenarioRequest{
+			Name:        "donate more",
+			TotalIncome: &income,
+			Allowances:  []AllowanceRequest{{Type: "donation", Amount: 50000.0}},
+		})
+
+		want := CalculationRequest{
+			TotalIncome: 600000.0,
+			Allowances: []AllowanceRequest{
+				{Type: "k-receipt", Amount: 20000.0},
+				{Type: "donation", Amount: 50000.0},
+			},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given donation and k-receipt above their caps should claim only the capped amounts", func(t *testing.T) {
+		calculator := NewTaxCalulator(60000.0, 50000.0)
+		calculator.AllowanceDonation = 500000.0
+		calculator.AllowanceKReceipt = 20000.0
+
+		if got := calculator.ClaimedAllowances(); got != 120000.0 {
+			t.Errorf("expect claimed allowances = %v but got %v", 120000.0, got)
+		}
+	})
+
+	t.Run("given scenario saving 5000.0 tax for 50000.0 allowance should return saving 0.1 per baht", func(t *testing.T) {
+		taxDelta, allowanceDelta, savingPerBaht := CompareScenario(Result{Amount: 29000.0}, Result{Amount: 24000.0}, 0.0, 50000.0)
+
+		if taxDelta != -5000.0 || allowanceDelta != 50000.0 || savingPerBaht != 0.1 {
+			t.Errorf("expect deltas -5000.0, 50000.0, 0.1 but got %v, %v, %v", taxDelta, allowanceDelta, savingPerBaht)
+		}
+	})
+
+	t.Run("given scenario with same allowances should return zero saving per baht", func(t *testing.T) {
+		_, _, savingPerBaht := CompareScenario(Result{Amount: 29000.0}, Result{Amount: 39000.0}, 0.0, 0.0)
+
+		if savingPerBaht != 0.0 {
+			t.Errorf("expect saving per baht = %v but got %v", 0.0, savingPerBaht)
+		}
+	})
+}
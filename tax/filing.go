@@ -0,0 +1,138 @@
+package tax
+
+import (
+	"encoding/xml"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+type FilingForm string
+
+const (
+	FormPND91 FilingForm = "PND91"
+	FormPND90 FilingForm = "PND90"
+)
+
+const FilingTaxYear = 2567
+
+// Field numbers of the tax computation section shared by ภ.ง.ด.91 and ภ.ง.ด.90.
+// ภ.ง.ด.90 additionally breaks field 1 down per income category as "1.1"-"1.8".
+const (
+	FieldIncome            = "1"
+	FieldExpenses          = "2"
+	FieldIncomeAfterCosts  = "3"
+	FieldAllowances        = "4"
+	FieldBeforeDonation    = "5"
+	FieldDonation          = "6"
+	FieldNetIncome         = "7"
+	FieldTax               = "8"
+	FieldWitholdingTax     = "9"
+	FieldTaxPayable        = "10"
+	FieldTaxOverpaid       = "11"
+	FieldAllowancePersonal = "C1"
+	FieldAllowanceKReceipt = "C2"
+	FieldAllowanceSpouse   = "C3"
+)
+
+var incomeCategoryPattern = regexp.MustCompile(`^40\(([1-8])\)$`)
+
+type FilingField struct {
+	Number string
+	Name   string
+	Amount float64
+}
+
+type Filing struct {
+	Form           FilingForm
+	TaxYear        int
+	IncomeCategory string
+	Fields         []FilingField
+}
+
+// NewFiling maps a calculation onto the numbered fields of the Revenue
+// Department form for the income category. Salary income under section 40(1)
+// files ภ.ง.ด.91, every other category files ภ.ง.ด.90. The filing is for the
+// tax year the calculator's rules belong to.
+func NewFiling(calculator Calulator, taxYear int, incomeCategory string) (Filing, error) {
+	if incomeCategory == "" {
+		incomeCategory = "40(1)"
+	}
+	match := incomeCategoryPattern.FindStringSubmatch(incomeCategory)
+	if match == nil {
+		return Filing{}, NewLocalizedError(MsgUnknownIncomeCategory, incomeCategory)
+	}
+
+	filing := Filing{Form: FormPND91, TaxYear: taxYear, IncomeCategory: incomeCategory}
+	if match[1] != "1" {
+		filing.Form = FormPND90
+	}
+
+	result := calculator.CalculateTaxResult()
+	income := calculator.TotalIncome
+	allowances := calculator.GetAllowancePersonal() + calculator.GetAllowanceSpouse() + calculator.GetAllowanceKReceipt()
+	beforeDonation := math.Max(income-allowances, 0)
+	donation := math.Min(calculator.GetAllowanceDonation(), beforeDonation)
+
+	add := func(number, name string, amount float64) {
+		filing.Fields = append(filing.Fields, FilingField{Number: number, Name: name, Amount: amount})
+	}
+	add(FieldIncome, "เงินได้พึงประเมิน", income)
+	if filing.Form == FormPND90 {
+		add(FieldIncome+"."+match[1], "เงินได้ตามมาตรา "+incomeCategory, income)
+	}
+	add(FieldExpenses, "หัก ค่าใช้จ่าย", 0)
+	add(FieldIncomeAfterCosts, "คงเหลือ", income)
+	add(FieldAllowances, "หัก ค่าลดหย่อน", allowances)
+	add(FieldBeforeDonation, "คงเหลือ", beforeDonation)
+	add(FieldDonation, "หัก เงินบริจาค", donation)
+	add(FieldNetIncome, "เงินได้สุทธิ", beforeDonation-donation)
+	add(FieldTax, "ภาษีเงินได้ที่คำนวณได้", result.TotalTax)
+	add(FieldWitholdingTax, "หัก ภาษีหัก ณ ที่จ่าย", calculator.WitholdingTax)
+	add(FieldTaxPayable, "ภาษีที่ชำระเพิ่มเติม", math.Max(result.Amount, 0))
+	add(FieldTaxOverpaid, "ภาษีที่ชำระไว้เกิน", math.Max(-result.Amount, 0))
+	add(FieldAllowancePersonal, "ค่าลดหย่อนผู้มีเงินได้", calculator.GetAllowancePersonal())
+	add(FieldAllowanceKReceipt, "ค่าลดหย่อนช้อปลดภาษี (k-receipt)", calculator.GetAllowanceKReceipt())
+	add(FieldAllowanceSpouse, "ค่าลดหย่อนคู่สมรส", calculator.GetAllowanceSpouse())
+
+	return filing, nil
+}
+
+func (f Filing) FieldMap() map[string]float64 {
+	fields := make(map[string]float64, len(f.Fields))
+	for _, field := range f.Fields {
+		fields[field.Number] = field.Amount
+	}
+	return fields
+}
+
+type filingXMLField struct {
+	Number string `xml:"number,attr"`
+	Name   string `xml:"name,attr"`
+	Amount string `xml:",chardata"`
+}
+
+type filingXML struct {
+	XMLName        xml.Name         `xml:"TaxForm"`
+	Form           FilingForm       `xml:"form,attr"`
+	TaxYear        int              `xml:"taxYear,attr"`
+	IncomeCategory string           `xml:"incomeCategory,attr"`
+	Fields         []filingXMLField `xml:"Field"`
+}
+
+// MarshalXMLDocument returns the filled form as a standalone XML document.
+func (f Filing) MarshalXMLDocument() ([]byte, error) {
+	document := filingXML{Form: f.Form, TaxYear: f.TaxYear, IncomeCategory: f.IncomeCategory}
+	for _, field := range f.Fields {
+		document.Fields = append(document.Fields, filingXMLField{
+			Number: field.Number,
+			Name:   field.Name,
+			Amount: strconv.FormatFloat(field.Amount, 'f', 2, 64),
+		})
+	}
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,88 @@
+package tax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFiling(t *testing.T) {
+	t.Run("given salary income 500000.0 wht 25000.0 donation 200000.0 should map to PND91 fields", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		taxCalulator.WitholdingTax = 25000.00
+		taxCalulator.AllowanceDonation = 200000.00
+
+		got, err := NewFiling(taxCalulator, FilingTaxYear, "")
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if got.Form != FormPND91 {
+			t.Errorf("expect form %v but got %v", FormPND91, got.Form)
+		}
+		want := map[string]float64{
+			"1":  500000.00,
+			"2":  0.00,
+			"3":  500000.00,
+			"4":  60000.00,
+			"5":  440000.00,
+			"6":  100000.00,
+			"7":  340000.00,
+			"8":  19000.00,
+			"9":  25000.00,
+			"10": 0.00,
+			"11": 6000.00,
+			"C1": 60000.00,
+			"C2": 0.00,
+			"C3": 0.00,
+		}
+		if !reflect.DeepEqual(want, got.FieldMap()) {
+			t.Errorf("expected %v but got %v", want, got.FieldMap())
+		}
+	})
+
+	t.Run("given income category 40(8) should map to PND90 with category field", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+
+		got, err := NewFiling(taxCalulator, FilingTaxYear, "40(8)")
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if got.Form != FormPND90 {
+			t.Errorf("expect form %v but got %v", FormPND90, got.Form)
+		}
+		if amount, ok := got.FieldMap()["1.8"]; !ok || amount != 500000.00 {
+			t.Errorf("expect field 1.8 = %v but got %v", 500000.00, amount)
+		}
+	})
+
+	t.Run("given unknown income category should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		_, err := NewFiling(taxCalulator, FilingTaxYear, "40(9)")
+		if err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+
+	t.Run("given filing should marshal xml document with numbered fields", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		filing, _ := NewFiling(taxCalulator, FilingTaxYear, "40(1)")
+
+		got, err := filing.MarshalXMLDocument()
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		for _, want := range []string{
+			`<TaxForm form="PND91" taxYear="2567" incomeCategory="40(1)">`,
+			`<Field number="1" name="เงินได้พึงประเมิน">500000.00</Field>`,
+			`<Field number="8" name="ภาษีเงินได้ที่คำนวณได้">29000.00</Field>`,
+		} {
+			if !strings.Contains(string(got), want) {
+				t.Errorf("expected xml to contain %v but got %s", want, got)
+			}
+		}
+	})
+}
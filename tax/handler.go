@@ -1,23 +1,81 @@
 package tax
 
 import (
+	"context"
 	"encoding/csv"
-	"errors"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
 	"net/http"
 	"strconv"
 
+	"github.com/apirom9/assessment-tax/logging"
 	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
 )
 
+// Store keeps the allowance settings by type and the rule sets by tax year.
+// The update methods store the value only if the current version is version
+// and report whether they did; either way they return the stored value.
 type Store interface {
-	UpdateDefaultPersonalDeduction(value float64) error
-	GetDefaultPersonalDeduction() (float64, error)
-	UpdateMaxKReceipt(value float64) error
-	GetMaxKReceipt() (float64, error)
+	GetAllowanceSetting(ctx context.Context, allowanceType string) (Setting, error)
+	UpdateAllowanceSetting(ctx context.Context, allowanceType string, value float64, version int) (Setting, bool, error)
+	// GetRuleSet returns ErrRuleSetNotFound if the year has no rule set.
+	GetRuleSet(ctx context.Context, taxYear int) (RuleSet, error)
+	// UpdateRuleSet returns ErrRuleSetNotFound if the year has no rule set.
+	UpdateRuleSet(ctx context.Context, rules RuleSet, version int) (RuleSet, bool, error)
+	// CreateRuleSet stores the rule set if its year has none and reports
+	// whether it did; either way it returns the stored rule set.
+	CreateRuleSet(ctx context.Context, rules RuleSet) (RuleSet, bool, error)
+	// ApplyRules stores the rule set and the allowance settings by type in
+	// one transaction if their current versions are versions, incrementing
+	// the versions of the changed ones, and reports whether it did.
+	ApplyRules(ctx context.Context, rules RuleSet, allowances map[string]float64, versions RulesVersions) (bool, error)
 }
 
+// RowQuota limits how many CSV rows a client may calculate per day. Rows
+// consumed but not calculated are refunded.
+type RowQuota interface {
+	ConsumeRows(c echo.Context, rows int) (bool, error)
+	RefundRows(c echo.Context, rows int) error
+}
+
+// CSV row outcomes reported to the metrics recorder.
+const (
+	CsvRowsProcessed = "processed"
+	CsvRowsInvalid   = "invalid"
+	CsvRowsQuota     = "quota"
+)
+
+// MetricsRecorder receives the outcome of calculations for monitoring.
+type MetricsRecorder interface {
+	ObserveCalculation(result Result)
+	ObserveCsvRows(outcome string, rows int)
+}
+
+// Handler adapts TaxService to echo.
 type Handler struct {
-	Store Store
+	Service    *TaxService
+	ReportFont []byte
+	CsvQuota   RowQuota
+	Logger     *slog.Logger
+}
+
+func (h *Handler) logger(c echo.Context) *slog.Logger {
+	return logging.FromContext(c, h.Logger)
+}
+
+func (h *Handler) internalError(c echo.Context, err error) error {
+	h.logger(c).ErrorContext(c.Request().Context(), "request failed", slog.String("error", err.Error()))
+	return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+}
+
+func (h *Handler) observeCsvRows(outcome string, rows int) {
+	if h.Service.Metrics != nil && rows > 0 {
+		h.Service.Metrics.ObserveCsvRows(outcome, rows)
+	}
 }
 
 type AllowanceRequest struct {
@@ -29,6 +87,126 @@ type CalculationRequest struct {
 	TotalIncome    float64            `json:"totalIncome" example:"500000.0"`
 	WithHoldingTax float64            `json:"wht" example:"0.0"`
 	Allowances     []AllowanceRequest `json:"allowances"`
+	TaxYear        int                `json:"taxYear,omitempty" example:"2567"`
+}
+
+type FilingRequest struct {
+	CalculationRequest
+	IncomeCategory string `json:"incomeCategory" example:"40(1)"`
+}
+
+type FilingResponse struct {
+	Form           FilingForm         `json:"form" example:"PND91"`
+	TaxYear        int                `json:"taxYear" example:"2567"`
+	IncomeCategory string             `json:"incomeCategory" example:"40(1)"`
+	Fields         map[string]float64 `json:"fields"`
+}
+
+type InverseCalculationRequest struct {
+	TargetNetIncome *float64           `json:"targetNetIncome,omitempty" example:"400000.0"`
+	TargetTax       *float64           `json:"targetTax,omitempty" example:"29000.0"`
+	WithHoldingTax  float64            `json:"wht" example:"0.0"`
+	Allowances      []AllowanceRequest `json:"allowances"`
+	TaxYear         int                `json:"taxYear,omitempty" example:"2567"`
+}
+
+type InverseCalculationResponse struct {
+	TotalIncome float64  `json:"totalIncome" example:"500000.0"`
+	NetIncome   float64  `json:"netIncome" example:"471000.0"`
+	TotalTax    float64  `json:"totalTax" example:"29000.0"`
+	Calculation Response `json:"calculation"`
+}
+
+type WithholdingScheduleRequest struct {
+	MonthlyIncomes []float64          `json:"monthlyIncomes" example:"50000.0,50000.0,60000.0"`
+	Allowances     []AllowanceRequest `json:"allowances"`
+	TaxYear        int                `json:"taxYear,omitempty" example:"2567"`
+}
+
+type WithholdingMonthResponse struct {
+	Month                 int     `json:"month" example:"1"`
+	Income                float64 `json:"income" example:"50000.0"`
+	EstimatedAnnualIncome float64 `json:"estimatedAnnualIncome" example:"600000.0"`
+	EstimatedAnnualTax    float64 `json:"estimatedAnnualTax" example:"39000.0"`
+	WithHoldingTax        float64 `json:"wht" example:"3250.0"`
+}
+
+type WithholdingScheduleResponse struct {
+	AnnualIncome        float64                    `json:"annualIncome" example:"600000.0"`
+	AnnualTax           float64                    `json:"annualTax" example:"39000.0"`
+	TotalWithHoldingTax float64                    `json:"totalWht" example:"39000.0"`
+	Months              []WithholdingMonthResponse `json:"months"`
+}
+
+type ScenarioRequest struct {
+	Name           string             `json:"name" example:"k-receipt 50,000"`
+	TotalIncome    *float64           `json:"totalIncome,omitempty" example:"500000.0"`
+	WithHoldingTax *float64           `json:"wht,omitempty" example:"0.0"`
+	Allowances     []AllowanceRequest `json:"allowances"`
+}
+
+type CompareRequest struct {
+	Base      CalculationRequest `json:"base"`
+	Scenarios []ScenarioRequest  `json:"scenarios"`
+}
+
+type ScenarioResponse struct {
+	Name             string   `json:"name" example:"k-receipt 50,000"`
+	Calculation      Response `json:"calculation"`
+	TaxDelta         float64  `json:"taxDelta" example:"-5000.0"`
+	AllowanceDelta   float64  `json:"allowanceDelta" example:"50000.0"`
+	TaxSavingPerBaht float64  `json:"taxSavingPerBaht" example:"0.1"`
+}
+
+type CompareResponse struct {
+	Base      Response           `json:"base"`
+	Scenarios []ScenarioResponse `json:"scenarios"`
+}
+
+type AdviceRequest struct {
+	TotalIncome    float64            `json:"totalIncome" example:"500000.0"`
+	WithHoldingTax float64            `json:"wht" example:"0.0"`
+	Budget         float64            `json:"budget" example:"100000.0"`
+	Allowances     []AllowanceRequest `json:"allowances"`
+	TaxYear        int                `json:"taxYear,omitempty" example:"2567"`
+}
+
+type AllowanceAdviceResponse struct {
+	Type        string  `json:"allowanceType" example:"k-receipt"`
+	Claimed     float64 `json:"claimed" example:"0.0"`
+	Recommended float64 `json:"recommended" example:"50000.0"`
+	Cap         float64 `json:"cap" example:"50000.0"`
+	Headroom    float64 `json:"headroom" example:"0.0"`
+}
+
+type AdviceResponse struct {
+	RecommendedSpend float64                   `json:"recommendedSpend" example:"100000.0"`
+	TaxSaving        float64                   `json:"taxSaving" example:"10000.0"`
+	Allowances       []AllowanceAdviceResponse `json:"allowances"`
+	Calculation      Response                  `json:"calculation"`
+}
+
+type HouseholdRequest struct {
+	Taxpayer CalculationRequest `json:"taxpayer"`
+	Spouse   CalculationRequest `json:"spouse"`
+}
+
+type SeparateFilingResponse struct {
+	Taxpayer Response `json:"taxpayer"`
+	Spouse   Response `json:"spouse"`
+	Tax      float64  `json:"tax" example:"29000.0"`
+}
+
+type JointFilingResponse struct {
+	Calculation Response `json:"calculation"`
+	Tax         float64  `json:"tax" example:"29000.0"`
+}
+
+type HouseholdResponse struct {
+	Separate       SeparateFilingResponse `json:"separate"`
+	Joint          JointFilingResponse    `json:"joint"`
+	Recommendation FilingMode             `json:"recommendation" example:"separate"`
+	TaxSaving      float64                `json:"taxSaving" example:"0.0"`
 }
 
 type TaxLevelResponse struct {
@@ -36,16 +214,28 @@ type TaxLevelResponse struct {
 	TaxAmount float64 `json:"tax" example:"0.0"`
 }
 
+type TaxRateResponse struct {
+	TaxableIncome      float64  `json:"taxableIncome" example:"440000.0"`
+	EffectiveRate      float64  `json:"effectiveRate" example:"5.8"`
+	EffectiveRateOnNet float64  `json:"effectiveRateOnNetIncome" example:"6.59"`
+	MarginalLevel      string   `json:"marginalLevel" example:"150,001 - 500,000"`
+	MarginalRate       float64  `json:"marginalRate" example:"10.0"`
+	NextLevelThreshold *float64 `json:"nextLevelThreshold,omitempty" example:"650001.0"`
+	IncomeToNextLevel  *float64 `json:"incomeToNextLevel,omitempty" example:"210001.0"`
+}
+
 type Response struct {
 	Tax               float64            `json:"tax,omitempty" example:"29000.0"`
 	TaxRefund         float64            `json:"taxRefund,omitempty" example:"29000.0"`
 	TaxLevelResponses []TaxLevelResponse `json:"taxLevel"`
+	Rates             TaxRateResponse    `json:"rates"`
 }
 
 type ResponseTaxResultForCSV struct {
-	TotalIncome float64 `json:"totalIncome" example:"29000.0"`
-	Tax         float64 `json:"tax,omitempty" example:"29000.0"`
-	TaxRefund   float64 `json:"taxRefund,omitempty" example:"29000.0"`
+	TotalIncome float64         `json:"totalIncome" example:"29000.0"`
+	Tax         float64         `json:"tax,omitempty" example:"29000.0"`
+	TaxRefund   float64         `json:"taxRefund,omitempty" example:"29000.0"`
+	Rates       TaxRateResponse `json:"rates"`
 }
 
 type ResponseForCSV struct {
@@ -72,64 +262,173 @@ type UpdateKReceiptsResponse struct {
 	Amount float64 `json:"kReceipt" example:"29000.0"`
 }
 
-func (h *Handler) CreateTaxCalculatorFromRequest(request CalculationRequest) (Calulator, error) {
+type DeductionsResponse struct {
+	PersonalDeduction     float64 `json:"personalDeduction" example:"60000.0"`
+	PersonalDeductionETag string  `json:"personalDeductionEtag" example:"\"personal_default.1\""`
+	KReceipt              float64 `json:"kReceipt" example:"50000.0"`
+	KReceiptETag          string  `json:"kReceiptEtag" example:"\"kreceipt_max.1\""`
+}
 
-	defaultPersonalAllowance, err := h.Store.GetDefaultPersonalDeduction()
-	if err != nil {
-		return Calulator{}, err
-	}
-	maxKreceipt, err := h.Store.GetMaxKReceipt()
-	if err != nil {
-		return Calulator{}, err
+type RuleSetRequest struct {
+	PersonalDeductionMin float64 `json:"personalDeductionMin" example:"10000.0"`
+	PersonalDeductionMax float64 `json:"personalDeductionMax" example:"100000.0"`
+	KReceiptMin          float64 `json:"kReceiptMin" example:"0.0"`
+	KReceiptMax          float64 `json:"kReceiptMax" example:"100000.0"`
+	DonationMax          float64 `json:"donationMax" example:"100000.0"`
+}
+
+type RuleSetResponse struct {
+	TaxYear              int     `json:"taxYear" example:"2567"`
+	PersonalDeductionMin float64 `json:"personalDeductionMin" example:"10000.0"`
+	PersonalDeductionMax float64 `json:"personalDeductionMax" example:"100000.0"`
+	KReceiptMin          float64 `json:"kReceiptMin" example:"0.0"`
+	KReceiptMax          float64 `json:"kReceiptMax" example:"100000.0"`
+	DonationMax          float64 `json:"donationMax" example:"100000.0"`
+}
+
+func NewRuleSetResponse(rules RuleSet) RuleSetResponse {
+	return RuleSetResponse{
+		TaxYear:              rules.TaxYear,
+		PersonalDeductionMin: rules.PersonalDeductionMin,
+		PersonalDeductionMax: rules.PersonalDeductionMax,
+		KReceiptMin:          rules.KReceiptMin,
+		KReceiptMax:          rules.KReceiptMax,
+		DonationMax:          rules.DonationMax,
 	}
-	calculator := NewTaxCalulator(defaultPersonalAllowance, maxKreceipt)
+}
 
-	calculator.TotalIncome = request.TotalIncome
-	calculator.WitholdingTax = request.WithHoldingTax
-	for _, allowance := range request.Allowances {
-		if allowance.Type == "donation" {
-			calculator.AllowanceDonation = allowance.Amount
-		} else if allowance.Type == "k-receipt" {
-			calculator.AllowanceKReceipt = allowance.Amount
-		} else {
-			return calculator, errors.New("Unknown allowance type: " + allowance.Type)
-		}
+type UpdateAllowanceSettingRequest struct {
+	Amount float64 `json:"amount" example:"70000.0"`
+}
+
+// AllowanceSettingResponse is a setting of the allowance table. Its amount
+// must be more than min and within max.
+type AllowanceSettingResponse struct {
+	Type        string  `json:"type" example:"personal_default"`
+	Amount      float64 `json:"amount" example:"60000.0"`
+	Min         float64 `json:"min" example:"10000.0"`
+	Max         float64 `json:"max" example:"100000.0"`
+	Unit        string  `json:"unit" example:"THB"`
+	Description string  `json:"description" example:"Personal deduction every taxpayer receives"`
+	ETag        string  `json:"etag" example:"\"personal_default.1\""`
+}
+
+type AllowanceSettingsResponse struct {
+	Allowances []AllowanceSettingResponse `json:"allowances"`
+}
+
+func NewAllowanceSettingResponse(setting AllowanceSetting, locale Locale) AllowanceSettingResponse {
+	return AllowanceSettingResponse{
+		Type:        setting.Type,
+		Amount:      setting.Amount,
+		Min:         setting.MinAmount,
+		Max:         setting.MaxAmount,
+		Unit:        setting.Unit,
+		Description: locale.Message(setting.Description),
+		ETag:        SettingETag(setting.Type, setting.Version),
 	}
+}
+
+type RuleChangeResponse struct {
+	Field    string  `json:"field" example:"allowances.personal_default"`
+	Current  float64 `json:"current" example:"60000.0"`
+	Imported float64 `json:"imported" example:"70000.0"`
+}
 
-	return calculator, nil
+// RulesImportResponse lists the values of the imported document that differ
+// from the current ones, which were stored unless it was a dry run.
+type RulesImportResponse struct {
+	TaxYear int                  `json:"taxYear" example:"2567"`
+	DryRun  bool                 `json:"dryRun" example:"false"`
+	Applied bool                 `json:"applied" example:"true"`
+	Changes []RuleChangeResponse `json:"changes"`
 }
 
-func (h *Handler) CreateTaxCalculatorFromCsvRecord(record []string) (Calulator, error) {
+// RuleSetConflictResponse is returned with 412 when If-Match is not the ETag
+// of the current rule set, which is returned in the ETag header.
+type RuleSetConflictResponse struct {
+	Message string          `json:"message"`
+	Current RuleSetResponse `json:"current"`
+}
 
-	defaultPersonalAllowance, err := h.Store.GetDefaultPersonalDeduction()
-	if err != nil {
-		return Calulator{}, err
+// SettingConflictResponse is returned with 412 when If-Match is not the ETag
+// of the current setting, which is returned in the ETag header.
+type SettingConflictResponse struct {
+	Message string  `json:"message"`
+	Current float64 `json:"current" example:"60000.0"`
+}
+
+func NewTaxRateResponse(result Result, locale Locale) TaxRateResponse {
+	response := TaxRateResponse{
+		TaxableIncome:      result.TaxableIncome,
+		EffectiveRate:      result.EffectiveRateOnGross,
+		EffectiveRateOnNet: result.EffectiveRateOnNet,
+		MarginalLevel:      locale.LevelLabel(result.MarginalLevel),
+		MarginalRate:       result.MarginalRatePercentage,
 	}
-	maxKreceipt, err := h.Store.GetMaxKReceipt()
-	if err != nil {
-		return Calulator{}, err
+	if result.HasNextLevel {
+		nextLevelThreshold := result.NextLevelThreshold
+		incomeToNextLevel := result.IncomeToNextLevel
+		response.NextLevelThreshold = &nextLevelThreshold
+		response.IncomeToNextLevel = &incomeToNextLevel
 	}
-	calculator := NewTaxCalulator(defaultPersonalAllowance, maxKreceipt)
+	return response
+}
 
-	totalIncome, err := strconv.ParseFloat(record[0], 64)
-	if err != nil {
-		return calculator, err
+func NewResponse(calculator Calulator, locale Locale) Response {
+	return NewResponseFromResult(calculator, calculator.CalculateTaxResult(), locale)
+}
+
+func NewResponseFromResult(calculator Calulator, result Result, locale Locale) Response {
+	var taxLevelResponses []TaxLevelResponse
+	for index, level := range result.LevelAmounts {
+		taxLevelResponses = append(taxLevelResponses, TaxLevelResponse{
+			Level:     locale.LevelLabel(calculator.Levels[index]),
+			TaxAmount: level.Amount,
+		})
 	}
-	calculator.TotalIncome = totalIncome
 
-	wht, err := strconv.ParseFloat(record[1], 64)
-	if err != nil {
-		return calculator, err
+	response := Response{
+		TaxLevelResponses: taxLevelResponses,
+		Rates:             NewTaxRateResponse(result, locale),
 	}
-	calculator.WitholdingTax = wht
+	if result.Amount < 0 {
+		response.TaxRefund = -result.Amount
+	} else {
+		response.Tax = result.Amount
+	}
+	return response
+}
+
+var csvColumns = []string{"totalIncome", "wht", "donation"}
 
-	donation, err := strconv.ParseFloat(record[2], 64)
+func parseCsvValue(record []string, column, row int) (float64, error) {
+	value, err := strconv.ParseFloat(record[column], 64)
 	if err != nil {
-		return calculator, err
+		return 0, NewLocalizedError(MsgInvalidCsvValue, csvColumns[column], record[column], row)
 	}
-	calculator.AllowanceDonation = donation
+	return value, nil
+}
 
-	return calculator, nil
+// CalculationRequestFromCsvRecord reads a row of the uploaded CSV, whose
+// columns are total income, withholding tax and donation.
+func CalculationRequestFromCsvRecord(record []string, row int) (CalculationRequest, error) {
+	if len(record) != len(csvColumns) {
+		return CalculationRequest{}, NewLocalizedError(MsgInvalidCsvRecord, row, len(csvColumns))
+	}
+	var values [3]float64
+	for column := range csvColumns {
+		value, err := parseCsvValue(record, column, row)
+		if err != nil {
+			return CalculationRequest{}, err
+		}
+		values[column] = value
+	}
+	return CalculationRequest{
+		TotalIncome:    values[0],
+		WithHoldingTax: values[1],
+		Allowances:     []AllowanceRequest{{Type: AllowanceTypeDonation, Amount: values[2]}},
+	}, nil
 }
 
 // CalculateTax
@@ -144,37 +443,382 @@ func (h *Handler) CreateTaxCalculatorFromCsvRecord(record []string) (Calulator,
 //	@Failure		500	{object}	Err
 //	@Failure		400	{object}	Err
 //	@Param 			CalculationRequest body CalculationRequest true "Body for calculation request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
 func (h *Handler) CalculateTax(c echo.Context) error {
 
+	locale := LocaleFromContext(c)
 	var request CalculationRequest
 	if err := c.Bind(&request); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
 
-	calculator, err := h.CreateTaxCalculatorFromRequest(request)
+	calculation, err := h.Service.Calculate(c.Request().Context(), request)
 	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	h.logger(c).DebugContext(c.Request().Context(), "tax calculated",
+		slog.Float64("total_income", calculation.Calculator.TotalIncome),
+		slog.Float64("tax", calculation.Result.Amount),
+	)
+	return c.JSON(http.StatusOK, NewResponseFromResult(calculation.Calculator, calculation.Result, locale))
+}
+
+// CalculateTaxInverse
+//
+//	@Summary		Solve total income for a target net income or tax
+//	@Description	Find the total income that gives the target net income after tax, or the target tax before withholding tax, with the same allowances
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	InverseCalculationResponse
+//	@Router			/tax/calculations/inverse [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			InverseCalculationRequest body InverseCalculationRequest true "Body for inverse calculation request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) CalculateTaxInverse(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	var request InverseCalculationRequest
+	if err := c.Bind(&request); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
 
-	result := calculator.CalculateTaxResult()
-	var taxLevelResponses []TaxLevelResponse
-	for _, level := range result.LevelAmounts {
-		taxLevelResponses = append(taxLevelResponses, TaxLevelResponse{
-			Level:     level.Level,
-			TaxAmount: level.Amount,
+	var target InverseTarget
+	var amount float64
+	if request.TargetNetIncome != nil && request.TargetTax == nil {
+		target, amount = InverseTargetNetIncome, *request.TargetNetIncome
+	} else if request.TargetTax != nil && request.TargetNetIncome == nil {
+		target, amount = InverseTargetTax, *request.TargetTax
+	} else {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.Message(MsgInverseTargetRequired)})
+	}
+	if err := ValidateInverseTarget(amount); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), CalculationRequest{
+		WithHoldingTax: request.WithHoldingTax,
+		Allowances:     request.Allowances,
+		TaxYear:        request.TaxYear,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	totalIncome, err := calculator.SolveTotalIncome(target, amount)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	calculator.TotalIncome = totalIncome
+	totalTax := calculator.CalculateTaxResult().Amount + calculator.WitholdingTax
+
+	return c.JSON(http.StatusOK, InverseCalculationResponse{
+		TotalIncome: totalIncome,
+		NetIncome:   totalIncome - totalTax,
+		TotalTax:    totalTax,
+		Calculation: NewResponse(calculator, locale),
+	})
+}
+
+// CalculateWithholdingSchedule
+//
+//	@Summary		Calculate monthly withholding tax schedule
+//	@Description	Calculate monthly withholding tax by annualizing salary and re-estimating each month, so the schedule adds up to the annual tax
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	WithholdingScheduleResponse
+//	@Router			/tax/withholding/schedule [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			WithholdingScheduleRequest body WithholdingScheduleRequest true "Body for withholding schedule request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) CalculateWithholdingSchedule(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	var request WithholdingScheduleRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), CalculationRequest{
+		Allowances: request.Allowances,
+		TaxYear:    request.TaxYear,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	schedule, err := calculator.CalculateWithholdingSchedule(request.MonthlyIncomes)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	var response WithholdingScheduleResponse
+	for _, month := range schedule {
+		response.AnnualIncome = response.AnnualIncome + month.Income
+		response.TotalWithHoldingTax = roundSatang(response.TotalWithHoldingTax + month.WitholdingTax)
+		response.Months = append(response.Months, WithholdingMonthResponse{
+			Month:                 month.Month,
+			Income:                month.Income,
+			EstimatedAnnualIncome: month.EstimatedAnnualIncome,
+			EstimatedAnnualTax:    month.EstimatedAnnualTax,
+			WithHoldingTax:        month.WitholdingTax,
 		})
 	}
+	lastMonth := schedule[len(schedule)-1]
+	response.AnnualIncome = response.AnnualIncome + lastMonth.Income*float64(MonthsPerYear-lastMonth.Month)
+	response.AnnualTax = calculator.CalculateTotalTaxFor(response.AnnualIncome)
 
-	response := Response{TaxLevelResponses: taxLevelResponses}
-	if result.Amount < 0 {
-		response.TaxRefund = -result.Amount
-	} else {
-		response.Tax = result.Amount
+	return c.JSON(http.StatusOK, response)
+}
+
+// CompareTaxScenarios
+//
+//	@Summary		Compare what-if scenarios
+//	@Description	Calculate a base request and named scenario overrides, returning each result with deltas against the base and the tax saving per baht of extra allowance
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	CompareResponse
+//	@Router			/tax/calculations/compare [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			CompareRequest body CompareRequest true "Body for compare request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) CompareTaxScenarios(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	var request CompareRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if len(request.Scenarios) == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.Message(MsgScenariosRequired)})
+	}
+
+	baseCalculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), request.Base)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	baseResult := baseCalculator.CalculateTaxResult()
+
+	response := CompareResponse{Base: NewResponse(baseCalculator, locale)}
+	for _, scenario := range request.Scenarios {
+		scenarioRequest := request.Base.WithScenario(scenario)
+		calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), scenarioRequest)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: locale.Message(MsgScenarioInvalid, scenario.Name, locale.ErrorMessage(err))})
+		}
+		taxDelta, allowanceDelta, savingPerBaht := CompareScenario(
+			baseResult, calculator.CalculateTaxResult(),
+			baseCalculator.ClaimedAllowances(), calculator.ClaimedAllowances(),
+		)
+		response.Scenarios = append(response.Scenarios, ScenarioResponse{
+			Name:             scenario.Name,
+			Calculation:      NewResponse(calculator, locale),
+			TaxDelta:         taxDelta,
+			AllowanceDelta:   allowanceDelta,
+			TaxSavingPerBaht: savingPerBaht,
+		})
 	}
 
 	return c.JSON(http.StatusOK, response)
 }
 
+// AdviseAllowances
+//
+//	@Summary		Recommend allowance spending
+//	@Description	Recommend how to spend a budget on k-receipt and donation to minimise tax within each cap, with the resulting tax and the headroom left per allowance
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	AdviceResponse
+//	@Router			/tax/advice [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			AdviceRequest body AdviceRequest true "Body for advice request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) AdviseAllowances(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	var request AdviceRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), CalculationRequest{
+		TotalIncome:    request.TotalIncome,
+		WithHoldingTax: request.WithHoldingTax,
+		Allowances:     request.Allowances,
+		TaxYear:        request.TaxYear,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	advice, advised, err := calculator.AdviseAllowances(request.Budget)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	response := AdviceResponse{
+		TaxSaving:   calculator.CalculateTaxResult().Amount - advised.CalculateTaxResult().Amount,
+		Calculation: NewResponse(advised, locale),
+	}
+	for _, allowance := range advice {
+		response.RecommendedSpend = response.RecommendedSpend + allowance.Recommended
+		response.Allowances = append(response.Allowances, AllowanceAdviceResponse{
+			Type:        allowance.Type,
+			Claimed:     allowance.Claimed,
+			Recommended: allowance.Recommended,
+			Cap:         allowance.Cap,
+			Headroom:    allowance.Headroom,
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// CalculateHouseholdTax
+//
+//	@Summary		Calculate tax for a married couple
+//	@Description	Calculate separate and joint filing for a taxpayer and spouse and recommend the option with less tax. Tax is after withholding tax, negative for a refund
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	HouseholdResponse
+//	@Router			/tax/calculations/household [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			HouseholdRequest body HouseholdRequest true "Body for household calculation request"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) CalculateHouseholdTax(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	var request HouseholdRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	taxpayer, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), request.Taxpayer)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	spouse, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), request.Spouse)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	household := CalculateHousehold(taxpayer, spouse)
+	response := HouseholdResponse{
+		Separate: SeparateFilingResponse{
+			Taxpayer: NewResponse(household.Taxpayer, locale),
+			Spouse:   NewResponse(household.Spouse, locale),
+			Tax:      household.SeparateTax,
+		},
+		Joint: JointFilingResponse{
+			Calculation: NewResponse(household.Joint, locale),
+			Tax:         household.JointTax,
+		},
+		Recommendation: household.Recommended,
+		TaxSaving:      math.Abs(household.SeparateTax - household.JointTax),
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// CalculateTaxReport
+//
+//	@Summary		Calculate Tax and download PDF summary
+//	@Description	Calculate Tax and download PDF summary
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		application/pdf
+//	@Success		200	{file}	binary
+//	@Router			/tax/calculations/report [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			CalculationRequest body CalculationRequest true "Body for calculation request"
+//	@Param 			Accept-Language header string false "Report language (th or en), default th"
+func (h *Handler) CalculateTaxReport(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	if locale.Language == "" {
+		locale = GetLocale(LanguageThai)
+	}
+	var request CalculationRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), request)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	document, err := NewReport(calculator, locale).RenderPDF(h.ReportFont, locale)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="tax-summary.pdf"`)
+	return c.Blob(http.StatusOK, "application/pdf", document)
+}
+
+// ExportFiling
+//
+//	@Summary		Export calculation as PND 91/90 form fields
+//	@Description	Map a calculation onto the numbered fields of the ภ.ง.ด.91 (salary) or ภ.ง.ด.90 form, as JSON or a filled XML document
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json,xml
+//	@Success		200	{object}	FilingResponse
+//	@Router			/tax/filings/export [post]
+//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Err
+//	@Param 			FilingRequest body FilingRequest true "Body for filing export request"
+//	@Param 			format query string false "Export format (json or xml), default json"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) ExportFiling(c echo.Context) error {
+
+	locale := LocaleFromContext(c)
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "xml" {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.Message(MsgUnknownFilingFormat, format)})
+	}
+	var request FilingRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	calculator, err := h.Service.CreateTaxCalculatorFromRequest(c.Request().Context(), request.CalculationRequest)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	filing, err := NewFiling(calculator, request.CalculationYear(), request.IncomeCategory)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+
+	if format == "xml" {
+		document, err := filing.MarshalXMLDocument()
+		if err != nil {
+			return h.internalError(c, err)
+		}
+		return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, document)
+	}
+	return c.JSON(http.StatusOK, FilingResponse{
+		Form:           filing.Form,
+		TaxYear:        filing.TaxYear,
+		IncomeCategory: filing.IncomeCategory,
+		Fields:         filing.FieldMap(),
+	})
+}
+
 // CalculateTaxCsv
 //
 //	@Summary		Calculate Tax for upload CSV file
@@ -184,10 +828,16 @@ func (h *Handler) CalculateTax(c echo.Context) error {
 //	@Produce		json
 //	@Success		200	{object}	Response
 //	@Router			/tax/calculations/upload-csv [post]
+//	@Failure		409	{object}	Err
+//	@Failure		422	{object}	Err
 //	@Failure		500	{object}	Err
+//	@Failure		429	{object}	Err
 //	@Failure		400	{object}	Err
 //	@Param 			taxes.csv formData file true "Uploaded CSV for tax calculation"
+//	@Param 			Idempotency-Key header string false "Replay the first response when the request is retried with the same key"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
 func (h *Handler) CalculateTaxCsv(c echo.Context) error {
+	locale := LocaleFromContext(c)
 	file, err := c.FormFile("taxes.csv")
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
@@ -202,96 +852,508 @@ func (h *Handler) CalculateTaxCsv(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
 	}
-	var response ResponseForCSV
+	var requests []CalculationRequest
 	for index, record := range content {
 		if index == 0 {
 			// TODO check column names
 			continue
 		}
-		calculator, err := h.CreateTaxCalculatorFromCsvRecord(record)
+		request, err := CalculationRequestFromCsvRecord(record, index)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+			logging.AddFields(c, slog.Int("csv_rows", index-1), slog.Int("csv_rows_rejected", 1))
+			h.observeCsvRows(CsvRowsInvalid, 1)
+			return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+		}
+		requests = append(requests, request)
+	}
+	// Only rows that are calculated count against the quota, so a file
+	// rejected for an invalid row costs nothing.
+	if h.CsvQuota != nil && len(requests) > 0 {
+		ok, err := h.CsvQuota.ConsumeRows(c, len(requests))
+		if err != nil {
+			return h.internalError(c, err)
+		}
+		if !ok {
+			logging.AddFields(c, slog.Int("csv_rows_rejected", len(requests)))
+			h.observeCsvRows(CsvRowsQuota, len(requests))
+			return c.JSON(http.StatusTooManyRequests, Err{Message: locale.Message(MsgCsvQuotaExceeded)})
 		}
-		responseTaxResultForCSV := ResponseTaxResultForCSV{TotalIncome: calculator.TotalIncome}
-		taxResult := calculator.CalculateTaxResult()
-		if taxResult.Amount < 0 {
-			responseTaxResultForCSV.TaxRefund = -taxResult.Amount
+	}
+	calculations, err := h.Service.CalculateBatch(c.Request().Context(), requests)
+	if err != nil {
+		if h.CsvQuota != nil {
+			if refundErr := h.CsvQuota.RefundRows(c, len(requests)); refundErr != nil {
+				return h.internalError(c, refundErr)
+			}
+		}
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	var response ResponseForCSV
+	for _, calculation := range calculations {
+		responseTaxResultForCSV := ResponseTaxResultForCSV{
+			TotalIncome: calculation.Calculator.TotalIncome,
+			Rates:       NewTaxRateResponse(calculation.Result, locale),
+		}
+		if calculation.Result.Amount < 0 {
+			responseTaxResultForCSV.TaxRefund = -calculation.Result.Amount
 		} else {
-			responseTaxResultForCSV.Tax = taxResult.Amount
+			responseTaxResultForCSV.Tax = calculation.Result.Amount
 		}
 		response.Taxes = append(response.Taxes, responseTaxResultForCSV)
 	}
+	logging.AddFields(c, slog.Int("csv_rows", len(response.Taxes)))
+	h.observeCsvRows(CsvRowsProcessed, len(response.Taxes))
 	return c.JSON(http.StatusOK, response)
 }
 
+// GetDeductions
+//
+//	@Summary		Get deductions
+//	@Description	Get current personal deduction and max k-receipt deduction with the ETags to update them
+//	@Tags			tax
+//	@Produce		json
+//	@Success		200	{object}	DeductionsResponse
+//	@Router			/admin/deductions [get]
+//	@Failure		500	{object}	Err
+func (h *Handler) GetDeductions(c echo.Context) error {
+	deductions, err := h.Service.Deductions(c.Request().Context())
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	return c.JSON(http.StatusOK, DeductionsResponse{
+		PersonalDeduction:     deductions.PersonalDeduction,
+		PersonalDeductionETag: SettingETag(SettingPersonalDeduction, deductions.PersonalDeductionVersion),
+		KReceipt:              deductions.KReceipt,
+		KReceiptETag:          SettingETag(SettingMaxKReceipt, deductions.KReceiptVersion),
+	})
+}
+
+// GetPersonalDeduction
+//
+//	@Summary		Get personal deduction
+//	@Description	Get personal deduction, its ETag is returned in the ETag header. Alias of the personal_default allowance setting
+//	@Tags			tax
+//	@Produce		json
+//	@Success		200	{object}	UpdatePersonalDeductionResponse
+//	@Header			200	{string}	ETag	"Version of the setting to send in If-Match"
+//	@Router			/admin/deductions/personal [get]
+//	@Failure		500	{object}	Err
+func (h *Handler) GetPersonalDeduction(c echo.Context) error {
+	return h.getAllowanceSetting(c, SettingPersonalDeduction, func(setting AllowanceSetting) interface{} {
+		return UpdatePersonalDeductionResponse{Amount: setting.Amount}
+	})
+}
+
+// GetKReceipt
+//
+//	@Summary		Get max k-receipt deduction
+//	@Description	Get max k-receipt deduction, its ETag is returned in the ETag header. Alias of the kreceipt_max allowance setting
+//	@Tags			tax
+//	@Produce		json
+//	@Success		200	{object}	UpdateKReceiptsResponse
+//	@Header			200	{string}	ETag	"Version of the setting to send in If-Match"
+//	@Router			/admin/deductions/k-receipt [get]
+//	@Failure		500	{object}	Err
+func (h *Handler) GetKReceipt(c echo.Context) error {
+	return h.getAllowanceSetting(c, SettingMaxKReceipt, func(setting AllowanceSetting) interface{} {
+		return UpdateKReceiptsResponse{Amount: setting.Amount}
+	})
+}
+
+// ifMatchVersion returns the version of the named setting the request was
+// made against, or false if the request has no If-Match header.
+func ifMatchVersion(c echo.Context, name string) (int, bool) {
+	ifMatch := c.Request().Header.Get(HeaderIfMatch)
+	if ifMatch == "" {
+		return 0, false
+	}
+	return ParseSettingETag(name, ifMatch), true
+}
+
+// getAllowanceSetting writes the setting in the format of response with its
+// ETag header.
+func (h *Handler) getAllowanceSetting(c echo.Context, settingType string, response func(AllowanceSetting) interface{}) error {
+	setting, err := h.Service.AllowanceSetting(c.Request().Context(), settingType)
+	if ErrorKindOf(err) == ErrorKindNotFound {
+		return c.JSON(http.StatusNotFound, Err{Message: LocaleFromContext(c).ErrorMessage(err)})
+	}
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	c.Response().Header().Set(HeaderETag, SettingETag(setting.Type, setting.Version))
+	return c.JSON(http.StatusOK, response(setting))
+}
+
+// updateAllowanceSetting updates the setting with the version of If-Match and
+// writes the stored setting in the format of response. The ETag header is
+// the one of the stored setting or, on conflict, of the current one.
+func (h *Handler) updateAllowanceSetting(c echo.Context, settingType string, amount float64, response func(AllowanceSetting) interface{}) error {
+	locale := LocaleFromContext(c)
+	version, ok := ifMatchVersion(c, settingType)
+	if !ok {
+		return c.JSON(http.StatusPreconditionRequired, Err{Message: locale.Message(MsgIfMatchRequired)})
+	}
+	setting, err := h.Service.UpdateAllowanceSetting(c.Request().Context(), settingType, amount, version)
+	switch {
+	case err == nil:
+		c.Response().Header().Set(HeaderETag, SettingETag(settingType, setting.Version))
+		return c.JSON(http.StatusOK, response(setting))
+	case ErrorKindOf(err) == ErrorKindInvalidInput:
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	case ErrorKindOf(err) == ErrorKindNotFound:
+		return c.JSON(http.StatusNotFound, Err{Message: locale.ErrorMessage(err)})
+	case ErrorKindOf(err) == ErrorKindConflict:
+		c.Response().Header().Set(HeaderETag, SettingETag(settingType, setting.Version))
+		return c.JSON(http.StatusPreconditionFailed, SettingConflictResponse{Message: locale.ErrorMessage(err), Current: setting.Amount})
+	default:
+		return h.internalError(c, err)
+	}
+}
+
 // UpdatePersonalDeductionRequest
 //
 //	@Summary		Update personal deduction
-//	@Description	Update personal deduction
+//	@Description	Update personal deduction if If-Match is the ETag of the current setting. Alias of the personal_default allowance setting
 //	@Tags			tax
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	Response
+//	@Success		200	{object}	UpdatePersonalDeductionResponse
+//	@Header			200	{string}	ETag	"Version of the updated setting"
 //	@Router			/admin/deductions/personal [post]
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	SettingConflictResponse
+//	@Failure		422	{object}	Err
+//	@Failure		428	{object}	Err
 //	@Failure		500	{object}	Err
 //	@Failure		400	{object}	Err
 //	@Param 			UpdatePersonalDeductionRequest body UpdatePersonalDeductionRequest true "Body for update personal deduction"
+//	@Param 			If-Match header string true "ETag of the setting the update is based on"
+//	@Param 			Idempotency-Key header string false "Replay the first response when the request is retried with the same key"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
 func (h *Handler) UpdatePersonalDeduction(c echo.Context) error {
 	var request UpdatePersonalDeductionRequest
 	if err := c.Bind(&request); err != nil {
 		return err
 	}
-	if request.Amount > 100000 {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Personal deduction must be within 100,000"})
-	}
-	if request.Amount <= 10000 {
-		return c.JSON(http.StatusBadRequest, Err{Message: "Personal deduction must be more than 10,000"})
-	}
-	err := h.Store.UpdateDefaultPersonalDeduction(request.Amount)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
-	}
-	personalDeductAmount, err := h.Store.GetDefaultPersonalDeduction()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
-	}
-	return c.JSON(http.StatusOK, UpdatePersonalDeductionResponse{
-		Amount: personalDeductAmount,
+	return h.updateAllowanceSetting(c, SettingPersonalDeduction, request.Amount, func(setting AllowanceSetting) interface{} {
+		return UpdatePersonalDeductionResponse{Amount: setting.Amount}
 	})
 }
 
 // UpdateKReceipt
 //
 //	@Summary		Update max k-receipt deduction
-//	@Description	Update max k-receipt deduction
+//	@Description	Update max k-receipt deduction if If-Match is the ETag of the current setting. Alias of the kreceipt_max allowance setting
 //	@Tags			tax
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	Response
+//	@Success		200	{object}	UpdateKReceiptsResponse
+//	@Header			200	{string}	ETag	"Version of the updated setting"
 //	@Router			/admin/deductions/k-receipt [post]
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	SettingConflictResponse
+//	@Failure		422	{object}	Err
+//	@Failure		428	{object}	Err
 //	@Failure		500	{object}	Err
 //	@Failure		400	{object}	Err
 //	@Param 			UpdateKReceiptRequest body UpdateKReceiptRequest true "Body for update k-receipt deduction"
+//	@Param 			If-Match header string true "ETag of the setting the update is based on"
+//	@Param 			Idempotency-Key header string false "Replay the first response when the request is retried with the same key"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
 func (h *Handler) UpdateKReceipt(c echo.Context) error {
 	var request UpdateKReceiptRequest
 	if err := c.Bind(&request); err != nil {
 		return err
 	}
-	if request.Amount > 100000 {
-		return c.JSON(http.StatusBadRequest, Err{Message: "k-receipt deduction must be within 100,000"})
+	return h.updateAllowanceSetting(c, SettingMaxKReceipt, request.Amount, func(setting AllowanceSetting) interface{} {
+		return UpdateKReceiptsResponse{Amount: setting.Amount}
+	})
+}
+
+// GetAllowanceSettings
+//
+//	@Summary		List allowance settings
+//	@Description	List every allowance setting with its bounds under the current rule set, unit, description and ETag
+//	@Tags			tax
+//	@Produce		json
+//	@Success		200	{object}	AllowanceSettingsResponse
+//	@Router			/admin/allowances [get]
+//	@Failure		500	{object}	Err
+//	@Param 			Accept-Language header string false "Description language (th or en)"
+func (h *Handler) GetAllowanceSettings(c echo.Context) error {
+	settings, err := h.Service.AllowanceSettings(c.Request().Context())
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	locale := LocaleFromContext(c)
+	response := AllowanceSettingsResponse{Allowances: []AllowanceSettingResponse{}}
+	for _, setting := range settings {
+		response.Allowances = append(response.Allowances, NewAllowanceSettingResponse(setting, locale))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// UpdateAllowanceSetting
+//
+//	@Summary		Update allowance setting
+//	@Description	Update an allowance setting within its bounds if If-Match is the ETag of the current setting
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	AllowanceSettingResponse
+//	@Header			200	{string}	ETag	"Version of the updated setting"
+//	@Router			/admin/allowances/{type} [put]
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	SettingConflictResponse
+//	@Failure		422	{object}	Err
+//	@Failure		428	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Param 			type path string true "Allowance setting type, for example personal_default or kreceipt_max"
+//	@Param 			UpdateAllowanceSettingRequest body UpdateAllowanceSettingRequest true "New amount of the setting"
+//	@Param 			If-Match header string true "ETag of the setting the update is based on"
+//	@Param 			Idempotency-Key header string false "Replay the first response when the request is retried with the same key"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) UpdateAllowanceSetting(c echo.Context) error {
+	var request UpdateAllowanceSettingRequest
+	if err := c.Bind(&request); err != nil {
+		return err
 	}
-	if request.Amount <= 0 {
-		return c.JSON(http.StatusBadRequest, Err{Message: "k-receipt deduction must be more than 0"})
+	locale := LocaleFromContext(c)
+	return h.updateAllowanceSetting(c, c.Param("type"), request.Amount, func(setting AllowanceSetting) interface{} {
+		return NewAllowanceSettingResponse(setting, locale)
+	})
+}
+
+// parseTaxYear reads a tax year from a path or query parameter.
+func parseTaxYear(value string) (int, error) {
+	taxYear, err := strconv.Atoi(value)
+	if err != nil || taxYear <= 0 {
+		return 0, NewLocalizedError(MsgInvalidTaxYear, value)
 	}
-	err := h.Store.UpdateMaxKReceipt(request.Amount)
+	return taxYear, nil
+}
+
+// GetRules
+//
+//	@Summary		Get rule set
+//	@Description	Get the statutory bounds of a tax year, its ETag is returned in the ETag header
+//	@Tags			tax
+//	@Produce		json
+//	@Success		200	{object}	RuleSetResponse
+//	@Header			200	{string}	ETag	"Version of the rule set to send in If-Match"
+//	@Router			/admin/rules/{year} [get]
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Param 			year path int true "Tax year, for example 2567"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) GetRules(c echo.Context) error {
+	locale := LocaleFromContext(c)
+	taxYear, err := parseTaxYear(c.Param("year"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	rules, err := h.Service.Rules(c.Request().Context(), taxYear)
+	if ErrorKindOf(err) == ErrorKindNotFound {
+		return c.JSON(http.StatusNotFound, Err{Message: locale.ErrorMessage(err)})
 	}
-	amount, err := h.Store.GetMaxKReceipt()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Err{Message: err.Error()})
+		return h.internalError(c, err)
 	}
-	return c.JSON(http.StatusOK, UpdateKReceiptsResponse{
-		Amount: amount,
-	})
+	c.Response().Header().Set(HeaderETag, SettingETag(rules.ETagName(), rules.Version))
+	return c.JSON(http.StatusOK, NewRuleSetResponse(rules))
+}
+
+// UpdateRules
+//
+//	@Summary		Update rule set
+//	@Description	Update the statutory bounds of a tax year, which limit the deduction settings and cap allowances in calculations for the year. Send If-None-Match: * instead of If-Match to create the rule set of a year that has none
+//	@Tags			tax
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	RuleSetResponse
+//	@Header			200	{string}	ETag	"Version of the updated rule set"
+//	@Router			/admin/rules/{year} [put]
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		412	{object}	RuleSetConflictResponse
+//	@Failure		428	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Param 			year path int true "Tax year, for example 2567"
+//	@Param 			RuleSetRequest body RuleSetRequest true "Bounds of the tax year"
+//	@Param 			If-Match header string false "ETag of the rule set the update is based on"
+//	@Param 			If-None-Match header string false "* to create the rule set of a year that has none"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) UpdateRules(c echo.Context) error {
+	locale := LocaleFromContext(c)
+	taxYear, err := parseTaxYear(c.Param("year"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	}
+	var request RuleSetRequest
+	if err := c.Bind(&request); err != nil {
+		return err
+	}
+	rules := RuleSet{
+		TaxYear:              taxYear,
+		PersonalDeductionMin: request.PersonalDeductionMin,
+		PersonalDeductionMax: request.PersonalDeductionMax,
+		KReceiptMin:          request.KReceiptMin,
+		KReceiptMax:          request.KReceiptMax,
+		DonationMax:          request.DonationMax,
+	}
+	var stored RuleSet
+	if version, ok := ifMatchVersion(c, rules.ETagName()); ok {
+		stored, err = h.Service.UpdateRules(c.Request().Context(), rules, version)
+	} else if c.Request().Header.Get(HeaderIfNoneMatch) == "*" {
+		stored, err = h.Service.CreateRules(c.Request().Context(), rules)
+	} else {
+		return c.JSON(http.StatusPreconditionRequired, Err{Message: locale.Message(MsgIfMatchRequired)})
+	}
+	switch {
+	case err == nil:
+		c.Response().Header().Set(HeaderETag, SettingETag(stored.ETagName(), stored.Version))
+		return c.JSON(http.StatusOK, NewRuleSetResponse(stored))
+	case ErrorKindOf(err) == ErrorKindInvalidInput:
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	case ErrorKindOf(err) == ErrorKindNotFound:
+		return c.JSON(http.StatusNotFound, Err{Message: locale.ErrorMessage(err)})
+	case ErrorKindOf(err) == ErrorKindConflict:
+		c.Response().Header().Set(HeaderETag, SettingETag(stored.ETagName(), stored.Version))
+		return c.JSON(http.StatusPreconditionFailed, RuleSetConflictResponse{Message: locale.ErrorMessage(err), Current: NewRuleSetResponse(stored)})
+	default:
+		return h.internalError(c, err)
+	}
+}
+
+// ExportRules
+//
+//	@Summary		Export rules
+//	@Description	Export tax brackets, allowance settings and bounds of a tax year as a document signed with the rules signing key, to import it in another environment
+//	@Tags			tax
+//	@Produce		json
+//	@Produce		application/yaml
+//	@Success		200	{object}	RulesDocument
+//	@Router			/admin/rules/export [get]
+//	@Failure		400	{object}	Err
+//	@Failure		404	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Param 			year query int false "Tax year, default the current tax year"
+//	@Param 			format query string false "Export format (json or yaml), default json"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) ExportRules(c echo.Context) error {
+	locale := LocaleFromContext(c)
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "yaml" {
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.Message(MsgUnknownRulesFormat, format)})
+	}
+	taxYear := FilingTaxYear
+	if value := c.QueryParam("year"); value != "" {
+		parsed, err := parseTaxYear(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+		}
+		taxYear = parsed
+	}
+	document, err := h.Service.ExportRules(c.Request().Context(), taxYear)
+	if ErrorKindOf(err) == ErrorKindNotFound {
+		return c.JSON(http.StatusNotFound, Err{Message: locale.ErrorMessage(err)})
+	}
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	if format == "yaml" {
+		body, err := yaml.Marshal(document)
+		if err != nil {
+			return h.internalError(c, err)
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="rules-%d.yaml"`, document.TaxYear))
+		return c.Blob(http.StatusOK, MIMEApplicationYAML, body)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="rules-%d.json"`, document.TaxYear))
+	return c.JSON(http.StatusOK, document)
+}
+
+// isYAML reports whether the media type is one of the names YAML is sent as.
+func isYAML(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case MIMEApplicationYAML, "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// decodeRulesDocument reads a JSON or YAML document, rejecting unknown fields
+// so a misspelt value is not silently left out.
+func decodeRulesDocument(req *http.Request) (RulesDocument, error) {
+	var document RulesDocument
+	if isYAML(req.Header.Get(echo.HeaderContentType)) {
+		decoder := yaml.NewDecoder(req.Body)
+		decoder.KnownFields(true)
+		return document, decoder.Decode(&document)
+	}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	return document, decoder.Decode(&document)
+}
+
+// ImportRules
+//
+//	@Summary		Import rules
+//	@Description	Validate a document exported by /admin/rules/export and store the bounds of its tax year and, for the current tax year only, its allowance settings in one transaction. With dryRun only the differences from the current values are returned, with an ETag of their versions which the import must send in If-Match so values changed since the dry run are not overwritten
+//	@Tags			tax
+//	@Accept			json
+//	@Accept			application/yaml
+//	@Produce		json
+//	@Success		200	{object}	RulesImportResponse
+//	@Header			200	{string}	ETag	"Versions of the current values to send in If-Match"
+//	@Router			/admin/rules/import [post]
+//	@Failure		400	{object}	Err
+//	@Failure		409	{object}	Err
+//	@Failure		412	{object}	Err
+//	@Failure		422	{object}	Err
+//	@Failure		428	{object}	Err
+//	@Failure		500	{object}	Err
+//	@Param 			RulesDocument body RulesDocument true "Exported rules document"
+//	@Param 			dryRun query bool false "Only return the differences from the current values"
+//	@Param 			If-Match header string false "ETag returned by the dry run, required unless dryRun is set"
+//	@Param 			Idempotency-Key header string false "Replay the first response when the request is retried with the same key"
+//	@Param 			Accept-Language header string false "Response language (th or en)"
+func (h *Handler) ImportRules(c echo.Context) error {
+	locale := LocaleFromContext(c)
+	dryRun := false
+	if value := c.QueryParam("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		}
+		dryRun = parsed
+	}
+	ifMatch := c.Request().Header.Get(HeaderIfMatch)
+	if !dryRun && ifMatch == "" {
+		return c.JSON(http.StatusPreconditionRequired, Err{Message: locale.Message(MsgRulesImportETagRequired)})
+	}
+	document, err := decodeRulesDocument(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	result, err := h.Service.ImportRules(c.Request().Context(), document, dryRun, ParseRulesVersionsETag(document.TaxYear, ifMatch))
+	switch {
+	case ErrorKindOf(err) == ErrorKindInvalidInput:
+		return c.JSON(http.StatusBadRequest, Err{Message: locale.ErrorMessage(err)})
+	case ErrorKindOf(err) == ErrorKindConflict:
+		c.Response().Header().Set(HeaderETag, result.Versions.ETag(result.TaxYear))
+		return c.JSON(http.StatusPreconditionFailed, Err{Message: locale.ErrorMessage(err)})
+	case err != nil:
+		return h.internalError(c, err)
+	}
+	response := RulesImportResponse{TaxYear: result.TaxYear, DryRun: dryRun, Applied: result.Applied, Changes: []RuleChangeResponse{}}
+	for _, change := range result.Changes {
+		response.Changes = append(response.Changes, RuleChangeResponse{Field: change.Field, Current: change.Current, Imported: change.Imported})
+	}
+	c.Response().Header().Set(HeaderETag, result.Versions.ETag(result.TaxYear))
+	return c.JSON(http.StatusOK, response)
 }
@@ -2,43 +2,151 @@ package tax
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
 )
 
 type MockStore struct {
-	PersonalDeductionAmount float64
-	MaxKReceipt             float64
+	PersonalDeductionAmount  float64
+	PersonalDeductionVersion int
+	MaxKReceipt              float64
+	MaxKReceiptVersion       int
+	Rules                    map[int]RuleSet
 }
 
-func (m *MockStore) UpdateDefaultPersonalDeduction(value float64) error {
-	m.PersonalDeductionAmount = value
-	return nil
+// settings returns the fields of the allowance setting type.
+func (m *MockStore) settings(allowanceType string) (*float64, *int) {
+	switch allowanceType {
+	case SettingPersonalDeduction:
+		return &m.PersonalDeductionAmount, &m.PersonalDeductionVersion
+	case SettingMaxKReceipt:
+		return &m.MaxKReceipt, &m.MaxKReceiptVersion
+	}
+	return new(float64), new(int)
+}
+
+func (m *MockStore) GetAllowanceSetting(ctx context.Context, allowanceType string) (Setting, error) {
+	amount, version := m.settings(allowanceType)
+	return Setting{Amount: *amount, Version: *version}, nil
+}
+
+func (m *MockStore) UpdateAllowanceSetting(ctx context.Context, allowanceType string, value float64, version int) (Setting, bool, error) {
+	amount, current := m.settings(allowanceType)
+	if version != *current {
+		return Setting{Amount: *amount, Version: *current}, false, nil
+	}
+	*amount = value
+	*current++
+	return Setting{Amount: *amount, Version: *current}, true, nil
+}
+
+func (m *MockStore) GetRuleSet(ctx context.Context, taxYear int) (RuleSet, error) {
+	rules, ok := m.Rules[taxYear]
+	if !ok {
+		return RuleSet{}, ErrRuleSetNotFound
+	}
+	return rules, nil
+}
+
+func (m *MockStore) UpdateRuleSet(ctx context.Context, rules RuleSet, version int) (RuleSet, bool, error) {
+	stored, ok := m.Rules[rules.TaxYear]
+	if !ok {
+		return RuleSet{}, false, ErrRuleSetNotFound
+	}
+	if version != stored.Version {
+		return stored, false, nil
+	}
+	rules.Version = version + 1
+	m.Rules[rules.TaxYear] = rules
+	return rules, true, nil
+}
+
+func (m *MockStore) CreateRuleSet(ctx context.Context, rules RuleSet) (RuleSet, bool, error) {
+	if stored, ok := m.Rules[rules.TaxYear]; ok {
+		return stored, false, nil
+	}
+	rules.Version = 1
+	m.Rules[rules.TaxYear] = rules
+	return rules, true, nil
+}
+
+func (m *MockStore) ApplyRules(ctx context.Context, rules RuleSet, allowances map[string]float64, versions RulesVersions) (bool, error) {
+	current := RulesVersions{RuleSet: m.Rules[rules.TaxYear].Version, Allowances: map[string]int{}}
+	for allowanceType := range allowances {
+		_, version := m.settings(allowanceType)
+		current.Allowances[allowanceType] = *version
+	}
+	if !current.Equal(versions) {
+		return false, nil
+	}
+	rules.Version = m.Rules[rules.TaxYear].Version + 1
+	m.Rules[rules.TaxYear] = rules
+	for allowanceType, value := range allowances {
+		amount, version := m.settings(allowanceType)
+		if *amount != value {
+			*amount = value
+			*version++
+		}
+	}
+	return true, nil
 }
 
-func (m *MockStore) GetDefaultPersonalDeduction() (float64, error) {
-	return m.PersonalDeductionAmount, nil
+type MockRowQuota struct {
+	Remaining int
+	Requested int
+	Refunded  int
 }
 
-func (m *MockStore) UpdateMaxKReceipt(value float64) error {
-	m.MaxKReceipt = value
+func (m *MockRowQuota) RefundRows(c echo.Context, rows int) error {
+	m.Refunded = m.Refunded + rows
+	m.Remaining = m.Remaining + rows
 	return nil
 }
 
-func (m *MockStore) GetMaxKReceipt() (float64, error) {
-	return m.MaxKReceipt, nil
+func (m *MockRowQuota) ConsumeRows(c echo.Context, rows int) (bool, error) {
+	m.Requested = rows
+	if rows > m.Remaining {
+		return false, nil
+	}
+	m.Remaining = m.Remaining - rows
+	return true, nil
+}
+
+type MockMetrics struct {
+	Calculations int
+	CsvRows      map[string]int
+}
+
+func (m *MockMetrics) ObserveCalculation(result Result) {
+	m.Calculations++
+}
+
+func (m *MockMetrics) ObserveCsvRows(outcome string, rows int) {
+	if m.CsvRows == nil {
+		m.CsvRows = map[string]int{}
+	}
+	m.CsvRows[outcome] += rows
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
 }
 
 func NewMockStore() *MockStore {
-	return &MockStore{PersonalDeductionAmount: 60000.00, MaxKReceipt: 50000.00}
+	rules := DefaultRuleSet
+	rules.Version = 1
+	return &MockStore{PersonalDeductionAmount: 60000.00, PersonalDeductionVersion: 1, MaxKReceipt: 50000.00, MaxKReceiptVersion: 1, Rules: map[int]RuleSet{rules.TaxYear: rules}}
 }
 
 func TestTaxHandler(t *testing.T) {
@@ -59,7 +167,7 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
 		handler.CalculateTax(c)
 
 		if res.Result().StatusCode != http.StatusOK {
@@ -74,6 +182,7 @@ func TestTaxHandler(t *testing.T) {
 				{"1,000,001 - 2,000,000", 0.00},
 				{"2,000,001 ขึ้นไป", 0.00},
 			},
+			Rates: TaxRateResponse{440000.0, 5.8, 6.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(210001.0)},
 		}
 		var got Response
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
@@ -101,7 +210,7 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
 		handler.CalculateTax(c)
 
 		if res.Result().StatusCode != http.StatusOK {
@@ -116,6 +225,7 @@ func TestTaxHandler(t *testing.T) {
 				{"1,000,001 - 2,000,000", 0.00},
 				{"2,000,001 ขึ้นไป", 0.00},
 			},
+			Rates: TaxRateResponse{440000.0, 5.8, 6.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(210001.0)},
 		}
 		var got Response
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
@@ -143,7 +253,7 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
 		handler.CalculateTax(c)
 
 		if res.Result().StatusCode != http.StatusOK {
@@ -158,6 +268,7 @@ func TestTaxHandler(t *testing.T) {
 				{"1,000,001 - 2,000,000", 0.00},
 				{"2,000,001 ขึ้นไป", 0.00},
 			},
+			Rates: TaxRateResponse{340000.0, 3.8, 5.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(310001.0)},
 		}
 		var got Response
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
@@ -186,7 +297,7 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
 		handler.CalculateTax(c)
 
 		if res.Result().StatusCode != http.StatusOK {
@@ -201,6 +312,7 @@ func TestTaxHandler(t *testing.T) {
 				{"1,000,001 - 2,000,000", 0.00},
 				{"2,000,001 ขึ้นไป", 0.00},
 			},
+			Rates: TaxRateResponse{290000.0, 2.8, 4.83, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(360001.0)},
 		}
 		var got Response
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
@@ -226,7 +338,7 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
 		handler.CalculateTax(c)
 
 		if res.Result().StatusCode != http.StatusOK {
@@ -241,6 +353,7 @@ func TestTaxHandler(t *testing.T) {
 				{"1,000,001 - 2,000,000", 0.00},
 				{"2,000,001 ขึ้นไป", 0.00},
 			},
+			Rates: TaxRateResponse{40000.0, 0, 0, "0 - 150,000", 0, floatPointer(150001.0), floatPointer(110001.0)},
 		}
 		var got Response
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
@@ -251,45 +364,159 @@ func TestTaxHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("given request with CSV file should return 200 and response with tax info", func(t *testing.T) {
-		body := new(bytes.Buffer)
-		writer := multipart.NewWriter(body)
-		dataPart, err := writer.CreateFormFile("taxes.csv", "taxes.csv")
+	t.Run("given request with total income 5000000.0 and accept language en should return 200 and response with english levels", func(t *testing.T) {
+		body, err := json.Marshal(CalculationRequest{
+			TotalIncome:    5000000.0,
+			WithHoldingTax: 0,
+			Allowances:     []AllowanceRequest{},
+		})
 		if err != nil {
-			t.Errorf("Unable to create form file with error: %v", err)
+			t.Errorf("Unable to create body request, error: %v", err)
 		}
-		f, err := os.Open("../test.csv")
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderAcceptLanguage, "en-US,th;q=0.5")
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTax(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := Response{
+			Tax: 1051500.0,
+			TaxLevelResponses: []TaxLevelResponse{
+				{"0 - 150,000", 0.00},
+				{"150,001 - 500,000", 50000.00},
+				{"500,001 - 1,000,000", 150000.00},
+				{"1,000,001 - 2,000,000", 400000.00},
+				{"2,000,001 and above", 451500.00},
+			},
+			Rates: TaxRateResponse{4940000.0, 21.03, 21.29, "2,000,001 and above", 35, nil, nil},
+		}
+		var got Response
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request with unknown allowance and accept language th should return 400 and thai error message", func(t *testing.T) {
+		body, err := json.Marshal(CalculationRequest{
+			TotalIncome:    500000.0,
+			WithHoldingTax: 0,
+			Allowances: []AllowanceRequest{
+				{Type: "lottery", Amount: 1000.0},
+			},
+		})
 		if err != nil {
-			t.Errorf("Unable to open file with error: %v", err)
+			t.Errorf("Unable to create body request, error: %v", err)
 		}
-		_, err = io.Copy(dataPart, f)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderAcceptLanguage, "th")
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTax(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Result().StatusCode)
+		}
+		want := Err{"ไม่รู้จักประเภทค่าลดหย่อน: lottery"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request report with accept language en should return 200 and pdf document", func(t *testing.T) {
+		body, err := json.Marshal(CalculationRequest{
+			TotalIncome:    500000.0,
+			WithHoldingTax: 0,
+			Allowances: []AllowanceRequest{
+				{Type: "donation", Amount: 200000.0},
+			},
+		})
 		if err != nil {
-			t.Errorf("Unable to copy file with error: %v", err)
+			t.Errorf("Unable to create body request, error: %v", err)
 		}
-		err = writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderAcceptLanguage, "en")
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxReport(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		if got := res.Header().Get(echo.HeaderContentType); got != "application/pdf" {
+			t.Errorf("expected content type application/pdf but got %v", got)
+		}
+		if !bytes.HasPrefix(res.Body.Bytes(), []byte("%PDF-")) {
+			t.Errorf("expected pdf document")
+		}
+	})
+
+	t.Run("given request report without accept language should return 200 and thai pdf document with the shipped font", func(t *testing.T) {
+		font, err := os.ReadFile("../fonts/FreeSerif.ttf")
 		if err != nil {
-			t.Errorf("Unable to close writer after write body request, error : %v", err)
+			t.Fatalf("Unable to read report font, error: %v", err)
 		}
-		req := httptest.NewRequest(http.MethodPost, "/", body)
-		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		body, err := json.Marshal(CalculationRequest{TotalIncome: 500000.0})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		res := httptest.NewRecorder()
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.CalculateTaxCsv(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}, ReportFont: font}
+		handler.CalculateTaxReport(c)
 
 		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := ResponseForCSV{
-			Taxes: []ResponseTaxResultForCSV{
-				{TotalIncome: 500000.00, Tax: 29000},
-				{TotalIncome: 600000.00, TaxRefund: 3000},
-				{TotalIncome: 750000.00, Tax: 3750},
-			},
+		if !bytes.HasPrefix(res.Body.Bytes(), []byte("%PDF-")) {
+			t.Errorf("expected pdf document")
 		}
-		var got ResponseForCSV
+	})
+
+	t.Run("given request report without report font should return 500 and thai error message", func(t *testing.T) {
+		body, err := json.Marshal(CalculationRequest{TotalIncome: 500000.0})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxReport(c)
+
+		if res.Result().StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected status %v but got status %v", http.StatusInternalServerError, res.Result().StatusCode)
+		}
+		want := Err{"ยังไม่ได้ตั้งค่าฟอนต์สำหรับรายงาน"}
+		var got Err
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
@@ -298,8 +525,17 @@ func TestTaxHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("given request update personal deduction 29000.0 should return 200 and response with personal deduction amount 29000.0", func(t *testing.T) {
-		body, err := json.Marshal(UpdatePersonalDeductionRequest{29000.00})
+	t.Run("given request export filing with k-receipt 200000.0 should return 200 and response with PND91 fields", func(t *testing.T) {
+		body, err := json.Marshal(FilingRequest{
+			CalculationRequest: CalculationRequest{
+				TotalIncome:    500000.0,
+				WithHoldingTax: 0,
+				Allowances: []AllowanceRequest{
+					{Type: "k-receipt", Amount: 200000.0},
+				},
+			},
+			IncomeCategory: "40(1)",
+		})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -309,24 +545,81 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdatePersonalDeduction(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.ExportFiling(c)
 
 		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := UpdatePersonalDeductionResponse{29000.00}
-		var got UpdatePersonalDeductionResponse
+		var got FilingResponse
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
-		if !reflect.DeepEqual(got, want) {
-			t.Errorf("expected %v but got %v", want, got)
+		if got.Form != FormPND91 || got.Fields[FieldAllowances] != 110000.0 || got.Fields[FieldTaxPayable] != 24000.0 {
+			t.Errorf("expected PND91 with allowances 110000.0 and tax 24000.0 but got %v", got)
 		}
 	})
 
-	t.Run("given request update personal deduction 100001.0 should return 400 and response with error message", func(t *testing.T) {
-		body, err := json.Marshal(UpdatePersonalDeductionRequest{100001.00})
+	t.Run("given request export filing with format xml should return 200 and xml document", func(t *testing.T) {
+		body, err := json.Marshal(FilingRequest{CalculationRequest: CalculationRequest{TotalIncome: 500000.0}})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/?format=xml", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.ExportFiling(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		if got := res.Header().Get(echo.HeaderContentType); got != echo.MIMEApplicationXMLCharsetUTF8 {
+			t.Errorf("expected content type %v but got %v", echo.MIMEApplicationXMLCharsetUTF8, got)
+		}
+		if !bytes.Contains(res.Body.Bytes(), []byte(`<TaxForm form="PND91"`)) {
+			t.Errorf("expected PND91 xml document but got %s", res.Body.Bytes())
+		}
+	})
+
+	t.Run("given request export filing with tax year 2568 should return 200 and filing of that year", func(t *testing.T) {
+		store := NewMockStore()
+		rules := DefaultRuleSet
+		rules.TaxYear = 2568
+		store.Rules[2568] = rules
+		body, err := json.Marshal(FilingRequest{CalculationRequest: CalculationRequest{TotalIncome: 500000.0, TaxYear: 2568}})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		for format, want := range map[string]string{
+			"json": `"taxYear":2568`,
+			"xml":  `<TaxForm form="PND91" taxYear="2568"`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/?format="+format, bytes.NewBuffer(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+			e := echo.New()
+			c := e.NewContext(req, res)
+
+			handler := Handler{Service: &TaxService{Store: store}}
+			handler.ExportFiling(c)
+
+			if res.Result().StatusCode != http.StatusOK || !bytes.Contains(res.Body.Bytes(), []byte(want)) {
+				t.Errorf("expected status %v with %v but got status %v with %s", http.StatusOK, want, res.Result().StatusCode, res.Body.Bytes())
+			}
+		}
+	})
+
+	t.Run("given request inverse with target tax 29000.0 should return 200 and response with total income 500000.0", func(t *testing.T) {
+		targetTax := 29000.0
+		body, err := json.Marshal(InverseCalculationRequest{
+			TargetTax:      &targetTax,
+			WithHoldingTax: 25000.0,
+			Allowances:     []AllowanceRequest{},
+		})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -336,14 +629,29 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdatePersonalDeduction(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxInverse(c)
 
-		if res.Result().StatusCode != http.StatusBadRequest {
+		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := Err{"Personal deduction must be within 100,000"}
-		var got Err
+		want := InverseCalculationResponse{
+			TotalIncome: 500000.0,
+			NetIncome:   471000.0,
+			TotalTax:    29000.0,
+			Calculation: Response{
+				Tax: 4000.0,
+				TaxLevelResponses: []TaxLevelResponse{
+					{"0 - 150,000", 0.00},
+					{"150,001 - 500,000", 29000.00},
+					{"500,001 - 1,000,000", 0.00},
+					{"1,000,001 - 2,000,000", 0.00},
+					{"2,000,001 ขึ้นไป", 0.00},
+				},
+				Rates: TaxRateResponse{440000.0, 5.8, 6.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(210001.0)},
+			},
+		}
+		var got InverseCalculationResponse
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
@@ -352,8 +660,9 @@ func TestTaxHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("given request update personal deduction 10000.0 should return 400 and response with error message", func(t *testing.T) {
-		body, err := json.Marshal(UpdatePersonalDeductionRequest{10000.00})
+	t.Run("given request inverse with target net income 1e308 should return 400", func(t *testing.T) {
+		target := 1e308
+		body, err := json.Marshal(InverseCalculationRequest{TargetNetIncome: &target, Allowances: []AllowanceRequest{}})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -363,13 +672,32 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdatePersonalDeduction(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxInverse(c)
 
 		if res.Result().StatusCode != http.StatusBadRequest {
-			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Result().StatusCode)
 		}
-		want := Err{"Personal deduction must be more than 10,000"}
+	})
+
+	t.Run("given request inverse without target should return 400 and response with error message", func(t *testing.T) {
+		body, err := json.Marshal(InverseCalculationRequest{Allowances: []AllowanceRequest{}})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxInverse(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Result().StatusCode)
+		}
+		want := Err{"Exactly one of targetNetIncome or targetTax is required"}
 		var got Err
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
@@ -379,9 +707,11 @@ func TestTaxHandler(t *testing.T) {
 		}
 	})
 
-	// implem
-	t.Run("given request update k-receipt 2000.0 should return 200 and response with k-receipt amount 2000.0", func(t *testing.T) {
-		body, err := json.Marshal(UpdateKReceiptRequest{2000.00})
+	t.Run("given request withholding schedule for 3 months of 50000.0 should return 200 and projected annual tax", func(t *testing.T) {
+		body, err := json.Marshal(WithholdingScheduleRequest{
+			MonthlyIncomes: []float64{50000.0, 50000.0, 50000.0},
+			Allowances:     []AllowanceRequest{},
+		})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -391,14 +721,23 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdateKReceipt(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateWithholdingSchedule(c)
 
 		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := UpdateKReceiptsResponse{2000.00}
-		var got UpdateKReceiptsResponse
+		want := WithholdingScheduleResponse{
+			AnnualIncome:        600000.0,
+			AnnualTax:           39000.0,
+			TotalWithHoldingTax: 9750.0,
+			Months: []WithholdingMonthResponse{
+				{1, 50000.0, 600000.0, 39000.0, 3250.0},
+				{2, 50000.0, 600000.0, 39000.0, 3250.0},
+				{3, 50000.0, 600000.0, 39000.0, 3250.0},
+			},
+		}
+		var got WithholdingScheduleResponse
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
@@ -407,8 +746,16 @@ func TestTaxHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("given request update k-receipt 100001.0 should return 400 and response with error message", func(t *testing.T) {
-		body, err := json.Marshal(UpdateKReceiptRequest{100001.00})
+	t.Run("given request compare k-receipt, donation and both should return 200 and response with deltas", func(t *testing.T) {
+		body, err := json.Marshal(CompareRequest{
+			Base: CalculationRequest{TotalIncome: 500000.0, Allowances: []AllowanceRequest{}},
+			Scenarios: []ScenarioRequest{
+				{Name: "k-receipt", Allowances: []AllowanceRequest{{"k-receipt", 50000.0}}},
+				{Name: "donation", Allowances: []AllowanceRequest{{"donation", 50000.0}}},
+				{Name: "both", Allowances: []AllowanceRequest{{"k-receipt", 50000.0}, {"donation", 50000.0}}},
+				{Name: "donation above cap", Allowances: []AllowanceRequest{{"donation", 500000.0}}},
+			},
+		})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -418,24 +765,44 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdateKReceipt(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CompareTaxScenarios(c)
 
-		if res.Result().StatusCode != http.StatusBadRequest {
+		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := Err{"k-receipt deduction must be within 100,000"}
-		var got Err
+		var got CompareResponse
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
-		if !reflect.DeepEqual(got, want) {
-			t.Errorf("expected %v but got %v", want, got)
+		if got.Base.Tax != 29000.0 {
+			t.Errorf("expected base tax %v but got %v", 29000.0, got.Base.Tax)
+		}
+		want := []struct {
+			tax, taxDelta, allowanceDelta, savingPerBaht float64
+		}{
+			{24000.0, -5000.0, 50000.0, 0.1},
+			{24000.0, -5000.0, 50000.0, 0.1},
+			{19000.0, -10000.0, 100000.0, 0.1},
+			{19000.0, -10000.0, 100000.0, 0.1},
+		}
+		if len(got.Scenarios) != len(want) {
+			t.Fatalf("expected %v scenarios but got %v", len(want), len(got.Scenarios))
+		}
+		for index, scenario := range got.Scenarios {
+			if scenario.Calculation.Tax != want[index].tax || scenario.TaxDelta != want[index].taxDelta ||
+				scenario.AllowanceDelta != want[index].allowanceDelta || scenario.TaxSavingPerBaht != want[index].savingPerBaht {
+				t.Errorf("expected %v but got %v", want[index], scenario)
+			}
 		}
 	})
 
-	t.Run("given request update k-receipt 0.0 should return 400 and response with error message", func(t *testing.T) {
-		body, err := json.Marshal(UpdateKReceiptRequest{0.00})
+	t.Run("given request advice with total income 500000.0 and budget 100000.0 should return 200 and response with allocation", func(t *testing.T) {
+		body, err := json.Marshal(AdviceRequest{
+			TotalIncome: 500000.0,
+			Budget:      100000.0,
+			Allowances:  []AllowanceRequest{},
+		})
 		if err != nil {
 			t.Errorf("Unable to create body request, error: %v", err)
 		}
@@ -445,14 +812,32 @@ func TestTaxHandler(t *testing.T) {
 		e := echo.New()
 		c := e.NewContext(req, res)
 
-		handler := Handler{Store: NewMockStore()}
-		handler.UpdateKReceipt(c)
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.AdviseAllowances(c)
 
-		if res.Result().StatusCode != http.StatusBadRequest {
+		if res.Result().StatusCode != http.StatusOK {
 			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
 		}
-		want := Err{"k-receipt deduction must be more than 0"}
-		var got Err
+		want := AdviceResponse{
+			RecommendedSpend: 100000.0,
+			TaxSaving:        10000.0,
+			Allowances: []AllowanceAdviceResponse{
+				{"k-receipt", 0.0, 50000.0, 50000.0, 0.0},
+				{"donation", 0.0, 50000.0, 100000.0, 50000.0},
+			},
+			Calculation: Response{
+				Tax: 19000.0,
+				TaxLevelResponses: []TaxLevelResponse{
+					{"0 - 150,000", 0.00},
+					{"150,001 - 500,000", 19000.00},
+					{"500,001 - 1,000,000", 0.00},
+					{"1,000,001 - 2,000,000", 0.00},
+					{"2,000,001 ขึ้นไป", 0.00},
+				},
+				Rates: TaxRateResponse{340000.0, 3.8, 5.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(310001.0)},
+			},
+		}
+		var got AdviceResponse
 		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
 			t.Errorf("Unable to unmarshal json: %v", err)
 		}
@@ -460,4 +845,845 @@ func TestTaxHandler(t *testing.T) {
 			t.Errorf("expected %v but got %v", want, got)
 		}
 	})
+
+	t.Run("given request advice with tax year 2568 should return 200 and allocation within its caps", func(t *testing.T) {
+		store := NewMockStore()
+		rules := DefaultRuleSet
+		rules.TaxYear = 2568
+		rules.DonationMax = 50000.0
+		store.Rules[2568] = rules
+		body, err := json.Marshal(AdviceRequest{
+			TotalIncome: 500000.0,
+			Budget:      100000.0,
+			Allowances:  []AllowanceRequest{},
+			TaxYear:     2568,
+		})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.AdviseAllowances(c)
+
+		var got AdviceResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if res.Result().StatusCode != http.StatusOK || len(got.Allowances) != 2 || got.Allowances[1].Cap != 50000.0 {
+			t.Errorf("expected status %v with donation cap 50000.0 but got status %v with %v", http.StatusOK, res.Result().StatusCode, got.Allowances)
+		}
+	})
+
+	t.Run("given inverse, withholding and advice requests of tax year without rule set should return 400", func(t *testing.T) {
+		targetTax := 29000.0
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		for name, test := range map[string]struct {
+			request any
+			handle  func(echo.Context) error
+		}{
+			"inverse":     {InverseCalculationRequest{TargetTax: &targetTax, TaxYear: 2599}, handler.CalculateTaxInverse},
+			"withholding": {WithholdingScheduleRequest{MonthlyIncomes: []float64{50000.0}, TaxYear: 2599}, handler.CalculateWithholdingSchedule},
+			"advice":      {AdviceRequest{TotalIncome: 500000.0, Budget: 100000.0, TaxYear: 2599}, handler.AdviseAllowances},
+		} {
+			body, err := json.Marshal(test.request)
+			if err != nil {
+				t.Errorf("Unable to create body request, error: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			res := httptest.NewRecorder()
+			e := echo.New()
+			c := e.NewContext(req, res)
+
+			test.handle(c)
+
+			if res.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("expected %v request to return status %v but got status %v", name, http.StatusBadRequest, res.Result().StatusCode)
+			}
+		}
+	})
+
+	t.Run("given request household with both incomes 1000000.0 should return 200 and recommend separate filing", func(t *testing.T) {
+		body, err := json.Marshal(HouseholdRequest{
+			Taxpayer: CalculationRequest{TotalIncome: 1000000.0, WithHoldingTax: 50000.0, Allowances: []AllowanceRequest{}},
+			Spouse:   CalculationRequest{TotalIncome: 1000000.0, Allowances: []AllowanceRequest{}},
+		})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateHouseholdTax(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		var got HouseholdResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if got.Separate.Taxpayer.Tax != 43500.0 || got.Separate.Spouse.Tax != 93500.0 || got.Separate.Tax != 137000.0 {
+			t.Errorf("expected separate taxes 43500.0, 93500.0 and 137000.0 but got %v", got.Separate)
+		}
+		if got.Joint.Calculation.Tax != 196000.0 || got.Joint.Tax != 196000.0 {
+			t.Errorf("expected joint tax 196000.0 but got %v", got.Joint)
+		}
+		if got.Recommendation != FilingModeSeparate || got.TaxSaving != 59000.0 {
+			t.Errorf("expected separate recommendation saving 59000.0 but got %v saving %v", got.Recommendation, got.TaxSaving)
+		}
+	})
+
+	t.Run("given request with CSV file should return 200 and response with tax info", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		dataPart, err := writer.CreateFormFile("taxes.csv", "taxes.csv")
+		if err != nil {
+			t.Errorf("Unable to create form file with error: %v", err)
+		}
+		f, err := os.Open("../test.csv")
+		if err != nil {
+			t.Errorf("Unable to open file with error: %v", err)
+		}
+		_, err = io.Copy(dataPart, f)
+		if err != nil {
+			t.Errorf("Unable to copy file with error: %v", err)
+		}
+		err = writer.Close()
+		if err != nil {
+			t.Errorf("Unable to close writer after write body request, error : %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.CalculateTaxCsv(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := ResponseForCSV{
+			Taxes: []ResponseTaxResultForCSV{
+				{TotalIncome: 500000.00, Tax: 29000, Rates: TaxRateResponse{440000.0, 5.8, 6.59, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(210001.0)}},
+				{TotalIncome: 600000.00, TaxRefund: 3000, Rates: TaxRateResponse{520000.0, 6.17, 7.12, "150,001 - 500,000", 10, floatPointer(650001.0), floatPointer(130001.0)}},
+				{TotalIncome: 750000.00, Tax: 3750, Rates: TaxRateResponse{675000.0, 7.17, 7.96, "500,001 - 1,000,000", 15, floatPointer(1650001.0), floatPointer(975001.0)}},
+			},
+		}
+		var got ResponseForCSV
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request with CSV file exceeding daily row quota should return 429", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		dataPart, err := writer.CreateFormFile("taxes.csv", "taxes.csv")
+		if err != nil {
+			t.Errorf("Unable to create form file with error: %v", err)
+		}
+		dataPart.Write([]byte("totalIncome,wht,donation\n500000,0,0\n600000,0,0\n"))
+		err = writer.Close()
+		if err != nil {
+			t.Errorf("Unable to close writer after write body request, error : %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		quota := &MockRowQuota{Remaining: 1}
+		metrics := &MockMetrics{}
+		handler := Handler{Service: &TaxService{Store: NewMockStore(), Metrics: metrics}, CsvQuota: quota}
+		handler.CalculateTaxCsv(c)
+
+		if res.Result().StatusCode != http.StatusTooManyRequests {
+			t.Errorf("expected status %v but got status %v", http.StatusTooManyRequests, res.Result().StatusCode)
+		}
+		want := Err{"Daily quota of CSV rows exceeded"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if quota.Requested != 2 {
+			t.Errorf("expected 2 rows requested from quota but got %v", quota.Requested)
+		}
+		if metrics.CsvRows[CsvRowsQuota] != 2 || metrics.Calculations != 0 {
+			t.Errorf("expected 2 rows rejected by quota and no calculation but got %v, %v", metrics.CsvRows, metrics.Calculations)
+		}
+	})
+
+	t.Run("given request with CSV file with invalid row should not consume row quota", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		dataPart, _ := writer.CreateFormFile("taxes.csv", "taxes.csv")
+		dataPart.Write([]byte("totalIncome,wht,donation\n500000,0,0\n600000,abc,0\n"))
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		quota := &MockRowQuota{Remaining: 10}
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}, CsvQuota: quota}
+		handler.CalculateTaxCsv(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest || quota.Requested != 0 || quota.Remaining != 10 {
+			t.Errorf("expected status %v without consuming quota but got %v and %v", http.StatusBadRequest, res.Result().StatusCode, quota)
+		}
+	})
+
+	t.Run("given request with CSV file failing to calculate should refund row quota", func(t *testing.T) {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		dataPart, _ := writer.CreateFormFile("taxes.csv", "taxes.csv")
+		dataPart.Write([]byte("totalIncome,wht,donation\n500000,0,0\n600000,0,0\n"))
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		quota := &MockRowQuota{Remaining: 10}
+		store := &FailingStore{MockStore: *NewMockStore(), Err: errors.New("connection refused")}
+		handler := Handler{Service: &TaxService{Store: store}, CsvQuota: quota}
+		handler.CalculateTaxCsv(c)
+
+		if quota.Requested != 2 || quota.Refunded != 2 || quota.Remaining != 10 {
+			t.Errorf("expected 2 rows consumed and refunded but got %v", quota)
+		}
+	})
+
+	t.Run("given request update personal deduction 29000.0 should return 200 and response with personal deduction amount 29000.0", func(t *testing.T) {
+		body, err := json.Marshal(UpdatePersonalDeductionRequest{29000.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := UpdatePersonalDeductionResponse{29000.00}
+		var got UpdatePersonalDeductionResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request update personal deduction 100001.0 should return 400 and response with error message", func(t *testing.T) {
+		body, err := json.Marshal(UpdatePersonalDeductionRequest{100001.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := Err{"Personal deduction must be within 100,000"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request update personal deduction 10000.0 should return 400 and response with error message", func(t *testing.T) {
+		body, err := json.Marshal(UpdatePersonalDeductionRequest{10000.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := Err{"Personal deduction must be more than 10,000"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	// implem
+	t.Run("given request update k-receipt 2000.0 should return 200 and response with k-receipt amount 2000.0", func(t *testing.T) {
+		body, err := json.Marshal(UpdateKReceiptRequest{2000.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateKReceipt(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := UpdateKReceiptsResponse{2000.00}
+		var got UpdateKReceiptsResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request update k-receipt 100001.0 should return 400 and response with error message", func(t *testing.T) {
+		body, err := json.Marshal(UpdateKReceiptRequest{100001.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateKReceipt(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := Err{"k-receipt deduction must be within 100,000"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request update k-receipt 0.0 should return 400 and response with error message", func(t *testing.T) {
+		body, err := json.Marshal(UpdateKReceiptRequest{0.00})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateKReceipt(c)
+
+		if res.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := Err{"k-receipt deduction must be more than 0"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given request get deductions should return 200 and response with current deductions", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		e := echo.New()
+		c := e.NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.GetDeductions(c)
+
+		if res.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Result().StatusCode)
+		}
+		want := DeductionsResponse{
+			PersonalDeduction:     60000.00,
+			PersonalDeductionETag: `"personal_default.1"`,
+			KReceipt:              50000.00,
+			KReceiptETag:          `"kreceipt_max.1"`,
+		}
+		var got DeductionsResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given update with current ETag should return 200 with ETag of the next version", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 70000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Code != http.StatusOK || res.Header().Get(HeaderETag) != `"personal_default.2"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusOK, `"personal_default.2"`, res.Code, res.Header().Get(HeaderETag))
+		}
+	})
+
+	t.Run("given update with stale ETag should return 412 with current value and not update store", func(t *testing.T) {
+		store := NewMockStore()
+		store.PersonalDeductionAmount = 80000.0
+		store.PersonalDeductionVersion = 2
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 70000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Code != http.StatusPreconditionFailed || res.Header().Get(HeaderETag) != `"personal_default.2"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusPreconditionFailed, `"personal_default.2"`, res.Code, res.Header().Get(HeaderETag))
+		}
+		var got SettingConflictResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if got.Current != 80000.0 || store.PersonalDeductionAmount != 80000.0 {
+			t.Errorf("expected current value 80000.0 to be kept but got %v", got)
+		}
+	})
+
+	t.Run("given update with ETag of another setting should return 412", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 2000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateKReceipt(c)
+
+		if res.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected status %v but got status %v", http.StatusPreconditionFailed, res.Code)
+		}
+	})
+
+	t.Run("given update without If-Match should return 428", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 2000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		store := NewMockStore()
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdateKReceipt(c)
+
+		if res.Code != http.StatusPreconditionRequired || store.MaxKReceipt != 50000.0 {
+			t.Errorf("expected status %v but got status %v", http.StatusPreconditionRequired, res.Code)
+		}
+	})
+
+	t.Run("given get k-receipt should return ETag header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.GetKReceipt(c)
+
+		if res.Code != http.StatusOK || res.Header().Get(HeaderETag) != `"kreceipt_max.1"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusOK, `"kreceipt_max.1"`, res.Code, res.Header().Get(HeaderETag))
+		}
+	})
+
+	t.Run("given superuser update rules with current ETag should return 200 with stored rules", func(t *testing.T) {
+		body, err := json.Marshal(RuleSetRequest{
+			PersonalDeductionMin: 15000.0,
+			PersonalDeductionMax: 120000.0,
+			KReceiptMin:          0.0,
+			KReceiptMax:          50000.0,
+			DonationMax:          80000.0,
+		})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"rules_2567.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2567")
+
+		store := NewMockStore()
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdateRules(c)
+
+		if res.Code != http.StatusOK || res.Header().Get(HeaderETag) != `"rules_2567.2"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusOK, `"rules_2567.2"`, res.Code, res.Header().Get(HeaderETag))
+		}
+		if rules := store.Rules[2567]; rules.PersonalDeductionMax != 120000.0 || rules.DonationMax != 80000.0 {
+			t.Errorf("expected rules to be stored but got %v", rules)
+		}
+	})
+
+	t.Run("given rules with minimum above maximum should return 400", func(t *testing.T) {
+		body, err := json.Marshal(RuleSetRequest{
+			PersonalDeductionMin: 150000.0,
+			PersonalDeductionMax: 100000.0,
+			KReceiptMax:          50000.0,
+			DonationMax:          100000.0,
+		})
+		if err != nil {
+			t.Errorf("Unable to create body request, error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"rules_2567.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2567")
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateRules(c)
+
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Code)
+		}
+	})
+
+	t.Run("given If-None-Match star for tax year without rules should create them at version 1", func(t *testing.T) {
+		body := `{"personalDeductionMin": 10000.0, "personalDeductionMax": 120000.0, "kReceiptMax": 100000.0, "donationMax": 100000.0}`
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfNoneMatch, "*")
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2568")
+
+		store := NewMockStore()
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdateRules(c)
+
+		if res.Code != http.StatusOK || res.Header().Get(HeaderETag) != `"rules_2568.1"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusOK, `"rules_2568.1"`, res.Code, res.Header().Get(HeaderETag))
+		}
+		if store.Rules[2568].PersonalDeductionMax != 120000.0 || store.Rules[2567].PersonalDeductionMax != 100000.0 {
+			t.Errorf("expected only rules of 2568 to be stored but got %v", store.Rules)
+		}
+	})
+
+	t.Run("given If-None-Match star for tax year with rules should return 412 with current rules", func(t *testing.T) {
+		body := `{"personalDeductionMin": 10000.0, "personalDeductionMax": 120000.0, "kReceiptMax": 100000.0, "donationMax": 100000.0}`
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfNoneMatch, "*")
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2567")
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateRules(c)
+
+		if res.Code != http.StatusPreconditionFailed || res.Header().Get(HeaderETag) != `"rules_2567.1"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusPreconditionFailed, `"rules_2567.1"`, res.Code, res.Header().Get(HeaderETag))
+		}
+	})
+
+	t.Run("given update rules without If-Match or If-None-Match should return 428", func(t *testing.T) {
+		body := `{"personalDeductionMin": 10000.0, "personalDeductionMax": 120000.0, "kReceiptMax": 100000.0, "donationMax": 100000.0}`
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2568")
+
+		store := NewMockStore()
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdateRules(c)
+
+		if res.Code != http.StatusPreconditionRequired {
+			t.Errorf("expected status %v but got status %v", http.StatusPreconditionRequired, res.Code)
+		}
+		if _, ok := store.Rules[2568]; ok {
+			t.Errorf("expected no rules to be created but got %v", store.Rules[2568])
+		}
+	})
+
+	t.Run("given get rules of tax year without rules should return 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("2568")
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.GetRules(c)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("expected status %v but got status %v", http.StatusNotFound, res.Code)
+		}
+	})
+
+	t.Run("given get rules with invalid tax year should return 400", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("year")
+		c.SetParamValues("latest")
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.GetRules(c)
+
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Code)
+		}
+	})
+
+	t.Run("given personal deduction above configured maximum should return 400 with configured bound", func(t *testing.T) {
+		store := NewMockStore()
+		rules := store.Rules[FilingTaxYear]
+		rules.PersonalDeductionMax = 80000.0
+		store.Rules[FilingTaxYear] = rules
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 90000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"personal_default.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdatePersonalDeduction(c)
+
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Code)
+		}
+		want := Err{Message: "Personal deduction must be within 80,000"}
+		var got Err
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given get allowance settings should return every setting with bounds and metadata", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderAcceptLanguage, "en")
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.GetAllowanceSettings(c)
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v", http.StatusOK, res.Code)
+		}
+		want := AllowanceSettingsResponse{Allowances: []AllowanceSettingResponse{
+			{Type: "personal_default", Amount: 60000.0, Min: 10000.0, Max: 100000.0, Unit: "THB", Description: "Personal deduction every taxpayer receives", ETag: `"personal_default.1"`},
+			{Type: "kreceipt_max", Amount: 50000.0, Min: 0.0, Max: 100000.0, Unit: "THB", Description: "Maximum k-receipt deduction a taxpayer can claim", ETag: `"kreceipt_max.1"`},
+		}}
+		var got AllowanceSettingsResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given update allowance setting by type should update the same setting as its alias", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"amount": 70000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("type")
+		c.SetParamValues("kreceipt_max")
+
+		store := NewMockStore()
+		handler := Handler{Service: &TaxService{Store: store}}
+		handler.UpdateAllowanceSetting(c)
+
+		if res.Code != http.StatusOK || res.Header().Get(HeaderETag) != `"kreceipt_max.2"` {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusOK, `"kreceipt_max.2"`, res.Code, res.Header().Get(HeaderETag))
+		}
+		var got AllowanceSettingResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if got.Amount != 70000.0 || store.MaxKReceipt != 70000.0 {
+			t.Errorf("expected k-receipt to be updated to 70000.0 but got %v", got)
+		}
+	})
+
+	t.Run("given update unknown allowance setting should return 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"amount": 70000.0}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"lottery.1"`)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+		c.SetParamNames("type")
+		c.SetParamValues("lottery")
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore()}}
+		handler.UpdateAllowanceSetting(c)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("expected status %v but got status %v", http.StatusNotFound, res.Code)
+		}
+	})
+
+	t.Run("given exported YAML rules imported as dry run should return no changes", func(t *testing.T) {
+		service := &TaxService{Store: NewMockStore(), RulesSigningKey: []byte("key")}
+		handler := Handler{Service: service}
+		exportReq := httptest.NewRequest(http.MethodGet, "/?format=yaml", nil)
+		exportRes := httptest.NewRecorder()
+		handler.ExportRules(echo.New().NewContext(exportReq, exportRes))
+
+		if exportRes.Code != http.StatusOK || exportRes.Header().Get(echo.HeaderContentType) != MIMEApplicationYAML {
+			t.Fatalf("expected YAML with status %v but got %v %v", http.StatusOK, exportRes.Code, exportRes.Header().Get(echo.HeaderContentType))
+		}
+		req := httptest.NewRequest(http.MethodPost, "/?dryRun=true", exportRes.Body)
+		req.Header.Set(echo.HeaderContentType, MIMEApplicationYAML)
+		res := httptest.NewRecorder()
+		handler.ImportRules(echo.New().NewContext(req, res))
+
+		if res.Code != http.StatusOK {
+			t.Errorf("expected status %v but got status %v with %v", http.StatusOK, res.Code, res.Body)
+		}
+		want := RulesImportResponse{TaxYear: 2567, DryRun: true, Applied: false, Changes: []RuleChangeResponse{}}
+		var got RulesImportResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+			t.Errorf("Unable to unmarshal json: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if etag := `"rules_2567.1+personal_default.1+kreceipt_max.1"`; res.Header().Get(HeaderETag) != etag {
+			t.Errorf("expected ETag %v but got %v", etag, res.Header().Get(HeaderETag))
+		}
+	})
+
+	t.Run("given import with ETag of a stale dry run should return 412 with current ETag", func(t *testing.T) {
+		store := NewMockStore()
+		service := &TaxService{Store: store, RulesSigningKey: []byte("key")}
+		handler := Handler{Service: service}
+		document, _ := service.ExportRules(context.Background(), 2567)
+		document.Allowances[SettingMaxKReceipt] = 40000.0
+		body, _ := json.Marshal(document.Sign([]byte("key")))
+		store.PersonalDeductionVersion = 2
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"rules_2567.1+personal_default.1+kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+		handler.ImportRules(echo.New().NewContext(req, res))
+
+		if etag := `"rules_2567.1+personal_default.2+kreceipt_max.1"`; res.Code != http.StatusPreconditionFailed || res.Header().Get(HeaderETag) != etag {
+			t.Errorf("expected status %v with ETag %v but got %v with %v", http.StatusPreconditionFailed, etag, res.Code, res.Header().Get(HeaderETag))
+		}
+		if store.MaxKReceipt != 50000.0 {
+			t.Errorf("expected k-receipt to be unchanged but got %v", store.MaxKReceipt)
+		}
+	})
+
+	t.Run("given import without If-Match should return 428", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore(), RulesSigningKey: []byte("key")}}
+		handler.ImportRules(echo.New().NewContext(req, res))
+
+		if res.Code != http.StatusPreconditionRequired {
+			t.Errorf("expected status %v but got status %v", http.StatusPreconditionRequired, res.Code)
+		}
+	})
+
+	t.Run("given export of tax year without rules should return 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?year=2568", nil)
+		res := httptest.NewRecorder()
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore(), RulesSigningKey: []byte("key")}}
+		handler.ExportRules(echo.New().NewContext(req, res))
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("expected status %v but got status %v", http.StatusNotFound, res.Code)
+		}
+	})
+
+	t.Run("given rules document with unknown field should return 400", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"format": 1, "taxYear": 2567, "brackets": [], "lottery": 1}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIfMatch, `"rules_2567.1+personal_default.1+kreceipt_max.1"`)
+		res := httptest.NewRecorder()
+
+		handler := Handler{Service: &TaxService{Store: NewMockStore(), RulesSigningKey: []byte("key")}}
+		handler.ImportRules(echo.New().NewContext(req, res))
+
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("expected status %v but got status %v", http.StatusBadRequest, res.Code)
+		}
+	})
 }
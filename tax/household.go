@@ -0,0 +1,63 @@
+package tax
+
+type FilingMode string
+
+const (
+	FilingModeSeparate FilingMode = "separate"
+	FilingModeJoint    FilingMode = "joint"
+)
+
+type HouseholdResult struct {
+	Taxpayer    Calulator
+	Spouse      Calulator
+	Joint       Calulator
+	SeparateTax float64
+	JointTax    float64
+	Recommended FilingMode
+}
+
+// SeparateCalculators returns the calculators for filing separately. A spouse
+// without income cannot use their personal allowance, so it is claimed by the
+// other spouse as the spouse allowance.
+func SeparateCalculators(taxpayer, spouse Calulator) (Calulator, Calulator) {
+	if spouse.TotalIncome <= 0 && taxpayer.TotalIncome > 0 {
+		taxpayer.AllowanceSpouse = spouse.GetAllowancePersonal()
+	}
+	if taxpayer.TotalIncome <= 0 && spouse.TotalIncome > 0 {
+		spouse.AllowanceSpouse = taxpayer.GetAllowancePersonal()
+	}
+	return taxpayer, spouse
+}
+
+// JointCalculator combines both spouses into one calculator for filing
+// jointly. Income and withholding tax are added up and each spouse keeps
+// their own allowances within their own caps, so the joint caps are the sum
+// of both.
+func JointCalculator(taxpayer, spouse Calulator) Calulator {
+	joint := taxpayer
+	joint.TotalIncome = taxpayer.TotalIncome + spouse.TotalIncome
+	joint.WitholdingTax = taxpayer.WitholdingTax + spouse.WitholdingTax
+	joint.AllowancePersonal = taxpayer.GetAllowancePersonal() + spouse.GetAllowancePersonal()
+	joint.MaxAllowancePersonal = taxpayer.MaxAllowancePersonal + spouse.MaxAllowancePersonal
+	joint.AllowanceDonation = taxpayer.GetAllowanceDonation() + spouse.GetAllowanceDonation()
+	joint.MaxAllowanceDonation = taxpayer.MaxAllowanceDonation + spouse.MaxAllowanceDonation
+	joint.AllowanceKReceipt = taxpayer.GetAllowanceKReceipt() + spouse.GetAllowanceKReceipt()
+	joint.MaxAllowanceKReceipt = taxpayer.MaxAllowanceKReceipt + spouse.MaxAllowanceKReceipt
+	joint.AllowanceSpouse = 0
+	return joint
+}
+
+// CalculateHousehold computes both filing options for a married couple and
+// recommends the one with less tax to pay, preferring separate filing on a tie.
+func CalculateHousehold(taxpayer, spouse Calulator) HouseholdResult {
+	result := HouseholdResult{Joint: JointCalculator(taxpayer, spouse)}
+	result.Taxpayer, result.Spouse = SeparateCalculators(taxpayer, spouse)
+	result.SeparateTax = result.Taxpayer.CalculateTaxResult().Amount + result.Spouse.CalculateTaxResult().Amount
+	result.JointTax = result.Joint.CalculateTaxResult().Amount
+
+	result.Recommended = FilingModeSeparate
+	if result.JointTax < result.SeparateTax {
+		result.Recommended = FilingModeJoint
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package tax
+
+import "testing"
+
+func TestHousehold(t *testing.T) {
+	t.Run("given taxpayer income 500000.0 and spouse without income should claim spouse allowance and recommend separate", func(t *testing.T) {
+		taxpayer := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxpayer.TotalIncome = 500000.00
+		spouse := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		got := CalculateHousehold(taxpayer, spouse)
+
+		if got.Taxpayer.GetAllowanceSpouse() != 60000.00 {
+			t.Errorf("expect spouse allowance = %v but got %v", 60000.00, got.Taxpayer.GetAllowanceSpouse())
+		}
+		if got.SeparateTax != 23000.00 || got.JointTax != 23000.00 {
+			t.Errorf("expect separate and joint tax 23000.0 but got %v and %v", got.SeparateTax, got.JointTax)
+		}
+		if got.Recommended != FilingModeSeparate {
+			t.Errorf("expect recommendation %v but got %v", FilingModeSeparate, got.Recommended)
+		}
+	})
+
+	t.Run("given both spouses income 1000000.0 should recommend separate filing", func(t *testing.T) {
+		taxpayer := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxpayer.TotalIncome = 1000000.00
+		spouse := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		spouse.TotalIncome = 1000000.00
+
+		got := CalculateHousehold(taxpayer, spouse)
+
+		if got.SeparateTax != 187000.00 || got.JointTax != 246000.00 {
+			t.Errorf("expect separate tax 187000.0 and joint tax 246000.0 but got %v and %v", got.SeparateTax, got.JointTax)
+		}
+		if got.Recommended != FilingModeSeparate {
+			t.Errorf("expect recommendation %v but got %v", FilingModeSeparate, got.Recommended)
+		}
+	})
+
+	t.Run("given both spouses with donations should keep each donation cap in joint filing", func(t *testing.T) {
+		taxpayer := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxpayer.AllowanceDonation = 150000.00
+		spouse := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		spouse.AllowanceDonation = 50000.00
+
+		got := JointCalculator(taxpayer, spouse)
+
+		if got.GetAllowanceDonation() != 150000.00 || got.GetAllowancePersonal() != 120000.00 {
+			t.Errorf("expect joint donation 150000.0 and personal 120000.0 but got %v and %v", got.GetAllowanceDonation(), got.GetAllowancePersonal())
+		}
+	})
+}
@@ -0,0 +1,360 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderAcceptLanguage = "Accept-Language"
+
+type Language string
+
+const (
+	LanguageThai    Language = "th"
+	LanguageEnglish Language = "en"
+)
+
+type MessageKey string
+
+const (
+	MsgUnknownAllowanceType     MessageKey = "unknown_allowance_type"
+	MsgPersonalDeductionTooHigh MessageKey = "personal_deduction_too_high"
+	MsgPersonalDeductionTooLow  MessageKey = "personal_deduction_too_low"
+	MsgKReceiptTooHigh          MessageKey = "k_receipt_too_high"
+	MsgKReceiptTooLow           MessageKey = "k_receipt_too_low"
+	MsgInvalidCsvValue          MessageKey = "invalid_csv_value"
+	MsgInvalidCsvRecord         MessageKey = "invalid_csv_record"
+	MsgLevelRange               MessageKey = "level_range"
+	MsgLevelAbove               MessageKey = "level_above"
+	MsgAllowancePersonal        MessageKey = "allowance_personal"
+	MsgAllowanceDonation        MessageKey = "allowance_donation"
+	MsgAllowanceKReceipt        MessageKey = "allowance_k_receipt"
+	MsgAllowanceSpouse          MessageKey = "allowance_spouse"
+	MsgReportFontMissing        MessageKey = "report_font_missing"
+	MsgReportTitle              MessageKey = "report_title"
+	MsgReportTotalIncome        MessageKey = "report_total_income"
+	MsgReportAllowance          MessageKey = "report_allowance"
+	MsgReportClaimed            MessageKey = "report_claimed"
+	MsgReportApplied            MessageKey = "report_applied"
+	MsgReportTotalAllowances    MessageKey = "report_total_allowances"
+	MsgReportTaxableIncome      MessageKey = "report_taxable_income"
+	MsgReportLevel              MessageKey = "report_level"
+	MsgReportRate               MessageKey = "report_rate"
+	MsgReportTax                MessageKey = "report_tax"
+	MsgReportTotalTax           MessageKey = "report_total_tax"
+	MsgReportWitholdingTax      MessageKey = "report_witholding_tax"
+	MsgReportTaxPayable         MessageKey = "report_tax_payable"
+	MsgReportTaxRefund          MessageKey = "report_tax_refund"
+	MsgUnknownIncomeCategory    MessageKey = "unknown_income_category"
+	MsgUnknownFilingFormat      MessageKey = "unknown_filing_format"
+	MsgInverseTargetRequired    MessageKey = "inverse_target_required"
+	MsgInverseTargetNegative    MessageKey = "inverse_target_negative"
+	MsgWithholdingMonths        MessageKey = "withholding_months"
+	MsgNegativeMonthlyIncome    MessageKey = "negative_monthly_income"
+	MsgScenariosRequired        MessageKey = "scenarios_required"
+	MsgScenarioInvalid          MessageKey = "scenario_invalid"
+	MsgAdviceBudgetNegative     MessageKey = "advice_budget_negative"
+	MsgCsvQuotaExceeded         MessageKey = "csv_quota_exceeded"
+	MsgIfMatchRequired          MessageKey = "if_match_required"
+	MsgSettingModified          MessageKey = "setting_modified"
+	MsgRuleNegative             MessageKey = "rule_negative"
+	MsgRuleRange                MessageKey = "rule_range"
+	MsgRulesModified            MessageKey = "rules_modified"
+	MsgUnknownAllowanceSetting  MessageKey = "unknown_allowance_setting"
+	MsgSettingPersonalDeduction MessageKey = "setting_personal_deduction"
+	MsgSettingMaxKReceipt       MessageKey = "setting_max_k_receipt"
+	MsgRulesSigningKeyMissing   MessageKey = "rules_signing_key_missing"
+	MsgRulesFormat              MessageKey = "rules_format"
+	MsgRulesChecksumInvalid     MessageKey = "rules_checksum_invalid"
+	MsgRulesBracketsFixed       MessageKey = "rules_brackets_fixed"
+	MsgRulesAllowanceMissing    MessageKey = "rules_allowance_missing"
+	MsgUnknownRulesFormat       MessageKey = "unknown_rules_format"
+	MsgInverseTargetTooLarge    MessageKey = "inverse_target_too_large"
+	MsgRulesNotFound            MessageKey = "rules_not_found"
+	MsgInvalidTaxYear           MessageKey = "invalid_tax_year"
+	MsgRulesExist               MessageKey = "rules_exist"
+	MsgRulesImportModified      MessageKey = "rules_import_modified"
+	MsgRulesImportETagRequired  MessageKey = "rules_import_etag_required"
+	MsgReportFontNotThai        MessageKey = "report_font_not_thai"
+)
+
+type Locale struct {
+	Language Language
+	Messages map[MessageKey]string
+}
+
+var englishMessages = map[MessageKey]string{
+	MsgUnknownAllowanceType:     "Unknown allowance type: %s",
+	MsgPersonalDeductionTooHigh: "Personal deduction must be within %s",
+	MsgPersonalDeductionTooLow:  "Personal deduction must be more than %s",
+	MsgKReceiptTooHigh:          "k-receipt deduction must be within %s",
+	MsgKReceiptTooLow:           "k-receipt deduction must be more than %s",
+	MsgInvalidCsvValue:          "Invalid %s value %q in row %d",
+	MsgInvalidCsvRecord:         "Row %d must have %d columns",
+	MsgLevelRange:               "%s - %s",
+	MsgLevelAbove:               "%s and above",
+	MsgAllowancePersonal:        "Personal allowance",
+	MsgAllowanceDonation:        "Donation",
+	MsgAllowanceKReceipt:        "k-receipt",
+	MsgAllowanceSpouse:          "Spouse allowance",
+	MsgReportFontMissing:        "Report font is not configured",
+	MsgReportTitle:              "Personal Income Tax Summary",
+	MsgReportTotalIncome:        "Total income",
+	MsgReportAllowance:          "Allowance",
+	MsgReportClaimed:            "Claimed",
+	MsgReportApplied:            "Applied",
+	MsgReportTotalAllowances:    "Total allowances",
+	MsgReportTaxableIncome:      "Net taxable income",
+	MsgReportLevel:              "Income level",
+	MsgReportRate:               "Tax rate",
+	MsgReportTax:                "Tax",
+	MsgReportTotalTax:           "Total tax",
+	MsgReportWitholdingTax:      "Withholding tax",
+	MsgReportTaxPayable:         "Tax payable",
+	MsgReportTaxRefund:          "Tax refund",
+	MsgUnknownIncomeCategory:    "Unknown income category: %s, expected 40(1) to 40(8)",
+	MsgUnknownFilingFormat:      "Unknown export format: %s, expected json or xml",
+	MsgInverseTargetRequired:    "Exactly one of targetNetIncome or targetTax is required",
+	MsgInverseTargetNegative:    "Target amount must not be negative",
+	MsgWithholdingMonths:        "Monthly incomes must have between 1 and %d months",
+	MsgNegativeMonthlyIncome:    "Income of month %d must not be negative",
+	MsgScenariosRequired:        "At least one scenario is required",
+	MsgScenarioInvalid:          "Scenario %s: %s",
+	MsgAdviceBudgetNegative:     "Budget must not be negative",
+	MsgCsvQuotaExceeded:         "Daily quota of CSV rows exceeded",
+	MsgIfMatchRequired:          "If-Match header with the ETag of the setting is required",
+	MsgSettingModified:          "Setting was modified by another request, current value is %s",
+	MsgRuleNegative:             "%s must not be negative",
+	MsgRuleRange:                "%s must be less than %s",
+	MsgRulesModified:            "Rule set was modified by another request",
+	MsgUnknownAllowanceSetting:  "Unknown allowance setting: %s",
+	MsgSettingPersonalDeduction: "Personal deduction every taxpayer receives",
+	MsgSettingMaxKReceipt:       "Maximum k-receipt deduction a taxpayer can claim",
+	MsgRulesSigningKeyMissing:   "Rules signing key is not configured",
+	MsgRulesFormat:              "Unsupported rules document format %d",
+	MsgRulesChecksumInvalid:     "Checksum does not match, the document was modified or signed with another key",
+	MsgRulesBracketsFixed:       "Tax brackets cannot be changed by import",
+	MsgRulesAllowanceMissing:    "Allowance setting %s is missing",
+	MsgUnknownRulesFormat:       "Unknown rules format: %s, expected json or yaml",
+	MsgInverseTargetTooLarge:    "Target amount must be a number within %s",
+	MsgRulesNotFound:            "No rule set for tax year %d",
+	MsgInvalidTaxYear:           "Tax year must be a positive whole number, got %q",
+	MsgRulesExist:               "Rule set of tax year %d already exists",
+	MsgRulesImportModified:      "Rules were modified after the dry run, review the differences again",
+	MsgRulesImportETagRequired:  "If-Match header with the ETag of the dry run is required",
+	MsgReportFontNotThai:        "Report font has no glyphs for %d Thai characters: %s",
+}
+
+var thaiMessages = map[MessageKey]string{
+	MsgUnknownAllowanceType:     "ไม่รู้จักประเภทค่าลดหย่อน: %s",
+	MsgPersonalDeductionTooHigh: "ค่าลดหย่อนส่วนตัวต้องไม่เกิน %s บาท",
+	MsgPersonalDeductionTooLow:  "ค่าลดหย่อนส่วนตัวต้องมากกว่า %s บาท",
+	MsgKReceiptTooHigh:          "ค่าลดหย่อน k-receipt ต้องไม่เกิน %s บาท",
+	MsgKReceiptTooLow:           "ค่าลดหย่อน k-receipt ต้องมากกว่า %s บาท",
+	MsgInvalidCsvValue:          "ค่า %s ไม่ถูกต้อง %q ในแถวที่ %d",
+	MsgInvalidCsvRecord:         "แถวที่ %d ต้องมี %d คอลัมน์",
+	MsgLevelRange:               "%s - %s",
+	MsgLevelAbove:               "%s ขึ้นไป",
+	MsgAllowancePersonal:        "ค่าลดหย่อนส่วนตัว",
+	MsgAllowanceDonation:        "เงินบริจาค",
+	MsgAllowanceKReceipt:        "ช้อปลดภาษี (k-receipt)",
+	MsgAllowanceSpouse:          "ค่าลดหย่อนคู่สมรส",
+	MsgReportFontMissing:        "ยังไม่ได้ตั้งค่าฟอนต์สำหรับรายงาน",
+	MsgReportTitle:              "สรุปการคำนวณภาษีเงินได้บุคคลธรรมดา",
+	MsgReportTotalIncome:        "เงินได้ทั้งหมด",
+	MsgReportAllowance:          "ค่าลดหย่อน",
+	MsgReportClaimed:            "ยื่นขอ",
+	MsgReportApplied:            "ใช้สิทธิ์ได้",
+	MsgReportTotalAllowances:    "รวมค่าลดหย่อน",
+	MsgReportTaxableIncome:      "เงินได้สุทธิ",
+	MsgReportLevel:              "ขั้นเงินได้",
+	MsgReportRate:               "อัตราภาษี",
+	MsgReportTax:                "ภาษี",
+	MsgReportTotalTax:           "ภาษีที่คำนวณได้",
+	MsgReportWitholdingTax:      "ภาษีหัก ณ ที่จ่าย",
+	MsgReportTaxPayable:         "ภาษีที่ต้องชำระเพิ่ม",
+	MsgReportTaxRefund:          "ภาษีที่ได้รับคืน",
+	MsgUnknownIncomeCategory:    "ไม่รู้จักประเภทเงินได้: %s ต้องเป็น 40(1) ถึง 40(8)",
+	MsgUnknownFilingFormat:      "ไม่รู้จักรูปแบบไฟล์: %s ต้องเป็น json หรือ xml",
+	MsgInverseTargetRequired:    "ต้องระบุ targetNetIncome หรือ targetTax อย่างใดอย่างหนึ่ง",
+	MsgInverseTargetNegative:    "จำนวนเงินเป้าหมายต้องไม่ติดลบ",
+	MsgWithholdingMonths:        "เงินได้รายเดือนต้องมี 1 ถึง %d เดือน",
+	MsgNegativeMonthlyIncome:    "เงินได้ของเดือนที่ %d ต้องไม่ติดลบ",
+	MsgScenariosRequired:        "ต้องระบุอย่างน้อยหนึ่งสถานการณ์",
+	MsgScenarioInvalid:          "สถานการณ์ %s: %s",
+	MsgAdviceBudgetNegative:     "งบประมาณต้องไม่ติดลบ",
+	MsgCsvQuotaExceeded:         "เกินโควตาจำนวนแถว CSV ต่อวัน",
+	MsgIfMatchRequired:          "ต้องระบุ ETag ของการตั้งค่าใน header If-Match",
+	MsgSettingModified:          "การตั้งค่าถูกแก้ไขโดยคำขออื่นแล้ว ค่าปัจจุบันคือ %s บาท",
+	MsgRuleNegative:             "%s ต้องไม่ติดลบ",
+	MsgRuleRange:                "%s ต้องน้อยกว่า %s",
+	MsgRulesModified:            "ชุดกฎถูกแก้ไขโดยคำขออื่นแล้ว",
+	MsgUnknownAllowanceSetting:  "ไม่รู้จักการตั้งค่าค่าลดหย่อน: %s",
+	MsgSettingPersonalDeduction: "ค่าลดหย่อนส่วนตัวที่ผู้เสียภาษีทุกคนได้รับ",
+	MsgSettingMaxKReceipt:       "ค่าลดหย่อน k-receipt สูงสุดที่ผู้เสียภาษีใช้สิทธิ์ได้",
+	MsgRulesSigningKeyMissing:   "ยังไม่ได้ตั้งค่ากุญแจสำหรับลงนามชุดกฎ",
+	MsgRulesFormat:              "ไม่รองรับรูปแบบเอกสารชุดกฎ %d",
+	MsgRulesChecksumInvalid:     "checksum ไม่ตรงกัน เอกสารถูกแก้ไขหรือลงนามด้วยกุญแจอื่น",
+	MsgRulesBracketsFixed:       "ไม่สามารถเปลี่ยนขั้นเงินได้ด้วยการนำเข้า",
+	MsgRulesAllowanceMissing:    "ไม่พบการตั้งค่าค่าลดหย่อน %s",
+	MsgUnknownRulesFormat:       "ไม่รู้จักรูปแบบชุดกฎ: %s ต้องเป็น json หรือ yaml",
+	MsgInverseTargetTooLarge:    "จำนวนเงินเป้าหมายต้องเป็นตัวเลขที่ไม่เกิน %s บาท",
+	MsgRulesNotFound:            "ไม่พบชุดกฎของปีภาษี %d",
+	MsgInvalidTaxYear:           "ปีภาษีต้องเป็นจำนวนเต็มบวก แต่ได้รับ %q",
+	MsgRulesExist:               "มีชุดกฎของปีภาษี %d อยู่แล้ว",
+	MsgRulesImportModified:      "ชุดกฎถูกแก้ไขหลังจากการทดลองนำเข้า กรุณาตรวจสอบความแตกต่างอีกครั้ง",
+	MsgRulesImportETagRequired:  "ต้องระบุ ETag ของการทดลองนำเข้าใน header If-Match",
+	MsgReportFontNotThai:        "ฟอนต์สำหรับรายงานไม่มีตัวอักษรไทย %d ตัว: %s",
+}
+
+var locales = map[Language]Locale{
+	LanguageThai: {
+		Language: LanguageThai,
+		Messages: thaiMessages,
+	},
+	LanguageEnglish: {
+		Language: LanguageEnglish,
+		Messages: englishMessages,
+	},
+}
+
+// DefaultLocale is used when the client does not ask for a supported language.
+// It keeps the original level labels and English messages so existing clients
+// see the same responses as before.
+var DefaultLocale = Locale{
+	Messages: englishMessages,
+}
+
+func GetLocale(language Language) Locale {
+	if locale, ok := locales[language]; ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// ParseAcceptLanguage returns the supported language with the highest quality
+// value in an Accept-Language header, or an empty language if none match.
+func ParseAcceptLanguage(header string) Language {
+	type candidate struct {
+		language Language
+		quality  float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		base := Language(strings.SplitN(tag, "-", 2)[0])
+		if _, ok := locales[base]; ok {
+			candidates = append(candidates, candidate{language: base, quality: quality})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].language
+}
+
+func LocaleFromContext(c echo.Context) Locale {
+	return GetLocale(ParseAcceptLanguage(c.Request().Header.Get(HeaderAcceptLanguage)))
+}
+
+// Message renders a catalogue entry, formatting float64 arguments as amounts.
+func (l Locale) Message(key MessageKey, args ...interface{}) string {
+	format, ok := l.Messages[key]
+	if !ok {
+		format = englishMessages[key]
+	}
+	formatted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if amount, ok := arg.(float64); ok {
+			formatted[i] = formatNumber(amount)
+		} else {
+			formatted[i] = arg
+		}
+	}
+	return fmt.Sprintf(format, formatted...)
+}
+
+// formatNumber formats an amount with comma grouping and a dot decimal
+// separator, omitting the fraction for whole amounts. Thai and English
+// documents write amounts the same way.
+func formatNumber(value float64) string {
+	negative := value < 0
+	value = math.Abs(value)
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	integer, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(",")
+		}
+		grouped.WriteRune(digit)
+	}
+	result := grouped.String()
+	if fraction != "00" {
+		result = result + "." + fraction
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+func (l Locale) LevelLabel(level Level) string {
+	if l.Language == "" {
+		return level.Level
+	}
+	if level.MaxAmount == math.MaxFloat64 {
+		return l.Message(MsgLevelAbove, level.MinAmount)
+	}
+	return l.Message(MsgLevelRange, level.MinAmount, level.MaxAmount)
+}
+
+// LocalizedError is an error whose message can be rendered in the client's
+// language.
+type LocalizedError struct {
+	Key  MessageKey
+	Args []interface{}
+}
+
+func NewLocalizedError(key MessageKey, args ...interface{}) *LocalizedError {
+	return &LocalizedError{Key: key, Args: args}
+}
+
+func (e *LocalizedError) Error() string {
+	return DefaultLocale.Message(e.Key, e.Args...)
+}
+
+func (l Locale) ErrorMessage(err error) string {
+	var localized *LocalizedError
+	if errors.As(err, &localized) {
+		return l.Message(localized.Key, localized.Args...)
+	}
+	return err.Error()
+}
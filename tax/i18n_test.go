@@ -0,0 +1,82 @@
+package tax
+
+import (
+	"testing"
+)
+
+func TestLocalization(t *testing.T) {
+	t.Run("given accept language th-TH,en;q=0.8 should return thai", func(t *testing.T) {
+		got := ParseAcceptLanguage("th-TH,en;q=0.8")
+		if got != LanguageThai {
+			t.Errorf("expected %v but got %v", LanguageThai, got)
+		}
+	})
+
+	t.Run("given accept language th;q=0.5,en-US should return english", func(t *testing.T) {
+		got := ParseAcceptLanguage("th;q=0.5,en-US")
+		if got != LanguageEnglish {
+			t.Errorf("expected %v but got %v", LanguageEnglish, got)
+		}
+	})
+
+	t.Run("given unsupported accept language should return empty language", func(t *testing.T) {
+		got := ParseAcceptLanguage("fr-FR,de;q=0.9")
+		if got != "" {
+			t.Errorf("expected empty language but got %v", got)
+		}
+	})
+
+	t.Run("given amount 2000001.5 should format with grouping and fraction", func(t *testing.T) {
+		got := formatNumber(2000001.5)
+		want := "2,000,001.50"
+		if got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given amount 2000001.5 in each locale should format it the same way", func(t *testing.T) {
+		cases := []struct {
+			language Language
+			want     string
+		}{
+			{LanguageThai, "จำนวนเงินเป้าหมายต้องเป็นตัวเลขที่ไม่เกิน 2,000,001.50 บาท"},
+			{LanguageEnglish, "Target amount must be a number within 2,000,001.50"},
+		}
+		for _, tc := range cases {
+			got := GetLocale(tc.language).Message(MsgInverseTargetTooLarge, 2000001.5)
+			if got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		}
+	})
+
+	t.Run("given each locale should return level labels in that language", func(t *testing.T) {
+		levels := CreateLevels()
+		cases := []struct {
+			language Language
+			want     []string
+		}{
+			{"", []string{"0 - 150,000", "150,001 - 500,000", "500,001 - 1,000,000", "1,000,001 - 2,000,000", "2,000,001 ขึ้นไป"}},
+			{LanguageThai, []string{"0 - 150,000", "150,001 - 500,000", "500,001 - 1,000,000", "1,000,001 - 2,000,000", "2,000,001 ขึ้นไป"}},
+			{LanguageEnglish, []string{"0 - 150,000", "150,001 - 500,000", "500,001 - 1,000,000", "1,000,001 - 2,000,000", "2,000,001 and above"}},
+		}
+		for _, tc := range cases {
+			locale := GetLocale(tc.language)
+			for index, level := range levels {
+				if got := locale.LevelLabel(level); got != tc.want[index] {
+					t.Errorf("expected %v but got %v", tc.want[index], got)
+				}
+			}
+		}
+	})
+
+	t.Run("given localized error should render message in requested language", func(t *testing.T) {
+		err := NewLocalizedError(MsgPersonalDeductionTooHigh, 100000.0)
+		if got, want := err.Error(), "Personal deduction must be within 100,000"; got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+		if got, want := GetLocale(LanguageThai).ErrorMessage(err), "ค่าลดหย่อนส่วนตัวต้องไม่เกิน 100,000 บาท"; got != want {
+			t.Errorf("expected %v but got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package tax
+
+import "math"
+
+type InverseTarget string
+
+const (
+	InverseTargetNetIncome InverseTarget = "netIncome"
+	InverseTargetTax       InverseTarget = "tax"
+)
+
+// MaxInverseTotalIncome is the largest total income SolveTotalIncome
+// searches. Targets that need more are rejected rather than searched for.
+const MaxInverseTotalIncome = 1000000000000.00
+
+// inverseIterations bounds the doubling and bisection loops of
+// SolveTotalIncome. Bisecting MaxInverseTotalIncome down to half a satang
+// takes about 48 iterations.
+const inverseIterations = 100
+
+func (t *Calulator) inverseValue(target InverseTarget, totalIncome float64) float64 {
+	tax := t.CalculateTotalTaxFor(totalIncome)
+	if target == InverseTargetNetIncome {
+		return totalIncome - tax
+	}
+	return tax
+}
+
+// ValidateInverseTarget returns an error if amount is negative, not finite or
+// more than any total income SolveTotalIncome searches could give.
+func ValidateInverseTarget(amount float64) error {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount > MaxInverseTotalIncome {
+		return NewLocalizedError(MsgInverseTargetTooLarge, MaxInverseTotalIncome)
+	}
+	if amount < 0 {
+		return NewLocalizedError(MsgInverseTargetNegative)
+	}
+	return nil
+}
+
+// SolveTotalIncome finds the smallest total income, rounded up to the satang,
+// whose net income after tax or whose tax (before withholding tax) reaches the
+// target amount with the calculator's allowances. Both values never decrease
+// as income grows, so the answer is found by bisection.
+func (t *Calulator) SolveTotalIncome(target InverseTarget, amount float64) (float64, error) {
+	if target != InverseTargetNetIncome && target != InverseTargetTax {
+		return 0, NewLocalizedError(MsgInverseTargetRequired)
+	}
+	if err := ValidateInverseTarget(amount); err != nil {
+		return 0, err
+	}
+	if t.inverseValue(target, 0) >= amount {
+		return 0, nil
+	}
+	if t.inverseValue(target, MaxInverseTotalIncome) < amount {
+		return 0, NewLocalizedError(MsgInverseTargetTooLarge, MaxInverseTotalIncome)
+	}
+
+	low, high := 0.0, math.Max(amount, 1)
+	for i := 0; i < inverseIterations && high < MaxInverseTotalIncome && t.inverseValue(target, high) < amount; i++ {
+		low = high
+		high = math.Min(high*2, MaxInverseTotalIncome)
+	}
+	for i := 0; i < inverseIterations && high-low > 0.005; i++ {
+		middle := (low + high) / 2
+		if t.inverseValue(target, middle) >= amount {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	// The answer is in (low, high], which holds at most one satang, so it is
+	// either the first satang from low or the one after.
+	income := math.Ceil(low*100) / 100
+	if t.inverseValue(target, income) < amount {
+		income = income + 0.01
+	}
+	return math.Round(income*100) / 100, nil
+}
@@ -0,0 +1,91 @@
+package tax
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInverseCalculation(t *testing.T) {
+	t.Run("given target tax 29000.0 should return total income 500000.0", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		got, err := taxCalulator.SolveTotalIncome(InverseTargetTax, 29000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if want := 500000.00; got != want {
+			t.Errorf("expect total income = %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given target net income 471000.0 should return total income 500000.0", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		got, err := taxCalulator.SolveTotalIncome(InverseTargetNetIncome, 471000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if want := 500000.00; got != want {
+			t.Errorf("expect total income = %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given target net income 500000.0 and donation 200000.0 should return income that nets the target", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.AllowanceDonation = 200000.00
+
+		got, err := taxCalulator.SolveTotalIncome(InverseTargetNetIncome, 500000.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if want := 521111.12; got != want {
+			t.Errorf("expect total income = %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given target tax 0.0 should return total income 0.0", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		got, err := taxCalulator.SolveTotalIncome(InverseTargetTax, 0.00)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if got != 0.00 {
+			t.Errorf("expect total income = %v but got %v", 0.00, got)
+		}
+	})
+
+	t.Run("given negative target should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		_, err := taxCalulator.SolveTotalIncome(InverseTargetTax, -1.00)
+		if err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+
+	t.Run("given huge or non-finite targets should return error without searching forever", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		for _, amount := range []float64{1e15, 1e308, math.Inf(1), math.NaN()} {
+			for _, target := range []InverseTarget{InverseTargetTax, InverseTargetNetIncome} {
+				if got, err := taxCalulator.SolveTotalIncome(target, amount); err == nil {
+					t.Errorf("expect error for %v %v but got total income %v", target, amount, got)
+				}
+			}
+		}
+	})
+
+	t.Run("given target tax just below the maximum income's tax should return income within the maximum", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		amount := taxCalulator.CalculateTotalTaxFor(MaxInverseTotalIncome) - 1
+
+		got, err := taxCalulator.SolveTotalIncome(InverseTargetTax, amount)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if got > MaxInverseTotalIncome || taxCalulator.CalculateTotalTaxFor(got) < amount {
+			t.Errorf("expect total income within %v reaching tax %v but got %v", MaxInverseTotalIncome, amount, got)
+		}
+	})
+}
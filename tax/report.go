@@ -0,0 +1,156 @@
+package tax
+
+import (
+	"bytes"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const reportFontFamily = "report"
+
+type ReportAllowance struct {
+	Type    string
+	Claimed float64
+	Applied float64
+}
+
+type ReportLevel struct {
+	Level             string
+	TaxRatePercentage float64
+	Amount            float64
+}
+
+// Report is the data shown on the printable tax summary.
+type Report struct {
+	TotalIncome     float64
+	Allowances      []ReportAllowance
+	TotalAllowances float64
+	TaxableIncome   float64
+	Levels          []ReportLevel
+	TotalTax        float64
+	WitholdingTax   float64
+	TaxPayable      float64
+	TaxRefund       float64
+}
+
+func NewReport(calculator Calulator, locale Locale) Report {
+	result := calculator.CalculateTaxResult()
+	report := Report{
+		TotalIncome: calculator.TotalIncome,
+		Allowances: []ReportAllowance{
+			{Type: locale.Message(MsgAllowancePersonal), Claimed: calculator.AllowancePersonal, Applied: calculator.GetAllowancePersonal()},
+			{Type: locale.Message(MsgAllowanceDonation), Claimed: calculator.AllowanceDonation, Applied: calculator.GetAllowanceDonation()},
+			{Type: locale.Message(MsgAllowanceKReceipt), Claimed: calculator.AllowanceKReceipt, Applied: calculator.GetAllowanceKReceipt()},
+		},
+		TaxableIncome: calculator.CalculateIncomeAfterAllowances(),
+		TotalTax:      result.TotalTax,
+		WitholdingTax: calculator.WitholdingTax,
+	}
+	if calculator.AllowanceSpouse > 0 {
+		report.Allowances = append(report.Allowances, ReportAllowance{
+			Type: locale.Message(MsgAllowanceSpouse), Claimed: calculator.AllowanceSpouse, Applied: calculator.GetAllowanceSpouse(),
+		})
+	}
+	for _, allowance := range report.Allowances {
+		report.TotalAllowances = report.TotalAllowances + allowance.Applied
+	}
+	for index, level := range result.LevelAmounts {
+		report.Levels = append(report.Levels, ReportLevel{
+			Level:             locale.LevelLabel(calculator.Levels[index]),
+			TaxRatePercentage: calculator.Levels[index].TaxRatePercentage,
+			Amount:            level.Amount,
+		})
+	}
+	if result.Amount < 0 {
+		report.TaxRefund = -result.Amount
+	} else {
+		report.TaxPayable = result.Amount
+	}
+	return report
+}
+
+// CheckReportFont returns an error if a Thai report cannot be rendered with
+// the font, so a missing or broken font is found at startup rather than by
+// the first report request. The font must map every Thai character to a
+// glyph, as the PDF would silently leave out the ones it does not.
+func CheckReportFont(font []byte) error {
+	locale := GetLocale(LanguageThai)
+	if _, err := NewReport(NewTaxCalulator(60000.00, 50000.00), locale).RenderPDF(font, locale); err != nil {
+		return err
+	}
+	if missing := missingGlyphs(font, thaiSample); len(missing) > 0 {
+		return NewLocalizedError(MsgReportFontNotThai, len(missing), string(missing))
+	}
+	return nil
+}
+
+// RenderPDF renders the report with the given TrueType font embedded in the
+// document. Without a font only the built-in Latin fonts are available, so
+// Thai reports require one.
+func (r Report) RenderPDF(font []byte, locale Locale) ([]byte, error) {
+	if len(font) == 0 && locale.Language == LanguageThai {
+		return nil, NewLocalizedError(MsgReportFontMissing)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(locale.Message(MsgReportTitle), true)
+	fontFamily := "Helvetica"
+	if len(font) > 0 {
+		pdf.AddUTF8FontFromBytes(reportFontFamily, "", font)
+		fontFamily = reportFontFamily
+	}
+	pdf.AddPage()
+
+	pdf.SetFont(fontFamily, "", 16)
+	pdf.CellFormat(0, 10, locale.Message(MsgReportTitle), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "", 11)
+	row := func(label, value string) {
+		pdf.CellFormat(120, 8, label, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(60, 8, value, "1", 1, "R", false, 0, "")
+	}
+	header := func(columns ...string) {
+		widths := []float64{80, 50, 50}
+		pdf.SetFillColor(230, 230, 230)
+		for index, column := range columns {
+			pdf.CellFormat(widths[index], 8, column, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	row(locale.Message(MsgReportTotalIncome), formatNumber(r.TotalIncome))
+	pdf.Ln(4)
+
+	header(locale.Message(MsgReportAllowance), locale.Message(MsgReportClaimed), locale.Message(MsgReportApplied))
+	for _, allowance := range r.Allowances {
+		pdf.CellFormat(80, 8, allowance.Type, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 8, formatNumber(allowance.Claimed), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(50, 8, formatNumber(allowance.Applied), "1", 1, "R", false, 0, "")
+	}
+	row(locale.Message(MsgReportTotalAllowances), formatNumber(r.TotalAllowances))
+	row(locale.Message(MsgReportTaxableIncome), formatNumber(r.TaxableIncome))
+	pdf.Ln(4)
+
+	header(locale.Message(MsgReportLevel), locale.Message(MsgReportRate), locale.Message(MsgReportTax))
+	for _, level := range r.Levels {
+		pdf.CellFormat(80, 8, level.Level, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 8, formatNumber(level.TaxRatePercentage)+"%", "1", 0, "R", false, 0, "")
+		pdf.CellFormat(50, 8, formatNumber(level.Amount), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	row(locale.Message(MsgReportTotalTax), formatNumber(r.TotalTax))
+	row(locale.Message(MsgReportWitholdingTax), formatNumber(r.WitholdingTax))
+	if r.TaxRefund > 0 {
+		row(locale.Message(MsgReportTaxRefund), formatNumber(r.TaxRefund))
+	} else {
+		row(locale.Message(MsgReportTaxPayable), formatNumber(r.TaxPayable))
+	}
+
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
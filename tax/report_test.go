@@ -0,0 +1,140 @@
+package tax
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	t.Run("given total income 500000.0 wht 25000.0 donation 200000.0 should return report with claimed and applied allowances", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		taxCalulator.WitholdingTax = 25000.00
+		taxCalulator.AllowanceDonation = 200000.00
+
+		got := NewReport(taxCalulator, GetLocale(LanguageEnglish))
+
+		wantAllowances := []ReportAllowance{
+			{Type: "Personal allowance", Claimed: 60000.00, Applied: 60000.00},
+			{Type: "Donation", Claimed: 200000.00, Applied: 100000.00},
+			{Type: "k-receipt", Claimed: 0.00, Applied: 0.00},
+		}
+		if !reflect.DeepEqual(wantAllowances, got.Allowances) {
+			t.Errorf("expected %v but got %v", wantAllowances, got.Allowances)
+		}
+		if got.TotalAllowances != 160000.00 {
+			t.Errorf("expect total allowances = %v but got %v", 160000.00, got.TotalAllowances)
+		}
+		if got.TaxableIncome != 340000.00 {
+			t.Errorf("expect taxable income = %v but got %v", 340000.00, got.TaxableIncome)
+		}
+		if got.TotalTax != 19000.00 {
+			t.Errorf("expect total tax = %v but got %v", 19000.00, got.TotalTax)
+		}
+		if got.TaxRefund != 6000.00 || got.TaxPayable != 0.00 {
+			t.Errorf("expect tax refund = %v but got refund %v payable %v", 6000.00, got.TaxRefund, got.TaxPayable)
+		}
+		if got.Levels[4].Level != "2,000,001 and above" || got.Levels[4].TaxRatePercentage != 35 {
+			t.Errorf("expect last level to be english label with rate 35 but got %v", got.Levels[4])
+		}
+	})
+
+	t.Run("given english locale without font should render pdf with built-in font", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		locale := GetLocale(LanguageEnglish)
+
+		got, err := NewReport(taxCalulator, locale).RenderPDF(nil, locale)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if !bytes.HasPrefix(got, []byte("%PDF-")) {
+			t.Errorf("expect pdf document but got %q", got[:8])
+		}
+	})
+
+	t.Run("given thai locale without font should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		locale := GetLocale(LanguageThai)
+
+		_, err := NewReport(taxCalulator, locale).RenderPDF(nil, locale)
+		var localized *LocalizedError
+		if !errors.As(err, &localized) || localized.Key != MsgReportFontMissing {
+			t.Errorf("expect report font missing error but got %v", err)
+		}
+	})
+
+	t.Run("given thai locale with shipped font should render pdf with thai text", func(t *testing.T) {
+		font, err := os.ReadFile("../fonts/FreeSerif.ttf")
+		if err != nil {
+			t.Fatalf("Unable to read report font, error: %v", err)
+		}
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		taxCalulator.TotalIncome = 500000.00
+		locale := GetLocale(LanguageThai)
+
+		got, err := NewReport(taxCalulator, locale).RenderPDF(font, locale)
+		if err != nil {
+			t.Fatalf("expect no error but got %v", err)
+		}
+		if !bytes.HasPrefix(got, []byte("%PDF-")) {
+			t.Errorf("expect pdf document but got %q", got[:8])
+		}
+		if err := CheckReportFont(font); err != nil {
+			t.Errorf("expect shipped font to pass check but got %v", err)
+		}
+	})
+
+	t.Run("given file that is not a font should fail check", func(t *testing.T) {
+		if err := CheckReportFont([]byte("not a font")); err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+}
+
+// latinFont returns a font that only has a format 4 cmap mapping A to Z.
+func latinFont() []byte {
+	var font []byte
+	put := func(values ...uint16) {
+		for _, value := range values {
+			font = append(font, byte(value>>8), byte(value))
+		}
+	}
+	put(0x0001, 0x0000, 1, 0, 0, 0)
+	font = append(font, "cmap"...)
+	put(0, 0, 0, 28, 0, 44)
+	put(0, 1, 3, 1, 0, 12)
+	put(4, 32, 0, 4, 0, 0, 0)
+	put('Z', 0xFFFF, 0, 'A', 0xFFFF, 0xFFC0, 1, 0, 0)
+	return font
+}
+
+func TestMissingGlyphs(t *testing.T) {
+	t.Run("given latin font should miss every thai character", func(t *testing.T) {
+		got := missingGlyphs(latinFont(), append([]rune("AZ"), thaiSample...))
+
+		if !reflect.DeepEqual(got, thaiSample) {
+			t.Errorf("expected missing %q but got %q", string(thaiSample), string(got))
+		}
+	})
+
+	t.Run("given shipped font should have glyphs for thai but not for hangul", func(t *testing.T) {
+		font, err := os.ReadFile("../fonts/FreeSerif.ttf")
+		if err != nil {
+			t.Fatalf("Unable to read report font, error: %v", err)
+		}
+
+		if got := missingGlyphs(font, append([]rune("한"), thaiSample...)); string(got) != "한" {
+			t.Errorf("expected only 한 to be missing but got %q", string(got))
+		}
+	})
+
+	t.Run("given file that is not a font should miss every character", func(t *testing.T) {
+		if got := missingGlyphs([]byte("not a font"), []rune("กA")); string(got) != "กA" {
+			t.Errorf("expected every character to be missing but got %q", string(got))
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package tax
+
+import (
+	"encoding/binary"
+	"unicode"
+)
+
+// thaiSample holds every character of the Thai block, so a report font must
+// cover all Thai text a report can contain.
+var thaiSample = func() []rune {
+	var runes []rune
+	for r := rune(0x0E01); r <= 0x0E5B; r++ {
+		if unicode.Is(unicode.Thai, r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}()
+
+// fontCmap returns the character to glyph mapping of a TrueType font, or
+// nil if the font has no Unicode cmap subtable of format 4 or 12.
+func fontCmap(font []byte) func(rune) uint32 {
+	u16 := func(offset int) int {
+		if offset < 0 || offset+2 > len(font) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(font[offset:]))
+	}
+	u32 := func(offset int) int {
+		if offset < 0 || offset+4 > len(font) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint32(font[offset:]))
+	}
+
+	cmap := 0
+	for index := 0; index < u16(4); index++ {
+		record := 12 + 16*index
+		if record+16 <= len(font) && string(font[record:record+4]) == "cmap" {
+			cmap = u32(record + 8)
+		}
+	}
+	if cmap == 0 {
+		return nil
+	}
+
+	var subtable4, subtable12 int
+	for index := 0; index < u16(cmap+2); index++ {
+		record := cmap + 4 + 8*index
+		platform, encoding, subtable := u16(record), u16(record+2), cmap+u32(record+4)
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		switch u16(subtable) {
+		case 4:
+			subtable4 = subtable
+		case 12:
+			subtable12 = subtable
+		}
+	}
+
+	if subtable12 != 0 {
+		groups := u32(subtable12 + 12)
+		return func(r rune) uint32 {
+			for index := 0; index < groups; index++ {
+				group := subtable12 + 16 + 12*index
+				if start, end := u32(group), u32(group+4); int(r) >= start && int(r) <= end {
+					return uint32(u32(group+8) + int(r) - start)
+				}
+			}
+			return 0
+		}
+	}
+	if subtable4 != 0 {
+		segments := u16(subtable4+6) / 2
+		ends := subtable4 + 14
+		starts := ends + 2*segments + 2
+		deltas := starts + 2*segments
+		rangeOffsets := deltas + 2*segments
+		return func(r rune) uint32 {
+			for index := 0; index < segments; index++ {
+				start, end := u16(starts+2*index), u16(ends+2*index)
+				if int(r) < start || int(r) > end {
+					continue
+				}
+				delta, rangeOffset := u16(deltas+2*index), u16(rangeOffsets+2*index)
+				if rangeOffset == 0 {
+					return uint32((int(r) + delta) & 0xFFFF)
+				}
+				glyph := u16(rangeOffsets + 2*index + rangeOffset + 2*(int(r)-start))
+				if glyph == 0 {
+					return 0
+				}
+				return uint32((glyph + delta) & 0xFFFF)
+			}
+			return 0
+		}
+	}
+	return nil
+}
+
+// missingGlyphs returns the runes the font has no glyph for, all of them if
+// the font has no Unicode cmap.
+func missingGlyphs(font []byte, runes []rune) []rune {
+	glyph := fontCmap(font)
+	var missing []rune
+	for _, r := range runes {
+		if glyph == nil || glyph(r) == 0 {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,236 @@
+package tax
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrRuleSetNotFound = errors.New("rule set not found")
+
+// RuleSet holds the statutory bounds of a tax year. The admin endpoints
+// validate the deduction settings against them and the calculator caps the
+// personal, spouse and donation allowances with them. Minimums are
+// exclusive.
+type RuleSet struct {
+	TaxYear              int
+	PersonalDeductionMin float64
+	PersonalDeductionMax float64
+	KReceiptMin          float64
+	KReceiptMax          float64
+	DonationMax          float64
+	Version              int
+}
+
+// DefaultRuleSet is the rule set of tax year 2567, which init.sql seeds.
+var DefaultRuleSet = RuleSet{
+	TaxYear:              FilingTaxYear,
+	PersonalDeductionMin: 10000.00,
+	PersonalDeductionMax: 100000.00,
+	KReceiptMin:          0.00,
+	KReceiptMax:          100000.00,
+	DonationMax:          100000.00,
+}
+
+// ETagName is the name SettingETag tags versions of the rule set with.
+func (r RuleSet) ETagName() string {
+	return "rules_" + strconv.Itoa(r.TaxYear)
+}
+
+// Validate reports the first negative bound or minimum that is not below its
+// maximum.
+func (r RuleSet) Validate() error {
+	bounds := []struct {
+		name  string
+		value float64
+	}{
+		{"personalDeductionMin", r.PersonalDeductionMin},
+		{"personalDeductionMax", r.PersonalDeductionMax},
+		{"kReceiptMin", r.KReceiptMin},
+		{"kReceiptMax", r.KReceiptMax},
+		{"donationMax", r.DonationMax},
+	}
+	for _, bound := range bounds {
+		if bound.value < 0 {
+			return NewLocalizedError(MsgRuleNegative, bound.name)
+		}
+	}
+	if r.PersonalDeductionMin >= r.PersonalDeductionMax {
+		return NewLocalizedError(MsgRuleRange, "personalDeductionMin", "personalDeductionMax")
+	}
+	if r.KReceiptMin >= r.KReceiptMax {
+		return NewLocalizedError(MsgRuleRange, "kReceiptMin", "kReceiptMax")
+	}
+	return nil
+}
+
+const MIMEApplicationYAML = "application/yaml"
+
+// RulesDocumentFormat is the version of the layout of RulesDocument.
+const RulesDocumentFormat = 1
+
+const checksumPrefix = "hmac-sha256:"
+
+// RulesDocument is the exported rule set of a tax year with the allowance
+// settings, so the rules can be promoted between environments. Its checksum
+// is an HMAC of the rest of the document, so only documents exported with
+// the same signing key and not modified since are imported.
+type RulesDocument struct {
+	Format     int                `json:"format" yaml:"format"`
+	TaxYear    int                `json:"taxYear" yaml:"taxYear"`
+	Brackets   []RulesBracket     `json:"brackets" yaml:"brackets"`
+	Allowances map[string]float64 `json:"allowances" yaml:"allowances"`
+	Bounds     RulesBounds        `json:"bounds" yaml:"bounds"`
+	Checksum   string             `json:"checksum" yaml:"checksum"`
+}
+
+// RulesBracket is a tax level. The top level has no maximum.
+type RulesBracket struct {
+	Min  float64  `json:"min" yaml:"min"`
+	Max  *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Rate float64  `json:"rate" yaml:"rate"`
+}
+
+type RulesBounds struct {
+	PersonalDeductionMin float64 `json:"personalDeductionMin" yaml:"personalDeductionMin"`
+	PersonalDeductionMax float64 `json:"personalDeductionMax" yaml:"personalDeductionMax"`
+	KReceiptMin          float64 `json:"kReceiptMin" yaml:"kReceiptMin"`
+	KReceiptMax          float64 `json:"kReceiptMax" yaml:"kReceiptMax"`
+	DonationMax          float64 `json:"donationMax" yaml:"donationMax"`
+}
+
+func NewRulesBrackets(levels []Level) []RulesBracket {
+	brackets := make([]RulesBracket, 0, len(levels))
+	for _, level := range levels {
+		bracket := RulesBracket{Min: level.MinAmount, Rate: level.TaxRatePercentage}
+		if level.MaxAmount != math.MaxFloat64 {
+			maxAmount := level.MaxAmount
+			bracket.Max = &maxAmount
+		}
+		brackets = append(brackets, bracket)
+	}
+	return brackets
+}
+
+func (r RuleSet) Bounds() RulesBounds {
+	return RulesBounds{
+		PersonalDeductionMin: r.PersonalDeductionMin,
+		PersonalDeductionMax: r.PersonalDeductionMax,
+		KReceiptMin:          r.KReceiptMin,
+		KReceiptMax:          r.KReceiptMax,
+		DonationMax:          r.DonationMax,
+	}
+}
+
+func (b RulesBounds) RuleSet(taxYear int) RuleSet {
+	return RuleSet{
+		TaxYear:              taxYear,
+		PersonalDeductionMin: b.PersonalDeductionMin,
+		PersonalDeductionMax: b.PersonalDeductionMax,
+		KReceiptMin:          b.KReceiptMin,
+		KReceiptMax:          b.KReceiptMax,
+		DonationMax:          b.DonationMax,
+	}
+}
+
+// sum returns the HMAC of the document without its checksum. JSON encodes
+// map keys in order, so the same values always give the same sum whether the
+// document was read from JSON or YAML.
+func (d RulesDocument) sum(key []byte) []byte {
+	d.Checksum = ""
+	payload, _ := json.Marshal(d)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Sign returns the document with its checksum set.
+func (d RulesDocument) Sign(key []byte) RulesDocument {
+	d.Checksum = checksumPrefix + hex.EncodeToString(d.sum(key))
+	return d
+}
+
+// Verify reports whether the checksum of the document was made with key.
+func (d RulesDocument) Verify(key []byte) bool {
+	encoded, found := strings.CutPrefix(d.Checksum, checksumPrefix)
+	if !found {
+		return false
+	}
+	checksum, err := hex.DecodeString(encoded)
+	return err == nil && hmac.Equal(checksum, d.sum(key))
+}
+
+// RuleChange is a value of a rules document that differs from the current
+// one. Field is the path of the value in the document.
+type RuleChange struct {
+	Field    string
+	Current  float64
+	Imported float64
+}
+
+// RulesVersions are the versions of the rows a rules import writes: the rule
+// set of its tax year, which is 0 if the year has none, and the allowance
+// settings by type.
+type RulesVersions struct {
+	RuleSet    int
+	Allowances map[string]int
+}
+
+func (v RulesVersions) Equal(other RulesVersions) bool {
+	return v.RuleSet == other.RuleSet && maps.Equal(v.Allowances, other.Allowances)
+}
+
+// ETag returns the entity tag of the versions, which joins the tags of the
+// rule set and of every allowance setting, such as
+// "rules_2567.1+personal_default.3+kreceipt_max.1".
+func (v RulesVersions) ETag(taxYear int) string {
+	tags := []string{RuleSet{TaxYear: taxYear}.ETagName() + "." + strconv.Itoa(v.RuleSet)}
+	for _, settingType := range AllowanceSettingTypes {
+		tags = append(tags, settingType.Type+"."+strconv.Itoa(v.Allowances[settingType.Type]))
+	}
+	return strconv.Quote(strings.Join(tags, "+"))
+}
+
+// ParseRulesVersionsETag returns the versions of an entity tag made by ETag
+// for the tax year. A tag of another year or a malformed one gives versions
+// no import has, with a rule set version of -1.
+func ParseRulesVersionsETag(taxYear int, etag string) RulesVersions {
+	invalid := RulesVersions{RuleSet: -1}
+	unquoted, err := strconv.Unquote(strings.TrimSpace(etag))
+	if err != nil {
+		return invalid
+	}
+	tags := strings.Split(unquoted, "+")
+	if len(tags) != len(AllowanceSettingTypes)+1 {
+		return invalid
+	}
+	ruleSet, ok := parseTaggedVersion(RuleSet{TaxYear: taxYear}.ETagName(), tags[0])
+	if !ok {
+		return invalid
+	}
+	versions := RulesVersions{RuleSet: ruleSet, Allowances: map[string]int{}}
+	for index, settingType := range AllowanceSettingTypes {
+		version, ok := parseTaggedVersion(settingType.Type, tags[index+1])
+		if !ok {
+			return invalid
+		}
+		versions.Allowances[settingType.Type] = version
+	}
+	return versions
+}
+
+// RulesImport is the outcome of importing a rules document. Versions are
+// those of the current rows, which a dry run returns for the import to be
+// made against.
+type RulesImport struct {
+	TaxYear  int
+	Changes  []RuleChange
+	Applied  bool
+	Versions RulesVersions
+}
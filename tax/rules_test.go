@@ -0,0 +1,247 @@
+package tax
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var testSigningKey = []byte("staging-and-production")
+
+func TestRulesDocument(t *testing.T) {
+	t.Run("given signed document should verify only with the same key and without changes", func(t *testing.T) {
+		document := RulesDocument{
+			Format:     RulesDocumentFormat,
+			TaxYear:    2567,
+			Brackets:   NewRulesBrackets(CreateLevels()),
+			Allowances: map[string]float64{SettingPersonalDeduction: 60000.0, SettingMaxKReceipt: 50000.0},
+			Bounds:     DefaultRuleSet.Bounds(),
+		}.Sign(testSigningKey)
+
+		if !document.Verify(testSigningKey) {
+			t.Errorf("expected document to verify with its key")
+		}
+		if document.Verify([]byte("another-key")) {
+			t.Errorf("expected document not to verify with another key")
+		}
+		document.Allowances[SettingPersonalDeduction] = 70000.0
+		if document.Verify(testSigningKey) {
+			t.Errorf("expected modified document not to verify")
+		}
+	})
+
+	t.Run("given versions ETag should parse back only for its tax year", func(t *testing.T) {
+		versions := RulesVersions{RuleSet: 3, Allowances: map[string]int{SettingPersonalDeduction: 2, SettingMaxKReceipt: 1}}
+		etag := versions.ETag(2567)
+
+		if etag != `"rules_2567.3+personal_default.2+kreceipt_max.1"` {
+			t.Errorf("unexpected ETag %v", etag)
+		}
+		if parsed := ParseRulesVersionsETag(2567, etag); !parsed.Equal(versions) {
+			t.Errorf("expected %v but got %v", versions, parsed)
+		}
+		for _, invalid := range []string{`"rules_2567.3"`, `"personal_default.2"`, "rules_2567.3+personal_default.2+kreceipt_max.1"} {
+			if parsed := ParseRulesVersionsETag(2567, invalid); parsed.RuleSet != -1 {
+				t.Errorf("expected %v not to parse but got %v", invalid, parsed)
+			}
+		}
+		if parsed := ParseRulesVersionsETag(2568, etag); parsed.RuleSet != -1 {
+			t.Errorf("expected ETag of 2567 not to parse for 2568 but got %v", parsed)
+		}
+	})
+
+	t.Run("given levels should export top bracket without maximum", func(t *testing.T) {
+		brackets := NewRulesBrackets(CreateLevels())
+
+		if len(brackets) != 5 || brackets[4].Max != nil || *brackets[0].Max != 150000.0 || brackets[4].Rate != 35 {
+			t.Errorf("unexpected brackets %v", brackets)
+		}
+	})
+}
+
+func TestImportRules(t *testing.T) {
+	t.Run("given dry run should return changes without storing them", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		document, err := service.ExportRules(context.Background(), 2567)
+		if err != nil {
+			t.Fatalf("Unable to export rules, error: %v", err)
+		}
+		document.Allowances[SettingPersonalDeduction] = 70000.0
+		document.Bounds.DonationMax = 80000.0
+
+		result, err := service.ImportRules(context.Background(), document.Sign(testSigningKey), true, RulesVersions{})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		versions := RulesVersions{RuleSet: 1, Allowances: map[string]int{SettingPersonalDeduction: 1, SettingMaxKReceipt: 1}}
+		if !result.Versions.Equal(versions) {
+			t.Errorf("expected current versions %v but got %v", versions, result.Versions)
+		}
+		want := []RuleChange{
+			{Field: "allowances.personal_default", Current: 60000.0, Imported: 70000.0},
+			{Field: "bounds.donationMax", Current: 100000.0, Imported: 80000.0},
+		}
+		if !reflect.DeepEqual(result.Changes, want) || result.Applied {
+			t.Errorf("expected changes %v not applied but got %v", want, result)
+		}
+		if store.PersonalDeductionAmount != 60000.0 || store.Rules[2567].DonationMax != 100000.0 {
+			t.Errorf("expected store to be unchanged but got %v", store)
+		}
+	})
+
+	t.Run("given document should store changes and bump versions of changed settings", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		document, _ := service.ExportRules(context.Background(), 2567)
+		document.Allowances[SettingMaxKReceipt] = 40000.0
+		dryRun, _ := service.ImportRules(context.Background(), document.Sign(testSigningKey), true, RulesVersions{})
+
+		result, err := service.ImportRules(context.Background(), document.Sign(testSigningKey), false, dryRun.Versions)
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if !result.Applied || store.MaxKReceipt != 40000.0 || store.MaxKReceiptVersion != 2 || store.PersonalDeductionVersion != 1 {
+			t.Errorf("expected only k-receipt to be updated but got %v", store)
+		}
+		if result.Versions.Allowances[SettingMaxKReceipt] != 2 {
+			t.Errorf("expected versions after the import but got %v", result.Versions)
+		}
+	})
+
+	t.Run("given setting changed since the dry run should return conflict and not store the document", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		document, _ := service.ExportRules(context.Background(), 2567)
+		document.Allowances[SettingMaxKReceipt] = 40000.0
+		dryRun, _ := service.ImportRules(context.Background(), document.Sign(testSigningKey), true, RulesVersions{})
+		service.UpdateAllowanceSetting(context.Background(), SettingPersonalDeduction, 70000.0, 1)
+
+		result, err := service.ImportRules(context.Background(), document.Sign(testSigningKey), false, dryRun.Versions)
+
+		if ErrorKindOf(err) != ErrorKindConflict || result.Applied {
+			t.Errorf("expected kind %v but got %v", ErrorKindConflict, err)
+		}
+		if store.MaxKReceipt != 50000.0 || store.PersonalDeductionAmount != 70000.0 {
+			t.Errorf("expected the concurrent update to be kept but got %v", store)
+		}
+		if result.Versions.Allowances[SettingPersonalDeduction] != 2 {
+			t.Errorf("expected current versions but got %v", result.Versions)
+		}
+	})
+
+	t.Run("given document of tax year without rule set should create it", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		document, _ := service.ExportRules(context.Background(), 2567)
+		document.TaxYear = 2568
+		document.Bounds.DonationMax = 80000.0
+		document = document.Sign(testSigningKey)
+		dryRun, _ := service.ImportRules(context.Background(), document, true, RulesVersions{})
+
+		result, err := service.ImportRules(context.Background(), document, false, dryRun.Versions)
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if dryRun.Versions.RuleSet != 0 || !result.Applied || store.Rules[2568].DonationMax != 80000.0 || store.Rules[2567].DonationMax != 100000.0 {
+			t.Errorf("expected rules of 2568 to be created but got %v", store.Rules)
+		}
+	})
+
+	t.Run("given document of another tax year should keep current allowances", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		document, _ := service.ExportRules(context.Background(), 2567)
+		document.TaxYear = 2566
+		document.Allowances[SettingPersonalDeduction] = 20000.0
+		document.Allowances[SettingMaxKReceipt] = 90000.0
+		document.Bounds.DonationMax = 80000.0
+		document = document.Sign(testSigningKey)
+		dryRun, _ := service.ImportRules(context.Background(), document, true, RulesVersions{})
+		service.UpdateAllowanceSetting(context.Background(), SettingPersonalDeduction, 70000.0, 1)
+
+		result, err := service.ImportRules(context.Background(), document, false, dryRun.Versions)
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		want := []RuleChange{
+			{Field: "bounds.personalDeductionMin", Current: 0.0, Imported: 10000.0},
+			{Field: "bounds.personalDeductionMax", Current: 0.0, Imported: 100000.0},
+			{Field: "bounds.kReceiptMax", Current: 0.0, Imported: 100000.0},
+			{Field: "bounds.donationMax", Current: 0.0, Imported: 80000.0},
+		}
+		if !reflect.DeepEqual(dryRun.Changes, want) {
+			t.Errorf("expected changes %v but got %v", want, dryRun.Changes)
+		}
+		if !result.Applied || store.Rules[2566].DonationMax != 80000.0 {
+			t.Errorf("expected rules of 2566 to be stored but got %v", store.Rules)
+		}
+		if store.PersonalDeductionAmount != 70000.0 || store.MaxKReceipt != 50000.0 || store.MaxKReceiptVersion != 1 {
+			t.Errorf("expected current allowances to be unchanged but got %v", store)
+		}
+	})
+
+	t.Run("given invalid documents should return invalid input and not store them", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store, RulesSigningKey: testSigningKey}
+		exported, _ := service.ExportRules(context.Background(), 2567)
+		copyOf := func(document RulesDocument) RulesDocument {
+			document.Allowances = map[string]float64{}
+			for key, value := range exported.Allowances {
+				document.Allowances[key] = value
+			}
+			document.Brackets = NewRulesBrackets(CreateLevels())
+			return document
+		}
+
+		tampered := copyOf(exported)
+		tampered.Allowances[SettingPersonalDeduction] = 70000.0
+		noYear := copyOf(exported)
+		noYear.TaxYear = 0
+		brackets := copyOf(exported)
+		brackets.Brackets[1].Rate = 12
+		outOfBounds := copyOf(exported)
+		outOfBounds.Allowances[SettingPersonalDeduction] = 90000.0
+		outOfBounds.Bounds.PersonalDeductionMax = 80000.0
+		unknown := copyOf(exported)
+		unknown.Allowances["lottery"] = 100.0
+		missing := copyOf(exported)
+		delete(missing.Allowances, SettingMaxKReceipt)
+
+		documents := map[string]RulesDocument{
+			"tampered":       tampered,
+			"no year":        noYear.Sign(testSigningKey),
+			"brackets":       brackets.Sign(testSigningKey),
+			"out of bounds":  outOfBounds.Sign(testSigningKey),
+			"unknown type":   unknown.Sign(testSigningKey),
+			"missing type":   missing.Sign(testSigningKey),
+			"another key":    copyOf(exported).Sign([]byte("another-key")),
+			"unknown format": RulesDocument{Format: 2}.Sign(testSigningKey),
+		}
+		for name, document := range documents {
+			_, err := service.ImportRules(context.Background(), document, false, RulesVersions{RuleSet: 1})
+
+			if ErrorKindOf(err) != ErrorKindInvalidInput {
+				t.Errorf("expected kind %v for %v but got %v", ErrorKindInvalidInput, name, err)
+			}
+		}
+		if store.PersonalDeductionAmount != 60000.0 || store.Rules[2567].Version != 1 {
+			t.Errorf("expected store to be unchanged but got %v", store)
+		}
+	})
+
+	t.Run("given no signing key should not export or import", func(t *testing.T) {
+		service := TaxService{Store: NewMockStore()}
+
+		_, exportErr := service.ExportRules(context.Background(), 2567)
+		_, importErr := service.ImportRules(context.Background(), RulesDocument{}, true, RulesVersions{})
+
+		if exportErr == nil || importErr == nil {
+			t.Errorf("expected errors but got %v and %v", exportErr, importErr)
+		}
+	})
+}
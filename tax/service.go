@@ -0,0 +1,518 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorKind tells a transport how to report an error of TaxService.
+type ErrorKind string
+
+const (
+	ErrorKindInternal     ErrorKind = "internal"
+	ErrorKindInvalidInput ErrorKind = "invalid_input"
+	ErrorKindNotFound     ErrorKind = "not_found"
+	// ErrorKindConflict means a setting was updated with a version that is
+	// no longer current.
+	ErrorKindConflict ErrorKind = "conflict"
+)
+
+// ServiceError is the error returned by TaxService. Its message is the one of
+// the wrapped error, so a wrapped *LocalizedError is still rendered in the
+// client's language by Locale.ErrorMessage.
+type ServiceError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ServiceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+func invalidInput(err error) error {
+	return &ServiceError{Kind: ErrorKindInvalidInput, Err: err}
+}
+
+func notFound(err error) error {
+	return &ServiceError{Kind: ErrorKindNotFound, Err: err}
+}
+
+func conflict(err error) error {
+	return &ServiceError{Kind: ErrorKindConflict, Err: err}
+}
+
+func internal(err error) error {
+	return &ServiceError{Kind: ErrorKindInternal, Err: err}
+}
+
+// ErrorKindOf returns the kind of err, which is internal for errors that do
+// not come from TaxService.
+func ErrorKindOf(err error) ErrorKind {
+	var serviceError *ServiceError
+	if errors.As(err, &serviceError) {
+		return serviceError.Kind
+	}
+	return ErrorKindInternal
+}
+
+// TaxService is the transport-independent business logic of the API, shared
+// by the REST handlers and the gRPC server.
+type TaxService struct {
+	Store   Store
+	Metrics MetricsRecorder
+	// RulesSigningKey signs exported rules documents. Rules can neither be
+	// exported nor imported without it.
+	RulesSigningKey []byte
+}
+
+func (s *TaxService) observeCalculation(result Result) {
+	if s.Metrics != nil {
+		s.Metrics.ObserveCalculation(result)
+	}
+}
+
+// Calculation is a calculator and the tax it calculated.
+type Calculation struct {
+	Calculator Calulator
+	Result     Result
+}
+
+func applyRequest(calculator Calulator, request CalculationRequest) (Calulator, error) {
+	calculator.TotalIncome = request.TotalIncome
+	calculator.WitholdingTax = request.WithHoldingTax
+	for _, allowance := range request.Allowances {
+		if allowance.Type == AllowanceTypeDonation {
+			calculator.AllowanceDonation = allowance.Amount
+		} else if allowance.Type == AllowanceTypeKReceipt {
+			calculator.AllowanceKReceipt = allowance.Amount
+		} else {
+			return calculator, invalidInput(NewLocalizedError(MsgUnknownAllowanceType, allowance.Type))
+		}
+	}
+	return calculator, nil
+}
+
+// CalculationYear returns the tax year whose rule set calculates the request,
+// the current tax year unless the request names another.
+func (r CalculationRequest) CalculationYear() int {
+	if r.TaxYear == 0 {
+		return FilingTaxYear
+	}
+	return r.TaxYear
+}
+
+// NewCalculator returns a calculator with the rules of the tax year and the
+// current deductions, which callers calculating many requests build once and
+// pass to CalculateWith. A tax year without a rule set is invalid input.
+func (s *TaxService) NewCalculator(ctx context.Context, taxYear int) (Calulator, error) {
+	rules, err := s.Rules(ctx, taxYear)
+	if ErrorKindOf(err) == ErrorKindNotFound {
+		return Calulator{}, invalidInput(NewLocalizedError(MsgRulesNotFound, taxYear))
+	}
+	if err != nil {
+		return Calulator{}, err
+	}
+	deductions, err := s.Deductions(ctx)
+	if err != nil {
+		return Calulator{}, err
+	}
+	return NewTaxCalulatorWithRules(rules, deductions.PersonalDeduction, deductions.KReceipt), nil
+}
+
+// CreateTaxCalculatorFromRequest returns a calculator for the request with
+// the rules of its tax year and the current deductions.
+func (s *TaxService) CreateTaxCalculatorFromRequest(ctx context.Context, request CalculationRequest) (calculator Calulator, err error) {
+	ctx, span := tracer.Start(ctx, "CreateTaxCalculatorFromRequest")
+	defer func() { endSpan(span, err) }()
+
+	calculator, err = s.NewCalculator(ctx, request.CalculationYear())
+	if err != nil {
+		return Calulator{}, err
+	}
+	return applyRequest(calculator, request)
+}
+
+func (s *TaxService) Calculate(ctx context.Context, request CalculationRequest) (Calculation, error) {
+	calculator, err := s.CreateTaxCalculatorFromRequest(ctx, request)
+	if err != nil {
+		return Calculation{}, err
+	}
+	result := CalculateTaxResultTraced(ctx, calculator)
+	s.observeCalculation(result)
+	return Calculation{Calculator: calculator, Result: result}, nil
+}
+
+// CalculateWith calculates the request with a calculator from NewCalculator
+// for the request's tax year without reading the store.
+func (s *TaxService) CalculateWith(ctx context.Context, calculator Calulator, request CalculationRequest) (Calculation, error) {
+	calculator, err := applyRequest(calculator, request)
+	if err != nil {
+		return Calculation{}, err
+	}
+	result := CalculateTaxResultTraced(ctx, calculator)
+	s.observeCalculation(result)
+	return Calculation{Calculator: calculator, Result: result}, nil
+}
+
+// CalculateBatch calculates every request with the same deductions and the
+// rules of its tax year. It fails on the first invalid request without
+// calculating any.
+func (s *TaxService) CalculateBatch(ctx context.Context, requests []CalculationRequest) (calculations []Calculation, err error) {
+	ctx, span := tracer.Start(ctx, "CalculateBatch", trace.WithAttributes(attribute.Int("batch.size", len(requests))))
+	defer func() { endSpan(span, err) }()
+
+	yearCalculators := map[int]Calulator{}
+	calculators := make([]Calulator, len(requests))
+	for index, request := range requests {
+		calculator, ok := yearCalculators[request.CalculationYear()]
+		if !ok {
+			calculator, err = s.NewCalculator(ctx, request.CalculationYear())
+			if err != nil {
+				return nil, err
+			}
+			yearCalculators[request.CalculationYear()] = calculator
+		}
+		calculators[index], err = applyRequest(calculator, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, calculator := range calculators {
+		result := CalculateTaxResultTraced(ctx, calculator)
+		s.observeCalculation(result)
+		calculations = append(calculations, Calculation{Calculator: calculator, Result: result})
+	}
+	return calculations, nil
+}
+
+type Deductions struct {
+	PersonalDeduction        float64
+	PersonalDeductionVersion int
+	KReceipt                 float64
+	KReceiptVersion          int
+}
+
+// Deductions returns the current personal deduction and max k-receipt.
+func (s *TaxService) Deductions(ctx context.Context) (Deductions, error) {
+	personalDeduction, err := s.Store.GetAllowanceSetting(ctx, SettingPersonalDeduction)
+	if err != nil {
+		return Deductions{}, internal(err)
+	}
+	kReceipt, err := s.Store.GetAllowanceSetting(ctx, SettingMaxKReceipt)
+	if err != nil {
+		return Deductions{}, internal(err)
+	}
+	return Deductions{
+		PersonalDeduction:        personalDeduction.Amount,
+		PersonalDeductionVersion: personalDeduction.Version,
+		KReceipt:                 kReceipt.Amount,
+		KReceiptVersion:          kReceipt.Version,
+	}, nil
+}
+
+// Rules returns the rule set of the tax year, or a not found error if the
+// year has none.
+func (s *TaxService) Rules(ctx context.Context, taxYear int) (RuleSet, error) {
+	rules, err := s.Store.GetRuleSet(ctx, taxYear)
+	if errors.Is(err, ErrRuleSetNotFound) {
+		return RuleSet{}, notFound(NewLocalizedError(MsgRulesNotFound, taxYear))
+	}
+	if err != nil {
+		return RuleSet{}, internal(err)
+	}
+	return rules, nil
+}
+
+// UpdateRules validates and stores the rule set of its tax year if the
+// current version is version, like UpdateAllowanceSetting. Deductions set
+// outside the new bounds are kept, the calculator caps them.
+func (s *TaxService) UpdateRules(ctx context.Context, rules RuleSet, version int) (RuleSet, error) {
+	if err := rules.Validate(); err != nil {
+		return RuleSet{}, invalidInput(err)
+	}
+	stored, updated, err := s.Store.UpdateRuleSet(ctx, rules, version)
+	if errors.Is(err, ErrRuleSetNotFound) {
+		return RuleSet{}, notFound(NewLocalizedError(MsgRulesNotFound, rules.TaxYear))
+	}
+	if err != nil {
+		return RuleSet{}, internal(err)
+	}
+	if !updated {
+		return stored, conflict(NewLocalizedError(MsgRulesModified))
+	}
+	return stored, nil
+}
+
+// CreateRules validates and stores the rule set of a tax year that has none.
+// Otherwise it returns the existing rule set with a conflict error.
+func (s *TaxService) CreateRules(ctx context.Context, rules RuleSet) (RuleSet, error) {
+	if err := rules.Validate(); err != nil {
+		return RuleSet{}, invalidInput(err)
+	}
+	stored, created, err := s.Store.CreateRuleSet(ctx, rules)
+	if err != nil {
+		return RuleSet{}, internal(err)
+	}
+	if !created {
+		return stored, conflict(NewLocalizedError(MsgRulesExist, rules.TaxYear))
+	}
+	return stored, nil
+}
+
+func (s *TaxService) allowanceSetting(ctx context.Context, settingType AllowanceSettingType, rules RuleSet) (AllowanceSetting, error) {
+	setting, err := s.Store.GetAllowanceSetting(ctx, settingType.Type)
+	if err != nil {
+		return AllowanceSetting{}, internal(err)
+	}
+	return AllowanceSetting{
+		AllowanceSettingType: settingType,
+		Setting:              setting,
+		MinAmount:            settingType.Min(rules),
+		MaxAmount:            settingType.Max(rules),
+	}, nil
+}
+
+// AllowanceSetting returns the named setting, or a not found error if no
+// setting has the type.
+func (s *TaxService) AllowanceSetting(ctx context.Context, settingType string) (AllowanceSetting, error) {
+	found, ok := FindAllowanceSettingType(settingType)
+	if !ok {
+		return AllowanceSetting{}, notFound(NewLocalizedError(MsgUnknownAllowanceSetting, settingType))
+	}
+	rules, err := s.Rules(ctx, FilingTaxYear)
+	if err != nil {
+		return AllowanceSetting{}, err
+	}
+	return s.allowanceSetting(ctx, found, rules)
+}
+
+// AllowanceSettings returns every setting of AllowanceSettingTypes.
+func (s *TaxService) AllowanceSettings(ctx context.Context) ([]AllowanceSetting, error) {
+	rules, err := s.Rules(ctx, FilingTaxYear)
+	if err != nil {
+		return nil, err
+	}
+	settings := make([]AllowanceSetting, 0, len(AllowanceSettingTypes))
+	for _, settingType := range AllowanceSettingTypes {
+		setting, err := s.allowanceSetting(ctx, settingType, rules)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// UpdateAllowanceSetting validates the amount of the named setting against
+// the rule set and stores it if its current version is version and returns
+// the stored setting. Otherwise it returns the current setting with a
+// conflict error.
+func (s *TaxService) UpdateAllowanceSetting(ctx context.Context, settingType string, amount float64, version int) (AllowanceSetting, error) {
+	found, ok := FindAllowanceSettingType(settingType)
+	if !ok {
+		return AllowanceSetting{}, notFound(NewLocalizedError(MsgUnknownAllowanceSetting, settingType))
+	}
+	rules, err := s.Rules(ctx, FilingTaxYear)
+	if err != nil {
+		return AllowanceSetting{}, err
+	}
+	if err := found.Validate(rules, amount); err != nil {
+		return AllowanceSetting{}, err
+	}
+	setting, updated, err := s.Store.UpdateAllowanceSetting(ctx, settingType, amount, version)
+	if err != nil {
+		return AllowanceSetting{}, internal(err)
+	}
+	stored := AllowanceSetting{
+		AllowanceSettingType: found,
+		Setting:              setting,
+		MinAmount:            found.Min(rules),
+		MaxAmount:            found.Max(rules),
+	}
+	if !updated {
+		return stored, conflict(NewLocalizedError(MsgSettingModified, setting.Amount))
+	}
+	return stored, nil
+}
+
+// currentRules returns the values a rules document of the tax year is
+// compared with and their versions. A year without a rule set has zero bounds
+// and rule set version 0.
+func (s *TaxService) currentRules(ctx context.Context, taxYear int) (RulesDocument, RulesVersions, error) {
+	rules, err := s.Rules(ctx, taxYear)
+	if err != nil && ErrorKindOf(err) != ErrorKindNotFound {
+		return RulesDocument{}, RulesVersions{}, err
+	}
+	document := RulesDocument{
+		Format:     RulesDocumentFormat,
+		TaxYear:    taxYear,
+		Brackets:   NewRulesBrackets(CreateLevels()),
+		Allowances: map[string]float64{},
+		Bounds:     rules.Bounds(),
+	}
+	versions := RulesVersions{RuleSet: rules.Version, Allowances: map[string]int{}}
+	for _, settingType := range AllowanceSettingTypes {
+		setting, err := s.Store.GetAllowanceSetting(ctx, settingType.Type)
+		if err != nil {
+			return RulesDocument{}, RulesVersions{}, internal(err)
+		}
+		document.Allowances[settingType.Type] = setting.Amount
+		versions.Allowances[settingType.Type] = setting.Version
+	}
+	return document, versions, nil
+}
+
+// ExportRules returns the signed rules document of the tax year, or a not
+// found error if the year has no rule set.
+func (s *TaxService) ExportRules(ctx context.Context, taxYear int) (RulesDocument, error) {
+	if len(s.RulesSigningKey) == 0 {
+		return RulesDocument{}, internal(NewLocalizedError(MsgRulesSigningKeyMissing))
+	}
+	document, versions, err := s.currentRules(ctx, taxYear)
+	if err != nil {
+		return RulesDocument{}, err
+	}
+	if versions.RuleSet == 0 {
+		return RulesDocument{}, notFound(NewLocalizedError(MsgRulesNotFound, taxYear))
+	}
+	return document.Sign(s.RulesSigningKey), nil
+}
+
+// validateRulesDocument checks the document as a whole before any value is
+// compared with the current ones.
+func (s *TaxService) validateRulesDocument(document RulesDocument) error {
+	if document.Format != RulesDocumentFormat {
+		return invalidInput(NewLocalizedError(MsgRulesFormat, document.Format))
+	}
+	if !document.Verify(s.RulesSigningKey) {
+		return invalidInput(NewLocalizedError(MsgRulesChecksumInvalid))
+	}
+	if document.TaxYear <= 0 {
+		return invalidInput(NewLocalizedError(MsgInvalidTaxYear, strconv.Itoa(document.TaxYear)))
+	}
+	if !reflect.DeepEqual(document.Brackets, NewRulesBrackets(CreateLevels())) {
+		return invalidInput(NewLocalizedError(MsgRulesBracketsFixed))
+	}
+	rules := document.Bounds.RuleSet(document.TaxYear)
+	if err := rules.Validate(); err != nil {
+		return invalidInput(err)
+	}
+	for allowanceType := range document.Allowances {
+		if _, ok := FindAllowanceSettingType(allowanceType); !ok {
+			return invalidInput(NewLocalizedError(MsgUnknownAllowanceSetting, allowanceType))
+		}
+	}
+	for _, settingType := range AllowanceSettingTypes {
+		amount, ok := document.Allowances[settingType.Type]
+		if !ok {
+			return invalidInput(NewLocalizedError(MsgRulesAllowanceMissing, settingType.Type))
+		}
+		if !importsAllowances(document.TaxYear) {
+			continue
+		}
+		if err := settingType.Validate(rules, amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importsAllowances reports whether importing a document of the tax year
+// stores its allowance settings. The settings are not kept by tax year and
+// are bounded by the rules of the current year, so documents of other years
+// only store their bounds.
+func importsAllowances(taxYear int) bool {
+	return taxYear == FilingTaxYear
+}
+
+// importedVersions returns the versions of the rows an import of the tax
+// year writes.
+func importedVersions(taxYear int, versions RulesVersions) RulesVersions {
+	if importsAllowances(taxYear) {
+		return versions
+	}
+	return RulesVersions{RuleSet: versions.RuleSet, Allowances: map[string]int{}}
+}
+
+// ImportRules validates the document and returns how it differs from the
+// current rules of its tax year and their versions. Unless dryRun is set, it
+// stores the changes at once if the current versions are still versions,
+// those a dry run returned; otherwise it returns a conflict error with the
+// current versions. Tax brackets are fixed, so they must equal the current
+// ones. The allowances of documents of other than the current tax year are
+// ignored.
+func (s *TaxService) ImportRules(ctx context.Context, document RulesDocument, dryRun bool, versions RulesVersions) (RulesImport, error) {
+	if len(s.RulesSigningKey) == 0 {
+		return RulesImport{}, internal(NewLocalizedError(MsgRulesSigningKeyMissing))
+	}
+	if err := s.validateRulesDocument(document); err != nil {
+		return RulesImport{}, err
+	}
+	current, currentVersions, err := s.currentRules(ctx, document.TaxYear)
+	if err != nil {
+		return RulesImport{}, err
+	}
+
+	result := RulesImport{TaxYear: document.TaxYear, Changes: []RuleChange{}, Versions: currentVersions}
+	allowances := map[string]float64{}
+	if importsAllowances(document.TaxYear) {
+		allowances = document.Allowances
+	}
+	for _, settingType := range AllowanceSettingTypes {
+		if _, ok := allowances[settingType.Type]; ok && current.Allowances[settingType.Type] != document.Allowances[settingType.Type] {
+			result.Changes = append(result.Changes, RuleChange{
+				Field:    "allowances." + settingType.Type,
+				Current:  current.Allowances[settingType.Type],
+				Imported: document.Allowances[settingType.Type],
+			})
+		}
+	}
+	bounds := []struct {
+		field    string
+		current  float64
+		imported float64
+	}{
+		{"bounds.personalDeductionMin", current.Bounds.PersonalDeductionMin, document.Bounds.PersonalDeductionMin},
+		{"bounds.personalDeductionMax", current.Bounds.PersonalDeductionMax, document.Bounds.PersonalDeductionMax},
+		{"bounds.kReceiptMin", current.Bounds.KReceiptMin, document.Bounds.KReceiptMin},
+		{"bounds.kReceiptMax", current.Bounds.KReceiptMax, document.Bounds.KReceiptMax},
+		{"bounds.donationMax", current.Bounds.DonationMax, document.Bounds.DonationMax},
+	}
+	for _, bound := range bounds {
+		if bound.current != bound.imported {
+			result.Changes = append(result.Changes, RuleChange{Field: bound.field, Current: bound.current, Imported: bound.imported})
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	versions = importedVersions(document.TaxYear, versions)
+	if !versions.Equal(importedVersions(document.TaxYear, currentVersions)) {
+		return result, conflict(NewLocalizedError(MsgRulesImportModified))
+	}
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+	applied, err := s.Store.ApplyRules(ctx, document.Bounds.RuleSet(document.TaxYear), allowances, versions)
+	if err != nil {
+		return RulesImport{}, internal(err)
+	}
+	if _, result.Versions, err = s.currentRules(ctx, document.TaxYear); err != nil {
+		return RulesImport{}, err
+	}
+	if !applied {
+		return result, conflict(NewLocalizedError(MsgRulesImportModified))
+	}
+	result.Applied = true
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type FailingStore struct {
+	MockStore
+	Err error
+}
+
+func (f *FailingStore) GetAllowanceSetting(ctx context.Context, allowanceType string) (Setting, error) {
+	return Setting{}, f.Err
+}
+
+func TestTaxService(t *testing.T) {
+	t.Run("given unknown allowance should return invalid input error with localized message", func(t *testing.T) {
+		service := TaxService{Store: NewMockStore()}
+
+		_, err := service.Calculate(context.Background(), CalculationRequest{
+			TotalIncome: 500000.0,
+			Allowances:  []AllowanceRequest{{Type: "lottery", Amount: 100.0}},
+		})
+
+		if ErrorKindOf(err) != ErrorKindInvalidInput {
+			t.Errorf("expected kind %v but got %v", ErrorKindInvalidInput, ErrorKindOf(err))
+		}
+		want := GetLocale(LanguageThai).Message(MsgUnknownAllowanceType, "lottery")
+		if got := GetLocale(LanguageThai).ErrorMessage(err); got != want {
+			t.Errorf("expected message %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given store failure should return internal error wrapping it", func(t *testing.T) {
+		storeErr := errors.New("connection refused")
+		service := TaxService{Store: &FailingStore{MockStore: *NewMockStore(), Err: storeErr}}
+
+		_, err := service.Calculate(context.Background(), CalculationRequest{TotalIncome: 500000.0})
+
+		if ErrorKindOf(err) != ErrorKindInternal || !errors.Is(err, storeErr) {
+			t.Errorf("expected internal error wrapping %v but got %v", storeErr, err)
+		}
+	})
+
+	t.Run("given batch should calculate each request in order", func(t *testing.T) {
+		metrics := &MockMetrics{}
+		service := TaxService{Store: NewMockStore(), Metrics: metrics}
+
+		calculations, err := service.CalculateBatch(context.Background(), []CalculationRequest{
+			{TotalIncome: 500000.0},
+			{TotalIncome: 600000.0, WithHoldingTax: 40000.0},
+		})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if len(calculations) != 2 || calculations[0].Result.Amount != 29000.0 || calculations[1].Result.Amount != -1000.0 {
+			t.Errorf("unexpected calculations %v", calculations)
+		}
+		if metrics.Calculations != 2 {
+			t.Errorf("expected 2 observed calculations but got %v", metrics.Calculations)
+		}
+	})
+
+	t.Run("given batch with invalid request should calculate none", func(t *testing.T) {
+		metrics := &MockMetrics{}
+		service := TaxService{Store: NewMockStore(), Metrics: metrics}
+
+		_, err := service.CalculateBatch(context.Background(), []CalculationRequest{
+			{TotalIncome: 500000.0},
+			{TotalIncome: 500000.0, Allowances: []AllowanceRequest{{Type: "lottery", Amount: 100.0}}},
+		})
+
+		if ErrorKindOf(err) != ErrorKindInvalidInput {
+			t.Errorf("expected kind %v but got %v", ErrorKindInvalidInput, ErrorKindOf(err))
+		}
+		if metrics.Calculations != 0 {
+			t.Errorf("expected no observed calculations but got %v", metrics.Calculations)
+		}
+	})
+
+	t.Run("given personal deduction out of bounds should not update store", func(t *testing.T) {
+		store := NewMockStore()
+		service := TaxService{Store: store}
+
+		for _, amount := range []float64{10000.0, 100001.0} {
+			_, err := service.UpdateAllowanceSetting(context.Background(), SettingPersonalDeduction, amount, 1)
+
+			if ErrorKindOf(err) != ErrorKindInvalidInput {
+				t.Errorf("expected kind %v for %v but got %v", ErrorKindInvalidInput, amount, ErrorKindOf(err))
+			}
+		}
+		if store.PersonalDeductionAmount != 60000.0 {
+			t.Errorf("expected personal deduction to stay 60000.0 but got %v", store.PersonalDeductionAmount)
+		}
+	})
+
+	t.Run("given rule set with lower donation cap should cap donation in calculation", func(t *testing.T) {
+		store := NewMockStore()
+		rules := store.Rules[FilingTaxYear]
+		rules.DonationMax = 50000.0
+		store.Rules[FilingTaxYear] = rules
+		service := TaxService{Store: store}
+
+		calculation, err := service.Calculate(context.Background(), CalculationRequest{
+			TotalIncome: 500000.0,
+			Allowances:  []AllowanceRequest{{Type: AllowanceTypeDonation, Amount: 200000.0}},
+		})
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if calculation.Result.Amount != 24000.0 {
+			t.Errorf("expected tax 24000.0 but got %v", calculation.Result.Amount)
+		}
+	})
+
+	t.Run("given tax year without rule set should return invalid input naming the year", func(t *testing.T) {
+		service := TaxService{Store: NewMockStore()}
+
+		_, err := service.Calculate(context.Background(), CalculationRequest{TotalIncome: 500000.0, TaxYear: 2568})
+
+		if ErrorKindOf(err) != ErrorKindInvalidInput {
+			t.Errorf("expected kind %v but got %v", ErrorKindInvalidInput, ErrorKindOf(err))
+		}
+		want := GetLocale(LanguageEnglish).Message(MsgRulesNotFound, 2568)
+		if got := GetLocale(LanguageEnglish).ErrorMessage(err); got != want {
+			t.Errorf("expected message %v but got %v", want, got)
+		}
+	})
+
+	t.Run("given rule set of another tax year should calculate with its caps", func(t *testing.T) {
+		store := NewMockStore()
+		rules := DefaultRuleSet
+		rules.TaxYear = 2568
+		rules.DonationMax = 50000.0
+		store.Rules[2568] = rules
+		service := TaxService{Store: store}
+		request := CalculationRequest{
+			TotalIncome: 500000.0,
+			Allowances:  []AllowanceRequest{{Type: AllowanceTypeDonation, Amount: 200000.0}},
+		}
+
+		current, _ := service.Calculate(context.Background(), request)
+		request.TaxYear = 2568
+		next, err := service.Calculate(context.Background(), request)
+
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		if current.Result.Amount != 19000.0 || next.Result.Amount != 24000.0 {
+			t.Errorf("expected tax 19000.0 for 2567 and 24000.0 for 2568 but got %v and %v", current.Result.Amount, next.Result.Amount)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package tax
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+const (
+	SettingPersonalDeduction = "personal_default"
+	SettingMaxKReceipt       = "kreceipt_max"
+)
+
+// Setting is an admin setting and its version, which the store increments on
+// every update.
+type Setting struct {
+	Amount  float64
+	Version int
+}
+
+// AllowanceSettingType describes a row of the allowance table. An amount is
+// valid if it is more than Min and within Max of the current rule set.
+type AllowanceSettingType struct {
+	Type        string
+	Unit        string
+	Description MessageKey
+	Min         func(rules RuleSet) float64
+	Max         func(rules RuleSet) float64
+	TooLow      MessageKey
+	TooHigh     MessageKey
+}
+
+// AllowanceSettingTypes are the settings the admin endpoints can update.
+var AllowanceSettingTypes = []AllowanceSettingType{
+	{
+		Type:        SettingPersonalDeduction,
+		Unit:        "THB",
+		Description: MsgSettingPersonalDeduction,
+		Min:         func(rules RuleSet) float64 { return rules.PersonalDeductionMin },
+		Max:         func(rules RuleSet) float64 { return rules.PersonalDeductionMax },
+		TooLow:      MsgPersonalDeductionTooLow,
+		TooHigh:     MsgPersonalDeductionTooHigh,
+	},
+	{
+		Type:        SettingMaxKReceipt,
+		Unit:        "THB",
+		Description: MsgSettingMaxKReceipt,
+		Min:         func(rules RuleSet) float64 { return rules.KReceiptMin },
+		Max:         func(rules RuleSet) float64 { return rules.KReceiptMax },
+		TooLow:      MsgKReceiptTooLow,
+		TooHigh:     MsgKReceiptTooHigh,
+	},
+}
+
+func FindAllowanceSettingType(settingType string) (AllowanceSettingType, bool) {
+	for _, candidate := range AllowanceSettingTypes {
+		if candidate.Type == settingType {
+			return candidate, true
+		}
+	}
+	return AllowanceSettingType{}, false
+}
+
+// Validate returns an invalid input error if amount is out of the bounds of
+// rules.
+func (t AllowanceSettingType) Validate(rules RuleSet, amount float64) error {
+	if amount > t.Max(rules) {
+		return invalidInput(NewLocalizedError(t.TooHigh, t.Max(rules)))
+	}
+	if amount <= t.Min(rules) {
+		return invalidInput(NewLocalizedError(t.TooLow, t.Min(rules)))
+	}
+	return nil
+}
+
+// AllowanceSetting is the current value of a setting with its bounds under
+// the current rule set.
+type AllowanceSetting struct {
+	AllowanceSettingType
+	Setting
+	MinAmount float64
+	MaxAmount float64
+}
+
+// SettingETag returns the entity tag of a setting version. The tag includes
+// the setting name, so a tag read from one setting never matches another.
+func SettingETag(name string, version int) string {
+	return fmt.Sprintf("%q", name+"."+strconv.Itoa(version))
+}
+
+// ParseSettingETag returns the version of the entity tag of the named
+// setting, or zero, which no setting has, if the tag belongs to another
+// setting or is malformed.
+func ParseSettingETag(name, etag string) int {
+	unquoted, err := strconv.Unquote(strings.TrimSpace(etag))
+	if err != nil {
+		return 0
+	}
+	version, ok := parseTaggedVersion(name, unquoted)
+	if !ok {
+		return 0
+	}
+	return version
+}
+
+// parseTaggedVersion returns the version of an unquoted "name.version" tag.
+func parseTaggedVersion(name, tag string) (int, bool) {
+	version, found := strings.CutPrefix(tag, name+".")
+	if !found {
+		return 0, false
+	}
+	parsed, err := strconv.Atoi(version)
+	if err != nil || parsed < 0 {
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,31 @@
+package tax
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/apirom9/assessment-tax/tax")
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// CalculateTaxResultTraced calculates the tax within a span of ctx. Spans
+// leave the process unredacted, so they carry the marginal rate but no
+// amounts of the taxpayer.
+func CalculateTaxResultTraced(ctx context.Context, calculator Calulator) Result {
+	_, span := tracer.Start(ctx, "CalculateTaxResult")
+	defer span.End()
+	result := calculator.CalculateTaxResult()
+	span.SetAttributes(attribute.Float64("tax.marginal_rate", result.MarginalRatePercentage))
+	return result
+}
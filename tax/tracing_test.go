@@ -0,0 +1,41 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	t.Run("given request should trace calculator creation and calculation under the parent span", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		otel.SetTracerProvider(provider)
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+		service := TaxService{Store: NewMockStore()}
+		calculator, err := service.CreateTaxCalculatorFromRequest(ctx, CalculationRequest{TotalIncome: 500000.0})
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		CalculateTaxResultTraced(ctx, calculator)
+		parent.End()
+
+		spans := recorder.Ended()
+		want := []string{"CreateTaxCalculatorFromRequest", "CalculateTaxResult", "request"}
+		if len(spans) != len(want) {
+			t.Fatalf("expected %v spans but got %v", len(want), len(spans))
+		}
+		for index, span := range spans {
+			if span.Name() != want[index] {
+				t.Errorf("expected span %v but got %v", want[index], span.Name())
+			}
+			if span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+				t.Errorf("expected span %v in trace %v", span.Name(), parent.SpanContext().TraceID())
+			}
+		}
+	})
+}
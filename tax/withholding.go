@@ -0,0 +1,58 @@
+package tax
+
+import "math"
+
+const MonthsPerYear = 12
+
+type WithholdingMonth struct {
+	Month                 int
+	Income                float64
+	EstimatedAnnualIncome float64
+	EstimatedAnnualTax    float64
+	WitholdingTax         float64
+}
+
+func roundSatang(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// CalculateWithholdingSchedule computes monthly withholding tax the way the
+// Revenue Department prescribes for salaries: each month the annual income is
+// estimated from the income paid so far plus the current salary for the rest
+// of the year, and the tax still due on that estimate is spread over the
+// remaining months. Months after the given ones are projected at the last
+// salary. December settles the difference, so when all twelve months are
+// given the schedule adds up to the annual tax.
+func (t *Calulator) CalculateWithholdingSchedule(monthlyIncomes []float64) ([]WithholdingMonth, error) {
+	if len(monthlyIncomes) == 0 || len(monthlyIncomes) > MonthsPerYear {
+		return nil, NewLocalizedError(MsgWithholdingMonths, MonthsPerYear)
+	}
+
+	var schedule []WithholdingMonth
+	paidIncome, withheld := 0.0, 0.0
+	for index, income := range monthlyIncomes {
+		if income < 0 {
+			return nil, NewLocalizedError(MsgNegativeMonthlyIncome, index+1)
+		}
+		month := index + 1
+		remainingMonths := MonthsPerYear - index
+		estimatedIncome := paidIncome + income*float64(remainingMonths)
+		estimatedTax := t.CalculateTotalTaxFor(estimatedIncome)
+
+		wht := roundSatang(estimatedTax - withheld)
+		if month < MonthsPerYear {
+			wht = math.Max(roundSatang(wht/float64(remainingMonths)), 0)
+		}
+
+		schedule = append(schedule, WithholdingMonth{
+			Month:                 month,
+			Income:                income,
+			EstimatedAnnualIncome: estimatedIncome,
+			EstimatedAnnualTax:    estimatedTax,
+			WitholdingTax:         wht,
+		})
+		paidIncome = paidIncome + income
+		withheld = roundSatang(withheld + wht)
+	}
+	return schedule, nil
+}
@@ -0,0 +1,62 @@
+package tax
+
+import "testing"
+
+func TestWithholdingSchedule(t *testing.T) {
+	t.Run("given salary 50000.0 every month should withhold 3250.0 each month", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		incomes := []float64{50000, 50000, 50000, 50000, 50000, 50000, 50000, 50000, 50000, 50000, 50000, 50000}
+
+		got, err := taxCalulator.CalculateWithholdingSchedule(incomes)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		for _, month := range got {
+			if month.WitholdingTax != 3250.00 {
+				t.Errorf("expect wht of month %v = %v but got %v", month.Month, 3250.00, month.WitholdingTax)
+			}
+		}
+	})
+
+	t.Run("given salary raise from 50000.0 to 80000.0 in month 7 should re-estimate and sum to annual tax 60500.0", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+		incomes := []float64{50000, 50000, 50000, 50000, 50000, 50000, 80000, 80000, 80000, 80000, 80000, 80000}
+
+		got, err := taxCalulator.CalculateWithholdingSchedule(incomes)
+		if err != nil {
+			t.Errorf("expect no error but got %v", err)
+		}
+		if got[5].WitholdingTax != 3250.00 {
+			t.Errorf("expect wht of month 6 = %v but got %v", 3250.00, got[5].WitholdingTax)
+		}
+		if got[6].EstimatedAnnualIncome != 780000.00 || got[6].WitholdingTax != 6833.33 {
+			t.Errorf("expect month 7 estimate 780000.0 and wht 6833.33 but got %v", got[6])
+		}
+		total := 0.0
+		for _, month := range got {
+			total = roundSatang(total + month.WitholdingTax)
+		}
+		taxCalulator.TotalIncome = 780000.00
+		if want := taxCalulator.CalculateTaxResult().Amount; total != want {
+			t.Errorf("expect total wht = %v but got %v", want, total)
+		}
+	})
+
+	t.Run("given 13 months should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		_, err := taxCalulator.CalculateWithholdingSchedule(make([]float64, 13))
+		if err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+
+	t.Run("given negative monthly income should return error", func(t *testing.T) {
+		taxCalulator := NewTaxCalulator(DEFAULT_PERSONAL_ALLOWANCE, DEFAULT_MAX_KRECEIPT)
+
+		_, err := taxCalulator.CalculateWithholdingSchedule([]float64{50000, -1})
+		if err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+}
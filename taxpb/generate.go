@@ -0,0 +1,4 @@
+// Package taxpb contains the protobuf messages and gRPC stubs of TaxService.
+package taxpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tax.proto
@@ -0,0 +1,987 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        v4.25.3
+// source: tax.proto
+
+package taxpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DeductionType int32
+
+const (
+	DeductionType_DEDUCTION_TYPE_UNSPECIFIED DeductionType = 0
+	DeductionType_DEDUCTION_TYPE_PERSONAL    DeductionType = 1
+	DeductionType_DEDUCTION_TYPE_K_RECEIPT   DeductionType = 2
+)
+
+// Enum value maps for DeductionType.
+var (
+	DeductionType_name = map[int32]string{
+		0: "DEDUCTION_TYPE_UNSPECIFIED",
+		1: "DEDUCTION_TYPE_PERSONAL",
+		2: "DEDUCTION_TYPE_K_RECEIPT",
+	}
+	DeductionType_value = map[string]int32{
+		"DEDUCTION_TYPE_UNSPECIFIED": 0,
+		"DEDUCTION_TYPE_PERSONAL":    1,
+		"DEDUCTION_TYPE_K_RECEIPT":   2,
+	}
+)
+
+func (x DeductionType) Enum() *DeductionType {
+	p := new(DeductionType)
+	*p = x
+	return p
+}
+
+func (x DeductionType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeductionType) Descriptor() protoreflect.EnumDescriptor {
+	return file_tax_proto_enumTypes[0].Descriptor()
+}
+
+func (DeductionType) Type() protoreflect.EnumType {
+	return &file_tax_proto_enumTypes[0]
+}
+
+func (x DeductionType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeductionType.Descriptor instead.
+func (DeductionType) EnumDescriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{0}
+}
+
+type Allowance struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// donation or k-receipt
+	AllowanceType string  `protobuf:"bytes,1,opt,name=allowance_type,json=allowanceType,proto3" json:"allowance_type,omitempty"`
+	Amount        float64 `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
+}
+
+func (x *Allowance) Reset() {
+	*x = Allowance{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Allowance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Allowance) ProtoMessage() {}
+
+func (x *Allowance) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Allowance.ProtoReflect.Descriptor instead.
+func (*Allowance) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Allowance) GetAllowanceType() string {
+	if x != nil {
+		return x.AllowanceType
+	}
+	return ""
+}
+
+func (x *Allowance) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type CalculateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TotalIncome float64      `protobuf:"fixed64,1,opt,name=total_income,json=totalIncome,proto3" json:"total_income,omitempty"`
+	Wht         float64      `protobuf:"fixed64,2,opt,name=wht,proto3" json:"wht,omitempty"`
+	Allowances  []*Allowance `protobuf:"bytes,3,rep,name=allowances,proto3" json:"allowances,omitempty"`
+	// Buddhist Era year whose rule set caps the allowances, the current tax
+	// year when unset.
+	TaxYear int32 `protobuf:"varint,4,opt,name=tax_year,json=taxYear,proto3" json:"tax_year,omitempty"`
+}
+
+func (x *CalculateRequest) Reset() {
+	*x = CalculateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CalculateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculateRequest) ProtoMessage() {}
+
+func (x *CalculateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculateRequest.ProtoReflect.Descriptor instead.
+func (*CalculateRequest) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CalculateRequest) GetTotalIncome() float64 {
+	if x != nil {
+		return x.TotalIncome
+	}
+	return 0
+}
+
+func (x *CalculateRequest) GetWht() float64 {
+	if x != nil {
+		return x.Wht
+	}
+	return 0
+}
+
+func (x *CalculateRequest) GetAllowances() []*Allowance {
+	if x != nil {
+		return x.Allowances
+	}
+	return nil
+}
+
+func (x *CalculateRequest) GetTaxYear() int32 {
+	if x != nil {
+		return x.TaxYear
+	}
+	return 0
+}
+
+type TaxLevel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Level string  `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Tax   float64 `protobuf:"fixed64,2,opt,name=tax,proto3" json:"tax,omitempty"`
+}
+
+func (x *TaxLevel) Reset() {
+	*x = TaxLevel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TaxLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaxLevel) ProtoMessage() {}
+
+func (x *TaxLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaxLevel.ProtoReflect.Descriptor instead.
+func (*TaxLevel) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TaxLevel) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *TaxLevel) GetTax() float64 {
+	if x != nil {
+		return x.Tax
+	}
+	return 0
+}
+
+type TaxRates struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TaxableIncome            float64 `protobuf:"fixed64,1,opt,name=taxable_income,json=taxableIncome,proto3" json:"taxable_income,omitempty"`
+	EffectiveRate            float64 `protobuf:"fixed64,2,opt,name=effective_rate,json=effectiveRate,proto3" json:"effective_rate,omitempty"`
+	EffectiveRateOnNetIncome float64 `protobuf:"fixed64,3,opt,name=effective_rate_on_net_income,json=effectiveRateOnNetIncome,proto3" json:"effective_rate_on_net_income,omitempty"`
+	MarginalLevel            string  `protobuf:"bytes,4,opt,name=marginal_level,json=marginalLevel,proto3" json:"marginal_level,omitempty"`
+	MarginalRate             float64 `protobuf:"fixed64,5,opt,name=marginal_rate,json=marginalRate,proto3" json:"marginal_rate,omitempty"`
+	// Unset in the top level.
+	NextLevelThreshold *float64 `protobuf:"fixed64,6,opt,name=next_level_threshold,json=nextLevelThreshold,proto3,oneof" json:"next_level_threshold,omitempty"`
+	IncomeToNextLevel  *float64 `protobuf:"fixed64,7,opt,name=income_to_next_level,json=incomeToNextLevel,proto3,oneof" json:"income_to_next_level,omitempty"`
+}
+
+func (x *TaxRates) Reset() {
+	*x = TaxRates{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TaxRates) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaxRates) ProtoMessage() {}
+
+func (x *TaxRates) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaxRates.ProtoReflect.Descriptor instead.
+func (*TaxRates) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TaxRates) GetTaxableIncome() float64 {
+	if x != nil {
+		return x.TaxableIncome
+	}
+	return 0
+}
+
+func (x *TaxRates) GetEffectiveRate() float64 {
+	if x != nil {
+		return x.EffectiveRate
+	}
+	return 0
+}
+
+func (x *TaxRates) GetEffectiveRateOnNetIncome() float64 {
+	if x != nil {
+		return x.EffectiveRateOnNetIncome
+	}
+	return 0
+}
+
+func (x *TaxRates) GetMarginalLevel() string {
+	if x != nil {
+		return x.MarginalLevel
+	}
+	return ""
+}
+
+func (x *TaxRates) GetMarginalRate() float64 {
+	if x != nil {
+		return x.MarginalRate
+	}
+	return 0
+}
+
+func (x *TaxRates) GetNextLevelThreshold() float64 {
+	if x != nil && x.NextLevelThreshold != nil {
+		return *x.NextLevelThreshold
+	}
+	return 0
+}
+
+func (x *TaxRates) GetIncomeToNextLevel() float64 {
+	if x != nil && x.IncomeToNextLevel != nil {
+		return *x.IncomeToNextLevel
+	}
+	return 0
+}
+
+type CalculateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tax       float64     `protobuf:"fixed64,1,opt,name=tax,proto3" json:"tax,omitempty"`
+	TaxRefund float64     `protobuf:"fixed64,2,opt,name=tax_refund,json=taxRefund,proto3" json:"tax_refund,omitempty"`
+	TaxLevels []*TaxLevel `protobuf:"bytes,3,rep,name=tax_levels,json=taxLevels,proto3" json:"tax_levels,omitempty"`
+	Rates     *TaxRates   `protobuf:"bytes,4,opt,name=rates,proto3" json:"rates,omitempty"`
+}
+
+func (x *CalculateResponse) Reset() {
+	*x = CalculateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CalculateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculateResponse) ProtoMessage() {}
+
+func (x *CalculateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculateResponse.ProtoReflect.Descriptor instead.
+func (*CalculateResponse) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CalculateResponse) GetTax() float64 {
+	if x != nil {
+		return x.Tax
+	}
+	return 0
+}
+
+func (x *CalculateResponse) GetTaxRefund() float64 {
+	if x != nil {
+		return x.TaxRefund
+	}
+	return 0
+}
+
+func (x *CalculateResponse) GetTaxLevels() []*TaxLevel {
+	if x != nil {
+		return x.TaxLevels
+	}
+	return nil
+}
+
+func (x *CalculateResponse) GetRates() *TaxRates {
+	if x != nil {
+		return x.Rates
+	}
+	return nil
+}
+
+type CalculateBatchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Position of the request on the stream, starting at 0.
+	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Types that are assignable to Outcome:
+	//	*CalculateBatchResponse_Result
+	//	*CalculateBatchResponse_Error
+	Outcome isCalculateBatchResponse_Outcome `protobuf_oneof:"outcome"`
+}
+
+func (x *CalculateBatchResponse) Reset() {
+	*x = CalculateBatchResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CalculateBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculateBatchResponse) ProtoMessage() {}
+
+func (x *CalculateBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculateBatchResponse.ProtoReflect.Descriptor instead.
+func (*CalculateBatchResponse) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CalculateBatchResponse) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (m *CalculateBatchResponse) GetOutcome() isCalculateBatchResponse_Outcome {
+	if m != nil {
+		return m.Outcome
+	}
+	return nil
+}
+
+func (x *CalculateBatchResponse) GetResult() *CalculateResponse {
+	if x, ok := x.GetOutcome().(*CalculateBatchResponse_Result); ok {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *CalculateBatchResponse) GetError() string {
+	if x, ok := x.GetOutcome().(*CalculateBatchResponse_Error); ok {
+		return x.Error
+	}
+	return ""
+}
+
+type isCalculateBatchResponse_Outcome interface {
+	isCalculateBatchResponse_Outcome()
+}
+
+type CalculateBatchResponse_Result struct {
+	Result *CalculateResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+type CalculateBatchResponse_Error struct {
+	Error string `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+func (*CalculateBatchResponse_Result) isCalculateBatchResponse_Outcome() {}
+
+func (*CalculateBatchResponse_Error) isCalculateBatchResponse_Outcome() {}
+
+type GetSettingsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetSettingsRequest) Reset() {
+	*x = GetSettingsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetSettingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSettingsRequest) ProtoMessage() {}
+
+func (x *GetSettingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSettingsRequest.ProtoReflect.Descriptor instead.
+func (*GetSettingsRequest) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{6}
+}
+
+type Settings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PersonalDeduction float64 `protobuf:"fixed64,1,opt,name=personal_deduction,json=personalDeduction,proto3" json:"personal_deduction,omitempty"`
+	KReceipt          float64 `protobuf:"fixed64,2,opt,name=k_receipt,json=kReceipt,proto3" json:"k_receipt,omitempty"`
+	// The etags are the same as the ETag headers of the REST API.
+	PersonalDeductionEtag string `protobuf:"bytes,3,opt,name=personal_deduction_etag,json=personalDeductionEtag,proto3" json:"personal_deduction_etag,omitempty"`
+	KReceiptEtag          string `protobuf:"bytes,4,opt,name=k_receipt_etag,json=kReceiptEtag,proto3" json:"k_receipt_etag,omitempty"`
+}
+
+func (x *Settings) Reset() {
+	*x = Settings{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Settings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Settings) ProtoMessage() {}
+
+func (x *Settings) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Settings.ProtoReflect.Descriptor instead.
+func (*Settings) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Settings) GetPersonalDeduction() float64 {
+	if x != nil {
+		return x.PersonalDeduction
+	}
+	return 0
+}
+
+func (x *Settings) GetKReceipt() float64 {
+	if x != nil {
+		return x.KReceipt
+	}
+	return 0
+}
+
+func (x *Settings) GetPersonalDeductionEtag() string {
+	if x != nil {
+		return x.PersonalDeductionEtag
+	}
+	return ""
+}
+
+func (x *Settings) GetKReceiptEtag() string {
+	if x != nil {
+		return x.KReceiptEtag
+	}
+	return ""
+}
+
+type UpdateDeductionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type   DeductionType `protobuf:"varint,1,opt,name=type,proto3,enum=tax.v1.DeductionType" json:"type,omitempty"`
+	Amount float64       `protobuf:"fixed64,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	// etag of the deduction the update is based on.
+	Etag string `protobuf:"bytes,3,opt,name=etag,proto3" json:"etag,omitempty"`
+}
+
+func (x *UpdateDeductionRequest) Reset() {
+	*x = UpdateDeductionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tax_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateDeductionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDeductionRequest) ProtoMessage() {}
+
+func (x *UpdateDeductionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tax_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDeductionRequest.ProtoReflect.Descriptor instead.
+func (*UpdateDeductionRequest) Descriptor() ([]byte, []int) {
+	return file_tax_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateDeductionRequest) GetType() DeductionType {
+	if x != nil {
+		return x.Type
+	}
+	return DeductionType_DEDUCTION_TYPE_UNSPECIFIED
+}
+
+func (x *UpdateDeductionRequest) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *UpdateDeductionRequest) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+var File_tax_proto protoreflect.FileDescriptor
+
+var file_tax_proto_rawDesc = []byte{
+	0x0a, 0x09, 0x74, 0x61, 0x78, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x74, 0x61, 0x78,
+	0x2e, 0x76, 0x31, 0x22, 0x4a, 0x0a, 0x09, 0x41, 0x6c, 0x6c, 0x6f, 0x77, 0x61, 0x6e, 0x63, 0x65,
+	0x12, 0x25, 0x0a, 0x0e, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x61,
+	0x6e, 0x63, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22,
+	0x95, 0x01, 0x0a, 0x10, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x69, 0x6e,
+	0x63, 0x6f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x74, 0x6f, 0x74, 0x61,
+	0x6c, 0x49, 0x6e, 0x63, 0x6f, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x77, 0x68, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x77, 0x68, 0x74, 0x12, 0x31, 0x0a, 0x0a, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x77, 0x61, 0x6e, 0x63, 0x65,
+	0x52, 0x0a, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08,
+	0x74, 0x61, 0x78, 0x5f, 0x79, 0x65, 0x61, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07,
+	0x74, 0x61, 0x78, 0x59, 0x65, 0x61, 0x72, 0x22, 0x32, 0x0a, 0x08, 0x54, 0x61, 0x78, 0x4c, 0x65,
+	0x76, 0x65, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x61, 0x78,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x74, 0x61, 0x78, 0x22, 0x83, 0x03, 0x0a, 0x08,
+	0x54, 0x61, 0x78, 0x52, 0x61, 0x74, 0x65, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x74, 0x61, 0x78, 0x61,
+	0x62, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x0d, 0x74, 0x61, 0x78, 0x61, 0x62, 0x6c, 0x65, 0x49, 0x6e, 0x63, 0x6f, 0x6d, 0x65, 0x12,
+	0x25, 0x0a, 0x0e, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x72, 0x61, 0x74,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0d, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69,
+	0x76, 0x65, 0x52, 0x61, 0x74, 0x65, 0x12, 0x3e, 0x0a, 0x1c, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x5f, 0x6f, 0x6e, 0x5f, 0x6e, 0x65, 0x74, 0x5f,
+	0x69, 0x6e, 0x63, 0x6f, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x18, 0x65, 0x66,
+	0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x52, 0x61, 0x74, 0x65, 0x4f, 0x6e, 0x4e, 0x65, 0x74,
+	0x49, 0x6e, 0x63, 0x6f, 0x6d, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e,
+	0x61, 0x6c, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d,
+	0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x23, 0x0a,
+	0x0d, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x72, 0x61, 0x74, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x0c, 0x6d, 0x61, 0x72, 0x67, 0x69, 0x6e, 0x61, 0x6c, 0x52, 0x61,
+	0x74, 0x65, 0x12, 0x35, 0x0a, 0x14, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c,
+	0x5f, 0x74, 0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01,
+	0x48, 0x00, 0x52, 0x12, 0x6e, 0x65, 0x78, 0x74, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x54, 0x68, 0x72,
+	0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x88, 0x01, 0x01, 0x12, 0x34, 0x0a, 0x14, 0x69, 0x6e, 0x63,
+	0x6f, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x5f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x6c, 0x65, 0x76, 0x65,
+	0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x11, 0x69, 0x6e, 0x63, 0x6f, 0x6d,
+	0x65, 0x54, 0x6f, 0x4e, 0x65, 0x78, 0x74, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x88, 0x01, 0x01, 0x42,
+	0x17, 0x0a, 0x15, 0x5f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x5f, 0x74,
+	0x68, 0x72, 0x65, 0x73, 0x68, 0x6f, 0x6c, 0x64, 0x42, 0x17, 0x0a, 0x15, 0x5f, 0x69, 0x6e, 0x63,
+	0x6f, 0x6d, 0x65, 0x5f, 0x74, 0x6f, 0x5f, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x6c, 0x65, 0x76, 0x65,
+	0x6c, 0x22, 0x9d, 0x01, 0x0a, 0x11, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x61, 0x78, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x74, 0x61, 0x78, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x61, 0x78,
+	0x5f, 0x72, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x74,
+	0x61, 0x78, 0x52, 0x65, 0x66, 0x75, 0x6e, 0x64, 0x12, 0x2f, 0x0a, 0x0a, 0x74, 0x61, 0x78, 0x5f,
+	0x6c, 0x65, 0x76, 0x65, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74,
+	0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x78, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x09,
+	0x74, 0x61, 0x78, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x73, 0x12, 0x26, 0x0a, 0x05, 0x72, 0x61, 0x74,
+	0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76,
+	0x31, 0x2e, 0x54, 0x61, 0x78, 0x52, 0x61, 0x74, 0x65, 0x73, 0x52, 0x05, 0x72, 0x61, 0x74, 0x65,
+	0x73, 0x22, 0x86, 0x01, 0x0a, 0x16, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69, 0x6e, 0x64,
+	0x65, 0x78, 0x12, 0x33, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c, 0x63,
+	0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52,
+	0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x16, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x42,
+	0x09, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x63, 0x6f, 0x6d, 0x65, 0x22, 0x14, 0x0a, 0x12, 0x47, 0x65,
+	0x74, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x22, 0xb4, 0x01, 0x0a, 0x08, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x2d, 0x0a,
+	0x12, 0x70, 0x65, 0x72, 0x73, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x64, 0x65, 0x64, 0x75, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11, 0x70, 0x65, 0x72, 0x73, 0x6f,
+	0x6e, 0x61, 0x6c, 0x44, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09,
+	0x6b, 0x5f, 0x72, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x08, 0x6b, 0x52, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x12, 0x36, 0x0a, 0x17, 0x70, 0x65, 0x72,
+	0x73, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x64, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x65, 0x74, 0x61, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x15, 0x70, 0x65, 0x72, 0x73,
+	0x6f, 0x6e, 0x61, 0x6c, 0x44, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x45, 0x74, 0x61,
+	0x67, 0x12, 0x24, 0x0a, 0x0e, 0x6b, 0x5f, 0x72, 0x65, 0x63, 0x65, 0x69, 0x70, 0x74, 0x5f, 0x65,
+	0x74, 0x61, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6b, 0x52, 0x65, 0x63, 0x65,
+	0x69, 0x70, 0x74, 0x45, 0x74, 0x61, 0x67, 0x22, 0x6f, 0x0a, 0x16, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x44, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x29, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x15, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x61, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x65, 0x74, 0x61, 0x67, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x65, 0x74, 0x61, 0x67, 0x2a, 0x6a, 0x0a, 0x0d, 0x44, 0x65, 0x64, 0x75,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1e, 0x0a, 0x1a, 0x44, 0x45, 0x44,
+	0x55, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50,
+	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x1b, 0x0a, 0x17, 0x44, 0x45, 0x44,
+	0x55, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x45, 0x52, 0x53,
+	0x4f, 0x4e, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18, 0x44, 0x45, 0x44, 0x55, 0x43, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4b, 0x5f, 0x52, 0x45, 0x43, 0x45, 0x49,
+	0x50, 0x54, 0x10, 0x02, 0x32, 0xa0, 0x02, 0x0a, 0x0a, 0x54, 0x61, 0x78, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x12, 0x40, 0x0a, 0x09, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65,
+	0x12, 0x18, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c,
+	0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x74, 0x61, 0x78,
+	0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4e, 0x0a, 0x0e, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61,
+	0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x18, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31,
+	0x2e, 0x43, 0x61, 0x6c, 0x63, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1e, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x6c, 0x63, 0x75,
+	0x6c, 0x61, 0x74, 0x65, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x3b, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x53, 0x65, 0x74, 0x74,
+	0x69, 0x6e, 0x67, 0x73, 0x12, 0x1a, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
+	0x74, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x10, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e,
+	0x67, 0x73, 0x12, 0x43, 0x0a, 0x0f, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x44, 0x65, 0x64, 0x75,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x55,
+	0x70, 0x64, 0x61, 0x74, 0x65, 0x44, 0x65, 0x64, 0x75, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74, 0x61, 0x78, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x42, 0x29, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x70, 0x69, 0x72, 0x6f, 0x6d, 0x39, 0x2f, 0x61, 0x73,
+	0x73, 0x65, 0x73, 0x73, 0x6d, 0x65, 0x6e, 0x74, 0x2d, 0x74, 0x61, 0x78, 0x2f, 0x74, 0x61, 0x78,
+	0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_tax_proto_rawDescOnce sync.Once
+	file_tax_proto_rawDescData = file_tax_proto_rawDesc
+)
+
+func file_tax_proto_rawDescGZIP() []byte {
+	file_tax_proto_rawDescOnce.Do(func() {
+		file_tax_proto_rawDescData = protoimpl.X.CompressGZIP(file_tax_proto_rawDescData)
+	})
+	return file_tax_proto_rawDescData
+}
+
+var file_tax_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_tax_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_tax_proto_goTypes = []interface{}{
+	(DeductionType)(0),             // 0: tax.v1.DeductionType
+	(*Allowance)(nil),              // 1: tax.v1.Allowance
+	(*CalculateRequest)(nil),       // 2: tax.v1.CalculateRequest
+	(*TaxLevel)(nil),               // 3: tax.v1.TaxLevel
+	(*TaxRates)(nil),               // 4: tax.v1.TaxRates
+	(*CalculateResponse)(nil),      // 5: tax.v1.CalculateResponse
+	(*CalculateBatchResponse)(nil), // 6: tax.v1.CalculateBatchResponse
+	(*GetSettingsRequest)(nil),     // 7: tax.v1.GetSettingsRequest
+	(*Settings)(nil),               // 8: tax.v1.Settings
+	(*UpdateDeductionRequest)(nil), // 9: tax.v1.UpdateDeductionRequest
+}
+var file_tax_proto_depIdxs = []int32{
+	1, // 0: tax.v1.CalculateRequest.allowances:type_name -> tax.v1.Allowance
+	3, // 1: tax.v1.CalculateResponse.tax_levels:type_name -> tax.v1.TaxLevel
+	4, // 2: tax.v1.CalculateResponse.rates:type_name -> tax.v1.TaxRates
+	5, // 3: tax.v1.CalculateBatchResponse.result:type_name -> tax.v1.CalculateResponse
+	0, // 4: tax.v1.UpdateDeductionRequest.type:type_name -> tax.v1.DeductionType
+	2, // 5: tax.v1.TaxService.Calculate:input_type -> tax.v1.CalculateRequest
+	2, // 6: tax.v1.TaxService.CalculateBatch:input_type -> tax.v1.CalculateRequest
+	7, // 7: tax.v1.TaxService.GetSettings:input_type -> tax.v1.GetSettingsRequest
+	9, // 8: tax.v1.TaxService.UpdateDeduction:input_type -> tax.v1.UpdateDeductionRequest
+	5, // 9: tax.v1.TaxService.Calculate:output_type -> tax.v1.CalculateResponse
+	6, // 10: tax.v1.TaxService.CalculateBatch:output_type -> tax.v1.CalculateBatchResponse
+	8, // 11: tax.v1.TaxService.GetSettings:output_type -> tax.v1.Settings
+	8, // 12: tax.v1.TaxService.UpdateDeduction:output_type -> tax.v1.Settings
+	9, // [9:13] is the sub-list for method output_type
+	5, // [5:9] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_tax_proto_init() }
+func file_tax_proto_init() {
+	if File_tax_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_tax_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Allowance); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CalculateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TaxLevel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TaxRates); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CalculateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CalculateBatchResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSettingsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Settings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tax_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateDeductionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_tax_proto_msgTypes[3].OneofWrappers = []interface{}{}
+	file_tax_proto_msgTypes[5].OneofWrappers = []interface{}{
+		(*CalculateBatchResponse_Result)(nil),
+		(*CalculateBatchResponse_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_tax_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tax_proto_goTypes,
+		DependencyIndexes: file_tax_proto_depIdxs,
+		EnumInfos:         file_tax_proto_enumTypes,
+		MessageInfos:      file_tax_proto_msgTypes,
+	}.Build()
+	File_tax_proto = out.File
+	file_tax_proto_rawDesc = nil
+	file_tax_proto_goTypes = nil
+	file_tax_proto_depIdxs = nil
+}
@@ -0,0 +1,261 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: tax.proto
+
+package taxpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	TaxService_Calculate_FullMethodName       = "/tax.v1.TaxService/Calculate"
+	TaxService_CalculateBatch_FullMethodName  = "/tax.v1.TaxService/CalculateBatch"
+	TaxService_GetSettings_FullMethodName     = "/tax.v1.TaxService/GetSettings"
+	TaxService_UpdateDeduction_FullMethodName = "/tax.v1.TaxService/UpdateDeduction"
+)
+
+// TaxServiceClient is the client API for TaxService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TaxServiceClient interface {
+	Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error)
+	// CalculateBatch answers each request on the stream in order. Invalid
+	// requests are answered with an error message instead of ending the stream.
+	CalculateBatch(ctx context.Context, opts ...grpc.CallOption) (TaxService_CalculateBatchClient, error)
+	GetSettings(ctx context.Context, in *GetSettingsRequest, opts ...grpc.CallOption) (*Settings, error)
+	// UpdateDeduction fails with FailedPrecondition without an etag and with
+	// Aborted if the etag is not the one of the current deduction.
+	UpdateDeduction(ctx context.Context, in *UpdateDeductionRequest, opts ...grpc.CallOption) (*Settings, error)
+}
+
+type taxServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTaxServiceClient(cc grpc.ClientConnInterface) TaxServiceClient {
+	return &taxServiceClient{cc}
+}
+
+func (c *taxServiceClient) Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error) {
+	out := new(CalculateResponse)
+	err := c.cc.Invoke(ctx, TaxService_Calculate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taxServiceClient) CalculateBatch(ctx context.Context, opts ...grpc.CallOption) (TaxService_CalculateBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaxService_ServiceDesc.Streams[0], TaxService_CalculateBatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taxServiceCalculateBatchClient{stream}
+	return x, nil
+}
+
+type TaxService_CalculateBatchClient interface {
+	Send(*CalculateRequest) error
+	Recv() (*CalculateBatchResponse, error)
+	grpc.ClientStream
+}
+
+type taxServiceCalculateBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *taxServiceCalculateBatchClient) Send(m *CalculateRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *taxServiceCalculateBatchClient) Recv() (*CalculateBatchResponse, error) {
+	m := new(CalculateBatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *taxServiceClient) GetSettings(ctx context.Context, in *GetSettingsRequest, opts ...grpc.CallOption) (*Settings, error) {
+	out := new(Settings)
+	err := c.cc.Invoke(ctx, TaxService_GetSettings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taxServiceClient) UpdateDeduction(ctx context.Context, in *UpdateDeductionRequest, opts ...grpc.CallOption) (*Settings, error) {
+	out := new(Settings)
+	err := c.cc.Invoke(ctx, TaxService_UpdateDeduction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TaxServiceServer is the server API for TaxService service.
+// All implementations must embed UnimplementedTaxServiceServer
+// for forward compatibility
+type TaxServiceServer interface {
+	Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error)
+	// CalculateBatch answers each request on the stream in order. Invalid
+	// requests are answered with an error message instead of ending the stream.
+	CalculateBatch(TaxService_CalculateBatchServer) error
+	GetSettings(context.Context, *GetSettingsRequest) (*Settings, error)
+	// UpdateDeduction fails with FailedPrecondition without an etag and with
+	// Aborted if the etag is not the one of the current deduction.
+	UpdateDeduction(context.Context, *UpdateDeductionRequest) (*Settings, error)
+	mustEmbedUnimplementedTaxServiceServer()
+}
+
+// UnimplementedTaxServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTaxServiceServer struct {
+}
+
+func (UnimplementedTaxServiceServer) Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Calculate not implemented")
+}
+func (UnimplementedTaxServiceServer) CalculateBatch(TaxService_CalculateBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method CalculateBatch not implemented")
+}
+func (UnimplementedTaxServiceServer) GetSettings(context.Context, *GetSettingsRequest) (*Settings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSettings not implemented")
+}
+func (UnimplementedTaxServiceServer) UpdateDeduction(context.Context, *UpdateDeductionRequest) (*Settings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDeduction not implemented")
+}
+func (UnimplementedTaxServiceServer) mustEmbedUnimplementedTaxServiceServer() {}
+
+// UnsafeTaxServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TaxServiceServer will
+// result in compilation errors.
+type UnsafeTaxServiceServer interface {
+	mustEmbedUnimplementedTaxServiceServer()
+}
+
+func RegisterTaxServiceServer(s grpc.ServiceRegistrar, srv TaxServiceServer) {
+	s.RegisterService(&TaxService_ServiceDesc, srv)
+}
+
+func _TaxService_Calculate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaxServiceServer).Calculate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaxService_Calculate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaxServiceServer).Calculate(ctx, req.(*CalculateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaxService_CalculateBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TaxServiceServer).CalculateBatch(&taxServiceCalculateBatchServer{stream})
+}
+
+type TaxService_CalculateBatchServer interface {
+	Send(*CalculateBatchResponse) error
+	Recv() (*CalculateRequest, error)
+	grpc.ServerStream
+}
+
+type taxServiceCalculateBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *taxServiceCalculateBatchServer) Send(m *CalculateBatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *taxServiceCalculateBatchServer) Recv() (*CalculateRequest, error) {
+	m := new(CalculateRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _TaxService_GetSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaxServiceServer).GetSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaxService_GetSettings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaxServiceServer).GetSettings(ctx, req.(*GetSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaxService_UpdateDeduction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDeductionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaxServiceServer).UpdateDeduction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaxService_UpdateDeduction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaxServiceServer).UpdateDeduction(ctx, req.(*UpdateDeductionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TaxService_ServiceDesc is the grpc.ServiceDesc for TaxService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TaxService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tax.v1.TaxService",
+	HandlerType: (*TaxServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Calculate",
+			Handler:    _TaxService_Calculate_Handler,
+		},
+		{
+			MethodName: "GetSettings",
+			Handler:    _TaxService_GetSettings_Handler,
+		},
+		{
+			MethodName: "UpdateDeduction",
+			Handler:    _TaxService_UpdateDeduction_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CalculateBatch",
+			Handler:       _TaxService_CalculateBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "tax.proto",
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestTraceIDHeader(t *testing.T) {
+	t.Run("given traced request should return trace id header", func(t *testing.T) {
+		provider := sdktrace.NewTracerProvider()
+		ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+		defer span.End()
+		req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(req, res)
+
+		TraceIDHeader(func(c echo.Context) error {
+			return c.NoContent(http.StatusBadRequest)
+		})(c)
+
+		if got := res.Header().Get(HeaderTraceID); got != span.SpanContext().TraceID().String() {
+			t.Errorf("expected trace id %v but got %v", span.SpanContext().TraceID(), got)
+		}
+	})
+
+	t.Run("given untraced request should not return trace id header", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), res)
+
+		TraceIDHeader(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})(c)
+
+		if got := res.Header().Get(HeaderTraceID); got != "" {
+			t.Errorf("expected no trace id but got %v", got)
+		}
+	})
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("given unknown exporter should return error", func(t *testing.T) {
+		if _, err := Setup(context.Background(), "zipkin"); err == nil {
+			t.Errorf("expect error but got nil")
+		}
+	})
+}